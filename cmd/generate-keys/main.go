@@ -11,6 +11,7 @@ import (
 	"log"
 
 	"github.com/Bren2010/katie/crypto/vrf/p256"
+	"github.com/Bren2010/katie/crypto/vrf/p384"
 )
 
 func main() {
@@ -20,10 +21,12 @@ func main() {
 	switch flag.Arg(0) {
 	case "p256":
 		generateP256()
+	case "p384":
+		generateP384()
 	case "ed25519":
 		generateEd25519()
 	default:
-		log.Fatalf("Usage: generate-keys (p256|ed25519)")
+		log.Fatalf("Usage: generate-keys (p256|p384|ed25519)")
 	}
 }
 
@@ -55,6 +58,34 @@ func generateP256() {
 	fmt.Printf("VRF Public Key:      %x\n", vrfPublic)
 }
 
+func generateP384() {
+	sigKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	sigKeyRaw, err := sigKey.Bytes()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Signing Private Key: %x\n", sigKeyRaw)
+
+	sigPublic, err := sigKey.Public().(*ecdsa.PublicKey).Bytes()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("Signing Public Key:  %x\n", sigPublic)
+
+	vrfKey := p384.GeneratePrivateKey()
+	fmt.Printf("VRF Private Key:     %x\n", vrfKey)
+
+	temp, err := p384.NewPrivateKey(vrfKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	vrfPublic := temp.PublicKey().Bytes()
+	fmt.Printf("VRF Public Key:      %x\n", vrfPublic)
+}
+
 func generateEd25519() {
 	sigKey := make([]byte, ed25519.SeedSize)
 	if _, err := rand.Read(sigKey); err != nil {
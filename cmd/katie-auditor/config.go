@@ -0,0 +1,193 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Bren2010/katie/crypto/suites"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config specifies the file format of config files.
+type Config struct {
+	ServerAddr  string     `yaml:"server-addr"`
+	MetricsAddr string     `yaml:"metrics-addr"`
+	TLSConfig   *TLSConfig `yaml:"tls"`
+	tlsConfig   *tls.Config
+
+	LogConfig *LogConfig `yaml:"log"`
+
+	DatabaseFile string `yaml:"db-file"`
+}
+
+// TLSConfig specifies the API server's TLS config. It's required, and the
+// server also requires a valid client certificate, so that only the
+// Transparency Log operator is able to submit updates. Otherwise, anyone could
+// submit an update that advances the auditor's position, causing the
+// operator's real updates to be rejected.
+type TLSConfig struct {
+	Cert     string `yaml:"cert"`
+	Key      string `yaml:"key"`
+	ClientCA string `yaml:"client-ca"` // CA for validating client certificates.
+}
+
+// LogConfig specifies the public configuration of the Transparency Log being
+// audited, along with the auditor's private key.
+type LogConfig struct {
+	CipherSuite string `yaml:"cipher-suite"` // Name of the cipher suite, like "KTSha256P256".
+
+	SigningPublicKey string `yaml:"signing-public-key"` // Hex-encoded signing public key of the log.
+	VRFPublicKey     string `yaml:"vrf-public-key"`     // Hex-encoded VRF public key of the log.
+	AuditorKey       string `yaml:"auditor-key"`        // Hex-encoded signing private key of the auditor.
+
+	MaxAuditorLag   time.Duration `yaml:"max-auditor-lag"`
+	AuditorStartPos uint64        `yaml:"auditor-start-pos"`
+
+	MaxAhead                   time.Duration `yaml:"max-ahead"`
+	MaxBehind                  time.Duration `yaml:"max-behind"`
+	ReasonableMonitoringWindow time.Duration `yaml:"reasonable-monitoring-window"`
+	MaximumLifetime            time.Duration `yaml:"maximum-lifetime"` // Optional.
+
+	publicConfig *structs.PublicConfig
+	auditorKey   suites.SigningPrivateKey
+}
+
+func ReadConfig(filename string) (*Config, error) {
+	// Read from file and parse.
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var parsed Config
+	if err := yaml.Unmarshal(raw, &parsed); err != nil {
+		return nil, err
+	}
+
+	// Check that all required fields are populated.
+	if parsed.ServerAddr == "" {
+		return nil, fmt.Errorf("field not provided: server-addr")
+	} else if parsed.MetricsAddr == "" {
+		return nil, fmt.Errorf("field not provided: metrics-addr")
+	} else if parsed.LogConfig == nil {
+		return nil, fmt.Errorf("field not provided: log")
+	} else if parsed.DatabaseFile == "" {
+		return nil, fmt.Errorf("field not provided: db-file")
+	} else if parsed.TLSConfig == nil {
+		return nil, fmt.Errorf("field not provided: tls")
+	}
+
+	// Parse TLS config.
+	parsed.tlsConfig, err = parsed.TLSConfig.parse()
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the Transparency Log's configuration.
+	if err := parsed.LogConfig.parse(); err != nil {
+		return nil, err
+	}
+
+	return &parsed, nil
+}
+
+func (tc *TLSConfig) parse() (*tls.Config, error) {
+	if tc.Cert == "" {
+		return nil, fmt.Errorf("field not provided: tls.cert")
+	} else if tc.Key == "" {
+		return nil, fmt.Errorf("field not provided: tls.key")
+	} else if tc.ClientCA == "" {
+		return nil, fmt.Errorf("field not provided: tls.client-ca")
+	}
+
+	cert, err := tls.LoadX509KeyPair(tc.Cert, tc.Key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate/key: %v", err)
+	}
+
+	certPool := x509.NewCertPool()
+	caCerts, err := os.ReadFile(tc.ClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS client CA: %v", err)
+	} else if ok := certPool.AppendCertsFromPEM(caCerts); !ok {
+		return nil, fmt.Errorf("no client CA certificates successfully parsed from file")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    certPool,
+	}, nil
+}
+
+func (lc *LogConfig) parse() error {
+	cs, ok := suites.ByName(lc.CipherSuite)
+	if !ok {
+		return fmt.Errorf("unknown cipher suite: %q", lc.CipherSuite)
+	}
+
+	if lc.SigningPublicKey == "" {
+		return fmt.Errorf("field not provided: log.signing-public-key")
+	} else if lc.VRFPublicKey == "" {
+		return fmt.Errorf("field not provided: log.vrf-public-key")
+	} else if lc.AuditorKey == "" {
+		return fmt.Errorf("field not provided: log.auditor-key")
+	} else if lc.MaxAuditorLag <= 0 {
+		return fmt.Errorf("field not provided: log.max-auditor-lag")
+	} else if lc.ReasonableMonitoringWindow <= 0 {
+		return fmt.Errorf("field not provided: log.reasonable-monitoring-window")
+	} else if lc.MaximumLifetime < 0 {
+		return fmt.Errorf("field must not be negative: log.maximum-lifetime")
+	}
+
+	// Parse cryptographic keys.
+	rawSigKey, err := hex.DecodeString(lc.SigningPublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse signing public key: %v", err)
+	}
+	sigKey, err := cs.ParseSigningPublicKey(rawSigKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse signing public key: %v", err)
+	}
+	rawVrfKey, err := hex.DecodeString(lc.VRFPublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse vrf public key: %v", err)
+	}
+	vrfKey, err := cs.ParseVRFPublicKey(rawVrfKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse vrf public key: %v", err)
+	}
+	rawAuditorKey, err := hex.DecodeString(lc.AuditorKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse auditor key: %v", err)
+	}
+	lc.auditorKey, err = cs.ParseSigningPrivateKey(rawAuditorKey)
+	if err != nil {
+		return fmt.Errorf("failed to parse auditor key: %v", err)
+	}
+
+	lc.publicConfig = &structs.PublicConfig{
+		SignatureKey: sigKey,
+		VrfKey:       vrfKey,
+		Config: structs.Config{
+			Suite: cs,
+			Mode:  structs.ThirdPartyAuditing,
+
+			AuditorPublicKey: lc.auditorKey.Public(),
+			MaxAuditorLag:    uint64(lc.MaxAuditorLag.Milliseconds()),
+			AuditorStartPos:  lc.AuditorStartPos,
+
+			MaxAhead:                   uint64(lc.MaxAhead.Milliseconds()),
+			MaxBehind:                  uint64(lc.MaxBehind.Milliseconds()),
+			ReasonableMonitoringWindow: uint64(lc.ReasonableMonitoringWindow.Milliseconds()),
+			MaximumLifetime:            uint64(lc.MaximumLifetime.Milliseconds()),
+		},
+	}
+
+	return nil
+}
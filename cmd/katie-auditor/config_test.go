@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// issue returns a new certificate and private key for `name`, signed by
+// `parent` if it's provided and self-signed otherwise.
+func issue(t *testing.T, name string, parent *tls.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+	}
+	signer, signerCert := any(key), template
+	if parent != nil {
+		signer, signerCert = parent.PrivateKey, parent.Leaf
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, signerCert, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{raw}, PrivateKey: key, Leaf: leaf}
+}
+
+// writePEM writes the PEM encoding of a block with the given type and contents
+// to a new file in `dir`, and returns its path.
+func writePEM(t *testing.T, dir, name, typ string, raw []byte) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: raw}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTLSConfigRequiresClientCert(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "ca", nil)
+	server := issue(t, "localhost", &ca)
+	client := issue(t, "operator", &ca)
+
+	rawKey, err := x509.MarshalPKCS8PrivateKey(server.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := &TLSConfig{
+		Cert:     writePEM(t, dir, "cert.pem", "CERTIFICATE", server.Certificate[0]),
+		Key:      writePEM(t, dir, "key.pem", "PRIVATE KEY", rawKey),
+		ClientCA: writePEM(t, dir, "ca.pem", "CERTIFICATE", ca.Certificate[0]),
+	}
+	tlsConfig, err := tc.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = tlsConfig
+	srv.StartTLS()
+	defer srv.Close()
+	url := strings.Replace(srv.URL, "127.0.0.1", "localhost", 1)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	get := func(certs []tls.Certificate) error {
+		c := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: certs,
+		}}}
+		res, err := c.Get(url)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+	if err := get(nil); err == nil {
+		t.Fatal("expected client without certificate to be rejected")
+	} else if err := get([]tls.Certificate{client}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSConfigRequired(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	raw := "server-addr: :8080\nmetrics-addr: :8081\nlog: {}\ndb-file: db\n"
+	if err := os.WriteFile(path, []byte(raw), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadConfig(path); err == nil || !strings.Contains(err.Error(), "tls") {
+		t.Fatalf("expected error for missing tls config, got: %v", err)
+	}
+	if _, err := (&TLSConfig{Cert: "cert.pem", Key: "key.pem"}).parse(); err == nil {
+		t.Fatal("expected error for missing client CA")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/Bren2010/katie/tree/transparency/auditor"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/wire"
+	"github.com/Bren2010/katie/tree/transparency/wire/transport"
+)
+
+// auditHandler wraps an auditor.Service to record metrics, and to choose the
+// status code that errors are returned to the operator with.
+type auditHandler struct {
+	service *auditor.Service
+}
+
+var _ wire.AuditorInterface = &auditHandler{}
+
+func (ah *auditHandler) Audit(ctx context.Context, pos uint64, update *structs.AuditorUpdate) (*structs.AuditorTreeHead, error) {
+	start := time.Now()
+	head, err := ah.service.Audit(ctx, pos, update)
+	auditDur.Observe(float64(time.Since(start).Microseconds()))
+
+	switch {
+	case err == nil:
+		auditOps.WithLabelValues("success").Inc()
+		treeSize.Set(float64(head.TreeSize))
+		return head, nil
+
+	case errors.Is(err, auditor.ErrUpdateRejected):
+		// The Transparency Log has misbehaved, or sent a corrupted update. The
+		// auditor won't advance past this position until it receives a valid
+		// update, so this requires human intervention.
+		auditOps.WithLabelValues("rejected").Inc()
+		rejectedCtr.Inc()
+		log.Printf("ALERT: rejected update for log entry %d: %v", pos, err)
+		return nil, &transport.Error{Status: http.StatusUnprocessableEntity, Err: err}
+
+	case errors.Is(err, auditor.ErrUnexpectedPosition):
+		auditOps.WithLabelValues("out-of-order").Inc()
+		return nil, &transport.Error{Status: http.StatusConflict, Err: err}
+
+	default:
+		auditOps.WithLabelValues("error").Inc()
+		return nil, err
+	}
+}
@@ -0,0 +1,73 @@
+// Command katie-auditor is a Third-Party Auditor for a Transparency Log. It
+// receives an AuditorUpdate from the log operator for each new log entry,
+// verifies it, and returns a signed auditor tree head.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"runtime"
+	"time"
+
+	"github.com/Bren2010/katie/db"
+	"github.com/Bren2010/katie/tree/transparency/auditor"
+	"github.com/Bren2010/katie/tree/transparency/wire/transport"
+)
+
+var (
+	Version   = "dev"
+	GoVersion = runtime.Version()
+
+	configFile = flag.String("config", "", "Location of config file.")
+)
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile | log.LUTC)
+	flag.Parse()
+
+	// Load config from disk.
+	if *configFile == "" {
+		log.Fatalf("No config file provided, see --help.")
+	}
+	config, err := ReadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load config file: %v", err)
+	}
+
+	// Start the metrics server.
+	go metrics(config.MetricsAddr)
+
+	// Load the auditor's state.
+	tx, err := db.NewLDBAuditorStore(config.DatabaseFile)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	a, err := auditor.NewAuditor(config.LogConfig.publicConfig, config.LogConfig.auditorKey, tx)
+	if err != nil {
+		log.Fatalf("Failed to initialize auditor: %v", err)
+	}
+	service := auditor.NewService(a)
+	treeSize.Set(float64(service.TreeSize()))
+
+	// Setup the API server.
+	mux := http.NewServeMux()
+	mux.Handle(transport.AuditPath, transport.NewAuditorServer(
+		config.LogConfig.publicConfig,
+		&auditHandler{service: service},
+	))
+
+	srv := &http.Server{
+		Addr:      config.ServerAddr,
+		Handler:   mux,
+		TLSConfig: config.tlsConfig,
+
+		ReadTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       30 * time.Second,
+	}
+
+	log.Printf("Starting auditor server at: %v (tree size %d)", config.ServerAddr, service.TreeSize())
+	log.Fatal(srv.ListenAndServeTLS("", ""))
+}
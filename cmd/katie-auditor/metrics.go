@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	buildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "build_info",
+			Help: "A metric with a constant '1' value labeled by version, and goversion.",
+		},
+		[]string{"version", "goversion"},
+	)
+	auditOps = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "audit_operations",
+			Help: "Incremented for each update received, labeled by result.",
+		},
+		[]string{"result"},
+	)
+	auditDur = prometheus.NewSummary(
+		prometheus.SummaryOpts{
+			Name: "audit_duration",
+			Help: "Summary of how long it takes to process an update.",
+		},
+	)
+	rejectedCtr = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "audit_rejections",
+			Help: "Incremented for each update that fails verification. Any increase should be alerted on.",
+		},
+	)
+	treeSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "audit_tree_size",
+			Help: "The number of log entries that have been audited.",
+		},
+	)
+)
+
+func metrics(addr string) {
+	buildInfo.WithLabelValues(Version, GoVersion).Set(1)
+	prometheus.MustRegister(buildInfo)
+	prometheus.MustRegister(auditOps)
+	prometheus.MustRegister(auditDur)
+	prometheus.MustRegister(rejectedCtr)
+	prometheus.MustRegister(treeSize)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/" {
+			fmt.Fprintln(rw, "Hi, I'm a katie-auditor metrics and debugging server!")
+		} else {
+			rw.WriteHeader(404)
+			fmt.Fprintln(rw, "404 not found")
+		}
+	})
+	mux.Handle("/metrics", promhttp.Handler())
+
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	mux.HandleFunc("/debug/version", func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "Version: %s, GoVersion: %s", Version, GoVersion)
+	})
+
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: mux,
+	}
+	log.Printf("Starting metrics server at: %v", addr)
+	log.Fatal(srv.ListenAndServe())
+}
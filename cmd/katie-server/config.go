@@ -1,17 +1,18 @@
-//go:build ignore
-
 package main
 
 import (
-	"crypto/ed25519"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
 	"fmt"
-	"io/ioutil"
+	"os"
+	"time"
 
+	"github.com/Bren2010/katie/crypto/remote"
+	"github.com/Bren2010/katie/crypto/suites"
 	"github.com/Bren2010/katie/crypto/vrf"
-	"github.com/Bren2010/katie/crypto/vrf/p256"
+	"github.com/Bren2010/katie/tree/transparency/structs"
 
 	"gopkg.in/yaml.v2"
 )
@@ -23,7 +24,11 @@ type Config struct {
 	TLSConfig   *TLSConfig `yaml:"tls"`
 	tlsConfig   *tls.Config
 
-	APIConfig *APIConfig `yaml:"api"`
+	APIConfig       *APIConfig       `yaml:"api"`
+	LogConfig       *LogConfig       `yaml:"log"`
+	SequencerConfig *SequencerConfig `yaml:"sequencer"`
+	ReaperConfig    *ReaperConfig    `yaml:"reaper"`
+	AuditorConfig   *AuditorConfig   `yaml:"auditor"` // Required only in third-party-auditing mode.
 
 	DatabaseFile string `yaml:"db-file"`
 }
@@ -39,17 +44,72 @@ type TLSConfig struct {
 
 type APIConfig struct {
 	HomeRedirect string `yaml:"home"`
+}
 
-	SigningKey string `yaml:"signing-key"` // 32 byte hex-encoded seed for the signing private key.
-	signingKey ed25519.PrivateKey
+// SequencerConfig specifies how label updates are batched into log entries.
+type SequencerConfig struct {
+	MaxBatchSize  int           `yaml:"max-batch-size"`  // Optional.
+	MaxBatchDelay time.Duration `yaml:"max-batch-delay"` // Optional.
+}
+
+// ReaperConfig specifies how labels that have exceeded the maximum lifetime of
+// the Transparency Log are found and removed. It's only used when a maximum
+// lifetime is configured.
+type ReaperConfig struct {
+	Interval     time.Duration `yaml:"interval"`       // Optional. How often to scan for expired labels.
+	MaxBatchSize int           `yaml:"max-batch-size"` // Optional.
+}
 
-	VRFKey string `yaml:"vrf-key"` // PEM encoded VRF private key.
-	vrfKey vrf.PrivateKey
+// AuditorConfig specifies how to reach the Third-Party Auditor, which each new
+// log entry is sent to.
+type AuditorConfig struct {
+	URL           string        `yaml:"url"`            // Base URL of the auditor's API.
+	Timeout       time.Duration `yaml:"timeout"`        // Optional.
+	RetryInterval time.Duration `yaml:"retry-interval"` // Optional. How often to retry while the auditor is behind.
+}
+
+// SignerConfig specifies how to reach the signing daemon that holds the
+// Transparency Log's private keys. When it's used, the signing-key and vrf-key
+// fields of LogConfig contain the names of keys held by the daemon instead.
+type SignerConfig struct {
+	Socket  string        `yaml:"socket"`  // Path of the daemon's Unix socket.
+	Timeout time.Duration `yaml:"timeout"` // Optional.
+}
+
+// LogConfig specifies the configuration of the Transparency Log.
+type LogConfig struct {
+	CipherSuite string `yaml:"cipher-suite"` // Name of the cipher suite, like "KTSha256P256".
+	Mode        string `yaml:"mode"`         // One of "contact-monitoring", "third-party-management", or "third-party-auditing".
+
+	SigningKey string        `yaml:"signing-key"` // Hex-encoded signing private key.
+	VRFKey     string        `yaml:"vrf-key"`     // Hex-encoded VRF private key.
+	Signer     *SignerConfig `yaml:"signer"`      // Optional. If provided, the keys are held by a signing daemon.
+
+	// Populated only when Mode is "third-party-management".
+	LeafPublicKey string `yaml:"leaf-public-key"` // Hex-encoded public key of the Service Operator.
+
+	// Populated only when Mode is "third-party-auditing".
+	MaxAuditorLag    time.Duration `yaml:"max-auditor-lag"`
+	AuditorStartPos  uint64        `yaml:"auditor-start-pos"`
+	AuditorPublicKey string        `yaml:"auditor-public-key"` // Hex-encoded public key of the auditor.
+
+	MaxAhead                   time.Duration `yaml:"max-ahead"`
+	MaxBehind                  time.Duration `yaml:"max-behind"`
+	ReasonableMonitoringWindow time.Duration `yaml:"reasonable-monitoring-window"`
+	MaximumLifetime            time.Duration `yaml:"maximum-lifetime"` // Optional.
+
+	privateConfig structs.PrivateConfig
+}
+
+var deploymentModes = map[string]structs.DeploymentMode{
+	"contact-monitoring":     structs.ContactMonitoring,
+	"third-party-management": structs.ThirdPartyManagement,
+	"third-party-auditing":   structs.ThirdPartyAuditing,
 }
 
 func ReadConfig(filename string) (*Config, error) {
 	// Read from file and parse.
-	raw, err := ioutil.ReadFile(filename)
+	raw, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
@@ -63,17 +123,31 @@ func ReadConfig(filename string) (*Config, error) {
 		return nil, fmt.Errorf("field not provided: server-addr")
 	} else if parsed.MetricsAddr == "" {
 		return nil, fmt.Errorf("field not provided: metrics-addr")
-	} else if parsed.APIConfig == nil {
-		return nil, fmt.Errorf("field not provided: api")
-	} else if parsed.APIConfig.HomeRedirect == "" {
-		return nil, fmt.Errorf("field not provided: api.home")
-	} else if parsed.APIConfig.SigningKey == "" {
-		return nil, fmt.Errorf("field not provided: api.signing-key")
-	} else if parsed.APIConfig.VRFKey == "" {
-		return nil, fmt.Errorf("field not provided: api.vrf-key")
+	} else if parsed.LogConfig == nil {
+		return nil, fmt.Errorf("field not provided: log")
 	} else if parsed.DatabaseFile == "" {
 		return nil, fmt.Errorf("field not provided: db-file")
 	}
+	if parsed.APIConfig == nil {
+		parsed.APIConfig = &APIConfig{}
+	}
+	if parsed.SequencerConfig == nil {
+		parsed.SequencerConfig = &SequencerConfig{}
+	} else if parsed.SequencerConfig.MaxBatchSize < 0 {
+		return nil, fmt.Errorf("field must not be negative: sequencer.max-batch-size")
+	} else if parsed.SequencerConfig.MaxBatchDelay < 0 {
+		return nil, fmt.Errorf("field must not be negative: sequencer.max-batch-delay")
+	}
+	if parsed.ReaperConfig == nil {
+		parsed.ReaperConfig = &ReaperConfig{}
+	}
+	if parsed.ReaperConfig.Interval < 0 {
+		return nil, fmt.Errorf("field must not be negative: reaper.interval")
+	} else if parsed.ReaperConfig.MaxBatchSize < 0 {
+		return nil, fmt.Errorf("field must not be negative: reaper.max-batch-size")
+	} else if parsed.ReaperConfig.Interval == 0 {
+		parsed.ReaperConfig.Interval = time.Hour
+	}
 
 	// Parse TLS config if necessary.
 	if parsed.TLSConfig != nil {
@@ -83,7 +157,7 @@ func ReadConfig(filename string) (*Config, error) {
 		}
 
 		certPool := x509.NewCertPool()
-		caCerts, err := ioutil.ReadFile(parsed.TLSConfig.ClientCA)
+		caCerts, err := os.ReadFile(parsed.TLSConfig.ClientCA)
 		if err != nil {
 			return nil, fmt.Errorf("failed to load TLS client CA: %v", err)
 		} else if ok := certPool.AppendCertsFromPEM(caCerts); !ok {
@@ -97,19 +171,143 @@ func ReadConfig(filename string) (*Config, error) {
 		}
 	}
 
+	// Parse the Transparency Log's configuration.
+	if err := parsed.LogConfig.parse(); err != nil {
+		return nil, err
+	}
+	if parsed.AuditorConfig == nil {
+		parsed.AuditorConfig = &AuditorConfig{}
+	}
+	if parsed.LogConfig.privateConfig.Mode == structs.ThirdPartyAuditing {
+		if parsed.AuditorConfig.URL == "" {
+			return nil, fmt.Errorf("field not provided: auditor.url")
+		} else if parsed.AuditorConfig.Timeout < 0 {
+			return nil, fmt.Errorf("field must not be negative: auditor.timeout")
+		} else if parsed.AuditorConfig.RetryInterval < 0 {
+			return nil, fmt.Errorf("field must not be negative: auditor.retry-interval")
+		} else if parsed.AuditorConfig.RetryInterval == 0 {
+			parsed.AuditorConfig.RetryInterval = 10 * time.Second
+		}
+	}
+
+	return &parsed, nil
+}
+
+func (lc *LogConfig) parse() error {
+	cs, ok := suites.ByName(lc.CipherSuite)
+	if !ok {
+		return fmt.Errorf("unknown cipher suite: %q", lc.CipherSuite)
+	}
+	mode, ok := deploymentModes[lc.Mode]
+	if !ok {
+		return fmt.Errorf("unknown deployment mode: %q", lc.Mode)
+	}
+
+	if lc.SigningKey == "" {
+		return fmt.Errorf("field not provided: log.signing-key")
+	} else if lc.VRFKey == "" {
+		return fmt.Errorf("field not provided: log.vrf-key")
+	} else if lc.ReasonableMonitoringWindow <= 0 {
+		return fmt.Errorf("field not provided: log.reasonable-monitoring-window")
+	} else if lc.MaximumLifetime < 0 {
+		return fmt.Errorf("field must not be negative: log.maximum-lifetime")
+	} else if lc.MaximumLifetime > 0 && lc.MaximumLifetime <= lc.ReasonableMonitoringWindow {
+		return fmt.Errorf("log.maximum-lifetime must be greater than log.reasonable-monitoring-window")
+	}
+
 	// Parse cryptographic keys.
-	seed, err := hex.DecodeString(parsed.APIConfig.SigningKey)
+	sigKey, vrfKey, err := lc.parseKeys(cs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse signing key: %v", err)
-	} else if len(seed) != ed25519.SeedSize {
-		return nil, fmt.Errorf("signing key is wrong size: wanted=%v, got=%v", ed25519.SeedSize, len(seed))
+		return err
 	}
-	parsed.APIConfig.signingKey = ed25519.NewKeyFromSeed(seed)
 
-	parsed.APIConfig.vrfKey, err = p256.NewVRFSignerFromPEM([]byte(parsed.APIConfig.VRFKey))
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse vrf key: %v", err)
+	lc.privateConfig = structs.PrivateConfig{
+		SignatureKey: sigKey,
+		VrfKey:       vrfKey,
+		Config: structs.Config{
+			Suite: cs,
+			Mode:  mode,
+
+			MaxAhead:                   uint64(lc.MaxAhead.Milliseconds()),
+			MaxBehind:                  uint64(lc.MaxBehind.Milliseconds()),
+			ReasonableMonitoringWindow: uint64(lc.ReasonableMonitoringWindow.Milliseconds()),
+			MaximumLifetime:            uint64(lc.MaximumLifetime.Milliseconds()),
+		},
 	}
 
-	return &parsed, nil
+	// Parse the fields that are specific to the deployment mode.
+	switch mode {
+	case structs.ThirdPartyManagement:
+		if lc.LeafPublicKey == "" {
+			return fmt.Errorf("field not provided: log.leaf-public-key")
+		}
+		raw, err := hex.DecodeString(lc.LeafPublicKey)
+		if err != nil {
+			return fmt.Errorf("failed to parse leaf public key: %v", err)
+		}
+		lc.privateConfig.LeafPublicKey, err = cs.ParseSigningPublicKey(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse leaf public key: %v", err)
+		}
+
+	case structs.ThirdPartyAuditing:
+		if lc.AuditorPublicKey == "" {
+			return fmt.Errorf("field not provided: log.auditor-public-key")
+		} else if lc.MaxAuditorLag <= 0 {
+			return fmt.Errorf("field not provided: log.max-auditor-lag")
+		}
+		raw, err := hex.DecodeString(lc.AuditorPublicKey)
+		if err != nil {
+			return fmt.Errorf("failed to parse auditor public key: %v", err)
+		}
+		lc.privateConfig.AuditorPublicKey, err = cs.ParseSigningPublicKey(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse auditor public key: %v", err)
+		}
+		lc.privateConfig.MaxAuditorLag = uint64(lc.MaxAuditorLag.Milliseconds())
+		lc.privateConfig.AuditorStartPos = lc.AuditorStartPos
+	}
+
+	return nil
+}
+
+// parseKeys returns the Transparency Log's signing and VRF private keys, either
+// by parsing them from the config or by connecting to the signing daemon.
+func (lc *LogConfig) parseKeys(cs suites.CipherSuite) (suites.SigningPrivateKey, vrf.PrivateKey, error) {
+	if lc.Signer != nil {
+		if lc.Signer.Socket == "" {
+			return nil, nil, fmt.Errorf("field not provided: log.signer.socket")
+		} else if lc.Signer.Timeout < 0 {
+			return nil, nil, fmt.Errorf("field must not be negative: log.signer.timeout")
+		}
+		client := remote.NewClient("unix", lc.Signer.Socket, lc.Signer.Timeout)
+
+		sigKey, err := remote.NewSigner(context.Background(), client, cs, lc.SigningKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load signing key: %v", err)
+		}
+		vrfKey, err := remote.NewProver(context.Background(), client, cs, lc.VRFKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load vrf key: %v", err)
+		}
+		return sigKey, vrfKey, nil
+	}
+
+	rawSigKey, err := hex.DecodeString(lc.SigningKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse signing key: %v", err)
+	}
+	sigKey, err := cs.ParseSigningPrivateKey(rawSigKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse signing key: %v", err)
+	}
+	rawVrfKey, err := hex.DecodeString(lc.VRFKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse vrf key: %v", err)
+	}
+	vrfKey, err := cs.ParseVRFPrivateKey(rawVrfKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse vrf key: %v", err)
+	}
+	return sigKey, vrfKey, nil
 }
@@ -1,238 +1,210 @@
-//go:build ignore
-
 package main
 
 import (
-	"crypto/ecdsa"
-	"crypto/ed25519"
-	"crypto/elliptic"
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
-	"strconv"
-	"strings"
-	"time"
 
 	"github.com/Bren2010/katie/db"
 	"github.com/Bren2010/katie/tree/transparency"
-	"github.com/gorilla/mux"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/wire"
+	"github.com/Bren2010/katie/tree/transparency/wire/transport"
 )
 
-// ApiResponse wraps either response data or an error message with a "success"
-// indicator boolean.
-type ApiResponse struct {
-	Success  bool        `json:"success"`
-	Response interface{} `json:"response,omitempty"`
-	Message  string      `json:"message,omitempty"`
+// statusRecorder wraps an http.ResponseWriter to record the status code of the
+// response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
 }
 
-// HttpError wraps an error that occurred while processing an HTTP request with
-// the HTTP status code that should be returned.
-type HttpError struct {
-	Status int
-	Err    error
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
 }
 
-func extractPath(req *http.Request) string {
-	full := req.URL.Path
-	if len(full) > 0 && full[0] == '/' {
-		full = full[1:]
-	}
-	parts := strings.Split(full, "/")
-	if len(parts) == 0 {
-		return "/"
-	} else if len(parts) == 1 {
-		return "/" + parts[0]
-	}
-	out := "/" + parts[0] + "/" + parts[1]
-	if out == "/v1/account" && req.Method == "POST" {
-		out = "POST:" + out
+func (sr *statusRecorder) Flush() {
+	if flusher, ok := sr.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
 	}
-	return out
 }
 
-// HandleAPI takes an API handler function as input and turns it into an
-// http.HandlerFunc by adding error handling.
-func HandleAPI(inner func(rw http.ResponseWriter, req *http.Request) *HttpError) http.HandlerFunc {
+// knownPaths is the set of paths that requests are counted under. Requests for
+// any other path are counted together, to keep the number of metrics bounded.
+var knownPaths = map[string]struct{}{
+	transport.SearchPath:         {},
+	transport.BatchSearchPath:    {},
+	transport.HistoryPath:        {},
+	transport.ConsistencyPath:    {},
+	transport.ContactMonitorPath: {},
+	transport.OwnerInitPath:      {},
+	transport.OwnerMonitorPath:   {},
+	transport.UpdatePath:         {},
+	transport.ManagerUpdatePath:  {},
+}
+
+// countRequests wraps an http.Handler to count requests by path and status
+// code.
+func countRequests(inner http.Handler) http.HandlerFunc {
 	return func(rw http.ResponseWriter, req *http.Request) {
-		path := extractPath(req)
-
-		if err := inner(rw, req); err != nil {
-			requestCtr.WithLabelValues(path, fmt.Sprint(err.Status)).Inc()
-			log.Printf("%v(%v): %v", req.URL.Path, err.Status, err.Err)
-
-			rw.WriteHeader(err.Status)
-			json.NewEncoder(rw).Encode(ApiResponse{
-				Success: false,
-				Message: err.Err.Error(),
-			})
-		} else {
-			requestCtr.WithLabelValues(path, "200").Inc()
+		sr := &statusRecorder{ResponseWriter: rw, status: http.StatusOK}
+		inner.ServeHTTP(sr, req)
+
+		path := req.URL.Path
+		if _, ok := knownPaths[path]; !ok {
+			path = "other"
 		}
+		requestCtr.WithLabelValues(path, fmt.Sprint(sr.status)).Inc()
 	}
 }
 
 type Handler struct {
 	config *APIConfig
-	tx     db.TransparencyStore
-	ch     chan<- InsertRequest
 }
 
 // Home redirects requests to a pre-configured URL, like the API documentation.
 func (h *Handler) Home(rw http.ResponseWriter, req *http.Request) {
+	if h.config.HomeRedirect == "" {
+		http.NotFound(rw, req)
+		return
+	}
 	http.Redirect(rw, req, h.config.HomeRedirect, http.StatusSeeOther)
 }
 
-type MetaResponse struct {
-	HashAlgorithm      string `json:"hash_algorithm"`
-	SignatureAlgorithm string `json:"signature_algorithm"`
-	VRFAlgorithm       string `json:"vrf_algorithm"`
-	SigningKey         []byte `json:"signing_key"`
-	VRFKey             []byte `json:"vrf_key"`
+// logView implements wire.ManagerInterface by creating a new Transparency Tree
+// over a snapshot of the database for each request, since a Tree is not safe
+// for concurrent use. The snapshot is released once the request is done, so
+// that it doesn't prevent the database from reclaiming deleted data.
+type logView struct {
+	private structs.PrivateConfig
+	tx      db.TransparencyStore
+	ch      chan<- transparency.UpdateRequest
 }
 
-// Meta returns a JSON object with the log's configuration and public keys.
-func (h *Handler) Meta(rw http.ResponseWriter, req *http.Request) *HttpError {
-	if req.Method != "GET" {
-		return &HttpError{http.StatusMethodNotAllowed, fmt.Errorf("method not allowed")}
-	}
-	vrfPub := h.config.vrfKey.Public().(*ecdsa.PublicKey)
-	res := ApiResponse{
-		Success: true,
-		Response: MetaResponse{
-			HashAlgorithm:      "sha256",
-			SignatureAlgorithm: "ed25519",
-			VRFAlgorithm:       "p256",
-			SigningKey:         h.config.signingKey.Public().(ed25519.PublicKey),
-			VRFKey:             elliptic.Marshal(vrfPub.Curve, vrfPub.X, vrfPub.Y),
-		},
+var _ wire.ManagerInterface = &logView{}
+
+// tree returns a Transparency Tree over a new snapshot of the database, and a
+// function that releases the snapshot.
+func (lv *logView) tree() (*transparency.Tree, func(), error) {
+	tx, err := lv.tx.Clone()
+	if err != nil {
+		return nil, nil, err
 	}
-	if err := json.NewEncoder(rw).Encode(res); err != nil {
-		return &HttpError{http.StatusInternalServerError, err}
+	tree, err := transparency.NewTree(lv.private, tx, lv.ch)
+	if err != nil {
+		tx.Release()
+		return nil, nil, err
 	}
+	return tree, tx.Release, nil
+}
 
-	return nil
+// releaseAfter returns a channel that forwards the responses from `in`, and
+// calls `release` once `in` is closed.
+func releaseAfter(ctx context.Context, in <-chan wire.UpdateResponse, release func()) <-chan wire.UpdateResponse {
+	out := make(chan wire.UpdateResponse)
+	go func() {
+		defer close(out)
+		defer release()
+		for res := range in {
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				// The stream is closed shortly after the context is done.
+				for range in {
+				}
+				return
+			}
+		}
+	}()
+	return out
 }
 
-// Consistency returns a consistency proof between two versions of the log.
-func (h *Handler) Consistency(rw http.ResponseWriter, req *http.Request) *HttpError {
-	if req.Method != "GET" {
-		return &HttpError{http.StatusMethodNotAllowed, fmt.Errorf("method not allowed")}
+func (lv *logView) Search(ctx context.Context, req *structs.SearchRequest) (*structs.SearchResponse, error) {
+	tree, release, err := lv.tree()
+	if err != nil {
+		return nil, err
 	}
-	vars := mux.Vars(req)
+	defer release()
+	return tree.Search(ctx, req)
+}
 
-	older, err := strconv.Atoi(vars["older"])
+func (lv *logView) BatchSearch(ctx context.Context, req *structs.BatchSearchRequest) (*structs.BatchSearchResponse, error) {
+	tree, release, err := lv.tree()
 	if err != nil {
-		return &HttpError{http.StatusBadRequest, fmt.Errorf("request path had unexpected format")}
+		return nil, err
 	}
-	newer, err := strconv.Atoi(vars["newer"])
+	defer release()
+	return tree.BatchSearch(ctx, req)
+}
+
+func (lv *logView) History(ctx context.Context, req *structs.HistoryRequest) (*structs.HistoryResponse, error) {
+	tree, release, err := lv.tree()
 	if err != nil {
-		return &HttpError{http.StatusBadRequest, fmt.Errorf("request path had unexpected format")}
+		return nil, err
 	}
+	defer release()
+	return tree.History(ctx, req)
+}
 
-	tree, err := transparency.NewTree(h.config.signingKey, h.config.vrfKey, h.tx)
+func (lv *logView) Consistency(ctx context.Context, req *structs.ConsistencyRequest) (*structs.ConsistencyResponse, error) {
+	tree, release, err := lv.tree()
 	if err != nil {
-		return &HttpError{http.StatusInternalServerError, err}
+		return nil, err
 	}
-	proof, err := tree.GetConsistency(older, newer)
+	defer release()
+	return tree.Consistency(ctx, req)
+}
+
+func (lv *logView) ContactMonitor(ctx context.Context, req *structs.ContactMonitorRequest) (*structs.ContactMonitorResponse, error) {
+	tree, release, err := lv.tree()
 	if err != nil {
-		return &HttpError{http.StatusBadRequest, err}
+		return nil, err
 	}
-	res := ApiResponse{Success: true, Response: proof}
-	if err := json.NewEncoder(rw).Encode(res); err != nil {
-		return &HttpError{http.StatusInternalServerError, err}
-	}
-
-	return nil
+	defer release()
+	return tree.ContactMonitor(ctx, req)
 }
 
-// Account handles both getting most recent account data over the GET method,
-// and updating account data over the POST method.
-func (h *Handler) Account(rw http.ResponseWriter, req *http.Request) *HttpError {
-	// TOOD: Figure out some kind of authentication / domain-separation.
-	if req.Method != "GET" && req.Method != "POST" {
-		return &HttpError{http.StatusMethodNotAllowed, fmt.Errorf("method not allowed")}
+func (lv *logView) OwnerInit(ctx context.Context, req *structs.OwnerInitRequest) (*structs.OwnerInitResponse, error) {
+	tree, release, err := lv.tree()
+	if err != nil {
+		return nil, err
 	}
-	vars := mux.Vars(req)
-	account := vars["account"]
+	defer release()
+	return tree.OwnerInit(ctx, req)
+}
 
-	last := -1
-	if str, ok := vars["last"]; ok {
-		parsed, err := strconv.Atoi(str)
-		if err != nil {
-			return &HttpError{http.StatusBadRequest, fmt.Errorf("request path had unexpected format")}
-		}
-		last = parsed
+func (lv *logView) OwnerMonitor(ctx context.Context, req *structs.OwnerMonitorRequest) (*structs.OwnerMonitorResponse, error) {
+	tree, release, err := lv.tree()
+	if err != nil {
+		return nil, err
 	}
+	defer release()
+	return tree.OwnerMonitor(ctx, req)
+}
 
-	tree, err := transparency.NewTree(h.config.signingKey, h.config.vrfKey, h.tx)
+func (lv *logView) Update(ctx context.Context, req *structs.UpdateRequest) (<-chan wire.UpdateResponse, error) {
+	tree, release, err := lv.tree()
 	if err != nil {
-		return &HttpError{http.StatusInternalServerError, err}
-	}
-
-	// If the request is over POST, read the request body and submit it to the
-	// inserter goroutine to be added to the log.
-	if req.Method == "POST" {
-		value := make([]byte, 16*1024)
-
-		n := 0
-		for {
-			m, err := req.Body.Read(value[n:])
-			n += m
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				return &HttpError{http.StatusInternalServerError, err}
-			} else if n == len(value) {
-				return &HttpError{http.StatusBadRequest, fmt.Errorf("request body is too large")}
-			}
-		}
-		if n == 0 {
-			return &HttpError{http.StatusBadRequest, fmt.Errorf("empty request body not allowed")}
-		}
-
-		resp := make(chan InsertResponse)
-		timer := time.NewTimer(5 * time.Second)
-		select {
-		case h.ch <- InsertRequest{Key: account, Value: value[:n], Resp: resp}:
-		case <-timer.C:
-			return &HttpError{http.StatusInternalServerError, fmt.Errorf("submitting insertion request timed out")}
-		}
-		var root *db.TransparencyTreeRoot
-		select {
-		case res := <-resp:
-			if res.Err != nil {
-				return &HttpError{http.StatusInternalServerError, res.Err}
-			}
-			root = res.Root
-		case <-timer.C:
-			return &HttpError{http.StatusInternalServerError, fmt.Errorf("waiting for insertion result timed out")}
-		}
-		if !timer.Stop() {
-			<-timer.C
-		}
-
-		tree.SetLatest(root)
+		return nil, err
 	}
-
-	// Search for the most recent version of account data.
-	sr, err := tree.Search(account)
+	ch, err := tree.Update(ctx, req)
 	if err != nil {
-		return &HttpError{http.StatusInternalServerError, err}
+		release()
+		return nil, err
 	}
-	if last != -1 {
-		// Add a consistency proof if requested.
-		sr.Consistency, err = tree.GetConsistency(last, int(sr.Root.TreeSize))
-		if err != nil {
-			return &HttpError{http.StatusInternalServerError, err}
-		}
+	return releaseAfter(ctx, ch, release), nil
+}
+
+func (lv *logView) ManagerUpdate(ctx context.Context, req *structs.ManagerUpdateRequest) (<-chan wire.UpdateResponse, error) {
+	tree, release, err := lv.tree()
+	if err != nil {
+		return nil, err
 	}
-	res := ApiResponse{Success: true, Response: sr}
-	if err := json.NewEncoder(rw).Encode(res); err != nil {
-		return &HttpError{http.StatusInternalServerError, err}
+	ch, err := tree.ManagerUpdate(ctx, req)
+	if err != nil {
+		release()
+		return nil, err
 	}
-
-	return nil
+	return releaseAfter(ctx, ch, release), nil
 }
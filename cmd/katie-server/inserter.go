@@ -1,41 +1,159 @@
-//go:build ignore
-
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
-	"github.com/Bren2010/katie/db"
 	"github.com/Bren2010/katie/tree/transparency"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/wire"
 )
 
-type InsertRequest struct {
-	Key   string
-	Value []byte
-	Resp  chan<- InsertResponse
-}
+// auditorBatchSize is the maximum number of AuditorUpdates that are loaded
+// from the database at once.
+const auditorBatchSize = 100
 
-type InsertResponse struct {
-	Root *db.TransparencyTreeRoot
-	Err  error
-}
+// inserter is a goroutine that sequences the update requests received by `seq`
+// into new log entries. If `reaper` is not nil, it is used to periodically
+// remove expired labels from the tree. If `feed` is not nil, it is used to
+// bring the Third-Party Auditor up-to-date after each new log entry. While the
+// auditor is behind, the inserter wakes up every `retry` to try again.
+func inserter(seq *transparency.Sequencer, feed *auditorFeed, reaper *reaperTask, retry time.Duration) {
+	ctx := context.Background()
 
-// inserter is a goroutine that receives insertion requests over `ch`, adds the
-// requested data to the tree, and responds with the new tree root.
-func inserter(tree *transparency.Tree, ch chan InsertRequest) {
 	for {
-		req := <-ch
+		var deadline time.Time
+		if reaper != nil {
+			deadline = reaper.run()
+		}
 
+		behind := false
+		if feed != nil {
+			var err error
+			behind, err = feed.sync(ctx)
+			if err != nil {
+				log.Printf("failed to send log entries to auditor: %v", err)
+			}
+		}
+		if retryAt := time.Now().Add(retry); behind && (deadline.IsZero() || retryAt.Before(deadline)) {
+			deadline = retryAt
+		}
+
+		nextCtx, cancel := ctx, context.CancelFunc(func() {})
+		if !deadline.IsZero() {
+			nextCtx, cancel = context.WithDeadline(ctx, deadline)
+		}
 		start := time.Now()
-		root, err := tree.Insert(req.Key, req.Value)
+		_, err := seq.Next(nextCtx)
+		cancel()
+		if err == transparency.ErrSequencerClosed {
+			return
+		} else if !deadline.IsZero() && errors.Is(err, context.DeadlineExceeded) {
+			continue
+		}
 		insertOps.WithLabelValues(fmt.Sprint(err == nil)).Inc()
 		insertDur.Observe(float64(time.Since(start).Microseconds()))
 
-		select {
-		case req.Resp <- InsertResponse{root, err}:
-		default:
+		if err != nil {
+			log.Printf("failed to sequence label updates: %v", err)
 		}
 	}
 	// TODO: Restart thread in case of panic.
 }
+
+// reaperTask runs a Reaper every `interval`. It must only be used from the
+// inserter goroutine, since it shares the tree with the sequencer.
+type reaperTask struct {
+	reaper   *transparency.Reaper
+	interval time.Duration
+	next     time.Time
+}
+
+// run removes a batch of expired labels if it's time to do so, and returns the
+// time that it should be called again. If any labels were removed, there may
+// be more and it should be called again right away. Otherwise, the prefix tree
+// tiles of expired log entries are garbage collected.
+func (rt *reaperTask) run() time.Time {
+	if time.Now().Before(rt.next) {
+		return rt.next
+	}
+	update, err := rt.reaper.Reap()
+	reapOps.WithLabelValues(fmt.Sprint(err == nil)).Inc()
+	rt.next = time.Now()
+	if err != nil {
+		log.Printf("failed to remove expired labels: %v", err)
+	} else if update != nil {
+		log.Printf("removed %d expired label-versions", len(update.Removed))
+		return rt.next
+	}
+
+	// Once there are no more expired labels to remove, delete the prefix tree
+	// tiles that are only needed by expired log entries.
+	stats, err := rt.reaper.CollectGarbage()
+	if err != nil {
+		log.Printf("failed to collect garbage from prefix tree: %v", err)
+	} else if stats.Tiles > 0 {
+		log.Printf("deleted %d prefix tree tiles (%d bytes)", stats.Tiles, stats.Bytes)
+	}
+	rt.next = rt.next.Add(rt.interval)
+	return rt.next
+}
+
+// auditorFeed sends the AuditorUpdates that are persisted by the Transparency
+// Tree to the Third-Party Auditor in order, and provides the auditor tree heads
+// that it returns back to the tree. It must only be used from the inserter
+// goroutine, since it shares the tree with the sequencer.
+type auditorFeed struct {
+	tree     *transparency.Tree
+	auditor  wire.AuditorInterface
+	startPos uint64
+}
+
+// sync sends all of the log entries that the auditor hasn't acknowledged yet,
+// starting at AuditorStartPos if the auditor hasn't acknowledged any. It
+// returns true if the auditor is still behind afterwards.
+//
+// An auditor with a non-zero start position is expected to have been
+// provisioned with its state as of that position, since it is never sent the
+// log entries before it.
+func (af *auditorFeed) sync(ctx context.Context) (bool, error) {
+	if af.tree.TreeHead() == nil {
+		return false, nil
+	}
+	n := af.tree.TreeHead().TreeSize
+
+	start := af.startPos
+	if head := af.tree.AuditorTreeHead(); head != nil {
+		start = head.TreeSize
+	}
+	for start < n {
+		end := min(n, start+auditorBatchSize)
+		updates, err := af.tree.AuditorUpdates(start, end)
+		if err != nil {
+			return true, err
+		}
+		for i, update := range updates {
+			if err := af.send(ctx, start+uint64(i), update); err != nil {
+				return true, err
+			}
+		}
+		start = end
+	}
+	return false, nil
+}
+
+// send sends the AuditorUpdate for the log entry at `pos` to the auditor and
+// stores the auditor tree head that it returns.
+func (af *auditorFeed) send(ctx context.Context, pos uint64, update *structs.AuditorUpdate) error {
+	head, err := af.auditor.Audit(ctx, pos, update)
+	auditOps.WithLabelValues(fmt.Sprint(err == nil)).Inc()
+	if err != nil {
+		return err
+	} else if head.TreeSize != pos+1 {
+		return fmt.Errorf("auditor returned tree head for unexpected tree size: %d", head.TreeSize)
+	}
+	return af.tree.SetAuditorTreeHead(head)
+}
@@ -1,5 +1,3 @@
-//go:build ignore
-
 // Command katie-server is the main server process that answers all client
 // requests and sequences new changes to the log.
 package main
@@ -13,7 +11,8 @@ import (
 
 	"github.com/Bren2010/katie/db"
 	"github.com/Bren2010/katie/tree/transparency"
-	"github.com/gorilla/mux"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/wire/transport"
 )
 
 var (
@@ -44,32 +43,66 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
-	tree, err := transparency.NewTree(config.APIConfig.signingKey, config.APIConfig.vrfKey, tx)
+	ch := make(chan transparency.UpdateRequest)
+	tree, err := transparency.NewTree(config.LogConfig.privateConfig, tx, ch)
 	if err != nil {
-		log.Fatalf("failed to initialize tree: %v", err)
+		log.Fatalf("Failed to initialize tree: %v", err)
 	}
-	ch := make(chan InsertRequest)
 
-	go inserter(tree, ch)
+	seq := transparency.NewSequencer(tree, ch, transparency.SequencerConfig{
+		MaxBatchSize:  config.SequencerConfig.MaxBatchSize,
+		MaxBatchDelay: config.SequencerConfig.MaxBatchDelay,
+	})
+	var feed *auditorFeed
+	if config.LogConfig.privateConfig.Mode == structs.ThirdPartyAuditing {
+		feed = &auditorFeed{
+			tree: tree,
+			auditor: transport.NewClient(
+				config.LogConfig.privateConfig.Public(),
+				config.AuditorConfig.URL,
+				&http.Client{Timeout: config.AuditorConfig.Timeout},
+			),
+			startPos: config.LogConfig.privateConfig.AuditorStartPos,
+		}
+	}
+	var reaper *reaperTask
+	if config.LogConfig.privateConfig.MaximumLifetime > 0 {
+		reaper = &reaperTask{
+			reaper: transparency.NewReaper(tree, transparency.ReaperConfig{
+				MaxBatchSize: config.ReaperConfig.MaxBatchSize,
+			}),
+			interval: config.ReaperConfig.Interval,
+		}
+	}
+	go inserter(seq, feed, reaper, config.AuditorConfig.RetryInterval)
 
 	// Setup handler for the API server.
-	h := &Handler{config: config.APIConfig, tx: tx.Clone(), ch: ch}
-	r := mux.NewRouter()
-	r.HandleFunc("/", h.Home)
-	r.HandleFunc("/v1/meta", HandleAPI(h.Meta))
-	r.HandleFunc("/v1/consistency/{older:[0-9]+}/{newer:[0-9]+}", HandleAPI(h.Consistency))
-	r.HandleFunc("/v1/account/{account}", HandleAPI(h.Account))
-	r.HandleFunc("/v1/account/{account}/{last:[0-9]+}", HandleAPI(h.Account))
+	view := &logView{
+		private: config.LogConfig.privateConfig,
+		tx:      tx,
+		ch:      ch,
+	}
+	var api *transport.Server
+	if config.LogConfig.privateConfig.Mode == structs.ThirdPartyManagement {
+		api = transport.NewManagerServer(config.LogConfig.privateConfig.Public(), view)
+	} else {
+		api = transport.NewServer(view)
+	}
+	h := &Handler{config: config.APIConfig}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", h.Home)
+	mux.Handle("/v1/", countRequests(api))
 
 	// Setup the API server.
 	srv := &http.Server{
 		Addr:      config.ServerAddr,
-		Handler:   r,
+		Handler:   mux,
 		TLSConfig: config.tlsConfig,
 
 		ReadTimeout:       10 * time.Second,
 		ReadHeaderTimeout: 10 * time.Second,
-		WriteTimeout:      10 * time.Second,
+		WriteTimeout:      30 * time.Second,
 		IdleTimeout:       30 * time.Second,
 	}
 
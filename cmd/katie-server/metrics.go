@@ -1,5 +1,3 @@
-//go:build ignore
-
 package main
 
 import (
@@ -33,6 +31,20 @@ var (
 			Help: "Summary of how long an insert operation takes to complete.",
 		},
 	)
+	reapOps = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "reap_operations",
+			Help: "Incremented for each scan for expired labels, labeled by success or failure.",
+		},
+		[]string{"success"},
+	)
+	auditOps = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "audit_operations",
+			Help: "Incremented for each log entry sent to the auditor, labeled by success or failure.",
+		},
+		[]string{"success"},
+	)
 	requestCtr = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "requests",
@@ -47,6 +59,8 @@ func metrics(addr string) {
 	prometheus.MustRegister(buildInfo)
 	prometheus.MustRegister(insertOps)
 	prometheus.MustRegister(insertDur)
+	prometheus.MustRegister(reapOps)
+	prometheus.MustRegister(auditOps)
 	prometheus.MustRegister(requestCtr)
 
 	mux := http.NewServeMux()
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/Bren2010/katie/crypto/suites"
+	"github.com/Bren2010/katie/crypto/vrf"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config specifies the file format of config files.
+type Config struct {
+	Socket string       `yaml:"socket"` // Path of the Unix socket to listen on.
+	Keys   []*KeyConfig `yaml:"keys"`
+
+	signingKeys map[string]suites.SigningPrivateKey
+	vrfKeys     map[string]vrf.PrivateKey
+}
+
+// KeyConfig specifies one of the private keys held by the signing daemon.
+type KeyConfig struct {
+	Name        string `yaml:"name"`
+	Type        string `yaml:"type"`         // Either "signing" or "vrf".
+	CipherSuite string `yaml:"cipher-suite"` // Name of the cipher suite, like "KTSha256P256".
+	Key         string `yaml:"key"`          // Hex-encoded private key.
+}
+
+func ReadConfig(filename string) (*Config, error) {
+	// Read from file and parse.
+	raw, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var parsed Config
+	if err := yaml.Unmarshal(raw, &parsed); err != nil {
+		return nil, err
+	}
+
+	// Check that all required fields are populated.
+	if parsed.Socket == "" {
+		return nil, fmt.Errorf("field not provided: socket")
+	} else if len(parsed.Keys) == 0 {
+		return nil, fmt.Errorf("field not provided: keys")
+	}
+
+	// Parse each of the keys.
+	parsed.signingKeys = make(map[string]suites.SigningPrivateKey)
+	parsed.vrfKeys = make(map[string]vrf.PrivateKey)
+	for i, kc := range parsed.Keys {
+		if kc == nil || kc.Name == "" {
+			return nil, fmt.Errorf("field not provided: keys[%d].name", i)
+		} else if kc.Key == "" {
+			return nil, fmt.Errorf("field not provided: keys[%d].key", i)
+		}
+		cs, ok := suites.ByName(kc.CipherSuite)
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite: %q", kc.CipherSuite)
+		}
+		rawKey, err := hex.DecodeString(kc.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %q: %v", kc.Name, err)
+		}
+
+		switch kc.Type {
+		case "signing":
+			if _, ok := parsed.signingKeys[kc.Name]; ok {
+				return nil, fmt.Errorf("duplicate signing key: %q", kc.Name)
+			}
+			parsed.signingKeys[kc.Name], err = cs.ParseSigningPrivateKey(rawKey)
+		case "vrf":
+			if _, ok := parsed.vrfKeys[kc.Name]; ok {
+				return nil, fmt.Errorf("duplicate vrf key: %q", kc.Name)
+			}
+			parsed.vrfKeys[kc.Name], err = cs.ParseVRFPrivateKey(rawKey)
+		default:
+			return nil, fmt.Errorf("unknown key type: %q", kc.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse key %q: %v", kc.Name, err)
+		}
+	}
+
+	return &parsed, nil
+}
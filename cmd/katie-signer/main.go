@@ -0,0 +1,49 @@
+// Command katie-signer is a signing daemon that holds the private keys of a
+// Transparency Log, and answers signing and VRF requests from katie-server over
+// a Unix socket.
+package main
+
+import (
+	"errors"
+	"flag"
+	"io/fs"
+	"log"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/Bren2010/katie/crypto/remote"
+)
+
+var configFile = flag.String("config", "", "Location of config file.")
+
+func main() {
+	log.SetFlags(log.LstdFlags | log.Lshortfile | log.LUTC)
+	flag.Parse()
+
+	// Load config from disk.
+	if *configFile == "" {
+		log.Fatalf("No config file provided, see --help.")
+	}
+	config, err := ReadConfig(*configFile)
+	if err != nil {
+		log.Fatalf("Failed to load config file: %v", err)
+	}
+
+	// Remove the socket left behind by a previous run, if any, and listen on a
+	// new one that only the current user has access to. The umask is set while
+	// the socket is created so that it never exists with wider permissions.
+	if err := os.Remove(config.Socket); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatalf("Failed to remove old socket: %v", err)
+	}
+	mask := syscall.Umask(0177)
+	ln, err := net.Listen("unix", config.Socket)
+	syscall.Umask(mask)
+	if err != nil {
+		log.Fatalf("Failed to listen on socket: %v", err)
+	}
+
+	log.Printf("Starting signing daemon at: %v", config.Socket)
+	server := remote.NewServer(config.signingKeys, config.vrfKeys)
+	log.Fatal(server.Serve(ln))
+}
@@ -0,0 +1,202 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Bren2010/katie/crypto/suites"
+	"github.com/Bren2010/katie/crypto/vrf"
+)
+
+// DefaultTimeout is the timeout used by a Client if none is given.
+const DefaultTimeout = 5 * time.Second
+
+// Client makes requests to a signing daemon. Each request is sent over a new
+// connection, so a Client is safe for concurrent use.
+type Client struct {
+	network, address string
+	timeout          time.Duration
+}
+
+// NewClient returns a new Client for the signing daemon listening at `address`
+// on the given network, like "unix". Each request made by the client fails if
+// it takes longer than `timeout`. If `timeout` is zero, DefaultTimeout is used.
+func NewClient(network, address string, timeout time.Duration) *Client {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Client{network: network, address: address, timeout: timeout}
+}
+
+// call sends a request for operation `op` to the daemon and returns the fields
+// of the response. It expects the response to contain `n` fields.
+func (c *Client) call(ctx context.Context, op byte, n int, fields ...[]byte) ([][]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// Unblock any pending reads or writes when the context is done.
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	kind, res, err := func() (byte, [][]byte, error) {
+		if err := writeFrame(conn, op, fields...); err != nil {
+			return 0, nil, err
+		}
+		return readFrame(conn)
+	}()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case StatusOK:
+		if len(res) != n {
+			return nil, errors.New("unexpected number of fields in response")
+		}
+		return res, nil
+	case StatusError:
+		if len(res) != 1 {
+			return nil, errors.New("unexpected number of fields in response")
+		}
+		return nil, fmt.Errorf("signing daemon returned error: %s", res[0])
+	default:
+		return nil, errors.New("unexpected response status")
+	}
+}
+
+// SigningPublicKey returns the encoded public key of the named signing key.
+func (c *Client) SigningPublicKey(ctx context.Context, name string) ([]byte, error) {
+	res, err := c.call(ctx, OpSigningPublicKey, 1, []byte(name))
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+// Sign returns the signature of `message` with the named signing key.
+func (c *Client) Sign(ctx context.Context, name string, message []byte) ([]byte, error) {
+	res, err := c.call(ctx, OpSign, 1, []byte(name), message)
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+// VRFPublicKey returns the encoded public key of the named VRF key.
+func (c *Client) VRFPublicKey(ctx context.Context, name string) ([]byte, error) {
+	res, err := c.call(ctx, OpVRFPublicKey, 1, []byte(name))
+	if err != nil {
+		return nil, err
+	}
+	return res[0], nil
+}
+
+// Prove returns the VRF output for `m` with the named VRF key, and the proof
+// that the output is correct.
+func (c *Client) Prove(ctx context.Context, name string, m []byte) (output, proof []byte, err error) {
+	res, err := c.call(ctx, OpProve, 2, []byte(name), m)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res[0], res[1], nil
+}
+
+// Signer implements suites.SigningPrivateKey by forwarding signing requests to
+// a signing daemon.
+type Signer struct {
+	client *Client
+	name   string
+	public suites.SigningPublicKey
+}
+
+var _ suites.SigningPrivateKey = &Signer{}
+
+// NewSigner returns a Signer for the signing key with the given name. The
+// public key is fetched from the daemon and parsed with `cs`.
+func NewSigner(ctx context.Context, client *Client, cs suites.CipherSuite, name string) (*Signer, error) {
+	raw, err := client.SigningPublicKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	public, err := cs.ParseSigningPublicKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{client: client, name: name, public: public}, nil
+}
+
+// SignContext signs `message`, using `ctx` for the request to the daemon.
+func (s *Signer) SignContext(ctx context.Context, message []byte) ([]byte, error) {
+	sig, err := s.client.Sign(ctx, s.name, message)
+	if err != nil {
+		return nil, err
+	} else if !s.public.Verify(message, sig) {
+		return nil, errors.New("signing daemon returned invalid signature")
+	}
+	return sig, nil
+}
+
+func (s *Signer) Sign(message []byte) ([]byte, error) {
+	return s.SignContext(context.Background(), message)
+}
+
+func (s *Signer) Public() suites.SigningPublicKey { return s.public }
+
+// Prover implements vrf.PrivateKey by forwarding proving requests to a signing
+// daemon.
+type Prover struct {
+	client *Client
+	name   string
+	public vrf.PublicKey
+}
+
+var _ vrf.PrivateKey = &Prover{}
+
+// NewProver returns a Prover for the VRF key with the given name. The public
+// key is fetched from the daemon and parsed with `cs`.
+func NewProver(ctx context.Context, client *Client, cs suites.CipherSuite, name string) (*Prover, error) {
+	raw, err := client.VRFPublicKey(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	public, err := cs.ParseVRFPublicKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Prover{client: client, name: name, public: public}, nil
+}
+
+// ProveContext computes the VRF output for `m` and the proof that it's correct,
+// using `ctx` for the request to the daemon.
+func (p *Prover) ProveContext(ctx context.Context, m []byte) (output, proof []byte, err error) {
+	output, proof, err = p.client.Prove(ctx, p.name, m)
+	if err != nil {
+		return nil, nil, err
+	}
+	verified, err := p.public.Verify(m, proof)
+	if err != nil {
+		return nil, nil, fmt.Errorf("signing daemon returned invalid proof: %w", err)
+	} else if !bytes.Equal(verified, output) {
+		return nil, nil, errors.New("signing daemon returned incorrect output")
+	}
+	return output, proof, nil
+}
+
+func (p *Prover) Prove(m []byte) (output, proof []byte, err error) {
+	return p.ProveContext(context.Background(), m)
+}
+
+func (p *Prover) PublicKey() vrf.PublicKey { return p.public }
@@ -0,0 +1,99 @@
+// Package remote implements signing and VRF private keys that are held by a
+// separate signing daemon, and the daemon's side of the protocol.
+//
+// The client and the daemon communicate over a stream connection, usually a
+// Unix socket. Each request and each response is sent as a frame: a 4-byte
+// big-endian length, followed by that many bytes. A request frame contains a
+// 1-byte operation followed by a list of fields, and a response frame contains
+// a 1-byte status followed by a list of fields. Each field is encoded as a
+// 4-byte big-endian length, followed by that many bytes.
+//
+// The supported operations are:
+//
+//	OpSigningPublicKey: [key name]          -> [public key]
+//	OpSign:             [key name, message] -> [signature]
+//	OpVRFPublicKey:     [key name]          -> [public key]
+//	OpProve:            [key name, input]   -> [output, proof]
+//
+// If the status of a response is not StatusOK, the response contains a single
+// field with an error message. Several requests may be sent over the same
+// connection, one after the other.
+package remote
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Operations that may be requested from the signing daemon.
+const (
+	OpSigningPublicKey byte = 1
+	OpSign             byte = 2
+	OpVRFPublicKey     byte = 3
+	OpProve            byte = 4
+)
+
+// Response statuses.
+const (
+	StatusOK    byte = 0
+	StatusError byte = 1
+)
+
+// MaxFrameSize is the maximum size of a request or response frame, in bytes.
+const MaxFrameSize = 16 * 1024 * 1024
+
+// writeFrame writes a frame containing the 1-byte `kind` followed by `fields`
+// to `w`.
+func writeFrame(w io.Writer, kind byte, fields ...[]byte) error {
+	size := 1
+	for _, field := range fields {
+		size += 4 + len(field)
+	}
+	if size > MaxFrameSize {
+		return errors.New("frame is too large")
+	}
+
+	buf := make([]byte, 0, 4+size)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(size))
+	buf = append(buf, kind)
+	for _, field := range fields {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(field)))
+		buf = append(buf, field...)
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFrame reads a frame from `r`, returning its 1-byte kind and its fields.
+func readFrame(r io.Reader) (kind byte, fields [][]byte, err error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size == 0 {
+		return 0, nil, errors.New("frame is empty")
+	} else if size > MaxFrameSize {
+		return 0, nil, errors.New("frame is too large")
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+
+	kind, body = body[0], body[1:]
+	for len(body) > 0 {
+		if len(body) < 4 {
+			return 0, nil, errors.New("malformed frame")
+		}
+		n := binary.BigEndian.Uint32(body)
+		body = body[4:]
+		if uint64(n) > uint64(len(body)) {
+			return 0, nil, errors.New("malformed frame")
+		}
+		fields = append(fields, body[:n])
+		body = body[n:]
+	}
+	return kind, fields, nil
+}
@@ -0,0 +1,162 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Bren2010/katie/crypto/suites"
+	"github.com/Bren2010/katie/crypto/vrf"
+)
+
+var cs = suites.KTSha256P256{}
+
+// listen returns a listener on a new Unix socket in a temporary directory.
+func listen(t *testing.T) net.Listener {
+	ln, err := net.Listen("unix", filepath.Join(t.TempDir(), "signer.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	return ln
+}
+
+// startServer starts a Server with one signing key and one VRF key, both named
+// "test", and returns the keys and a client for the server.
+func startServer(t *testing.T) (suites.SigningPrivateKey, vrf.PrivateKey, *Client) {
+	rawSigKey, _ := hex.DecodeString("d4987fdd18738be11e93f7f087bf3e0ef5743b8deea192509bbf716c9463c218")
+	sigKey, err := cs.ParseSigningPrivateKey(rawSigKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawVrfKey, _ := hex.DecodeString("d1f2dcc02cc82c1f2b623e91946c945a2a1eb2983a47f283d8dd2af3d9b9d9ad")
+	vrfKey, err := cs.ParseVRFPrivateKey(rawVrfKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln := listen(t)
+	server := NewServer(
+		map[string]suites.SigningPrivateKey{"test": sigKey},
+		map[string]vrf.PrivateKey{"test": vrfKey},
+	)
+	go server.Serve(ln)
+
+	return sigKey, vrfKey, NewClient("unix", ln.Addr().String(), 0)
+}
+
+func TestSigner(t *testing.T) {
+	sigKey, _, client := startServer(t)
+
+	signer, err := NewSigner(context.Background(), client, cs, "test")
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(signer.Public().Bytes(), sigKey.Public().Bytes()) {
+		t.Fatal("unexpected public key returned")
+	}
+	message := []byte("Hello, World!")
+	sig, err := signer.Sign(message)
+	if err != nil {
+		t.Fatal(err)
+	} else if !sigKey.Public().Verify(message, sig) {
+		t.Fatal("signature failed to verify")
+	}
+
+	if _, err := NewSigner(context.Background(), client, cs, "unknown"); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+}
+
+func TestProver(t *testing.T) {
+	_, vrfKey, client := startServer(t)
+
+	prover, err := NewProver(context.Background(), client, cs, "test")
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(prover.PublicKey().Bytes(), vrfKey.PublicKey().Bytes()) {
+		t.Fatal("unexpected public key returned")
+	}
+	m := []byte("Hello, World!")
+	output1, proof1, err := prover.Prove(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output2, proof2, err := vrfKey.Prove(m)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(output1, output2) || !bytes.Equal(proof1, proof2) {
+		t.Fatal("unexpected output or proof returned")
+	}
+
+	if _, err := NewProver(context.Background(), client, cs, "unknown"); err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+}
+
+func TestInvalidResponse(t *testing.T) {
+	sigKey, _, client := startServer(t)
+	signer, err := NewSigner(context.Background(), client, cs, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Point the signer at a daemon that returns a signature from the wrong
+	// message.
+	ln := listen(t)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, _, err := readFrame(conn); err != nil {
+			return
+		}
+		sig, _ := sigKey.Sign([]byte("wrong message"))
+		writeFrame(conn, StatusOK, sig)
+	}()
+	signer.client = NewClient("unix", ln.Addr().String(), 0)
+
+	if _, err := signer.Sign([]byte("Hello, World!")); err == nil {
+		t.Fatal("expected error for invalid signature")
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	// Start a daemon that accepts connections but never responds.
+	ln := listen(t)
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for range cap(conns) {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+	t.Cleanup(func() {
+		for range len(conns) {
+			(<-conns).Close()
+		}
+	})
+
+	client := NewClient("unix", ln.Addr().String(), 50*time.Millisecond)
+	_, err := client.Sign(context.Background(), "test", []byte("Hello, World!"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded error, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	client = NewClient("unix", ln.Addr().String(), time.Minute)
+	_, _, err = client.Prove(ctx, "test", []byte("Hello, World!"))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled error, got: %v", err)
+	}
+}
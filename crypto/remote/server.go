@@ -0,0 +1,106 @@
+package remote
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+
+	"github.com/Bren2010/katie/crypto/suites"
+	"github.com/Bren2010/katie/crypto/vrf"
+)
+
+// Server implements the signing daemon's side of the protocol, answering
+// requests with the keys that it holds.
+type Server struct {
+	signingKeys map[string]suites.SigningPrivateKey
+	vrfKeys     map[string]vrf.PrivateKey
+}
+
+// NewServer returns a new Server that holds the given signing and VRF keys,
+// indexed by name.
+func NewServer(signingKeys map[string]suites.SigningPrivateKey, vrfKeys map[string]vrf.PrivateKey) *Server {
+	return &Server{signingKeys: signingKeys, vrfKeys: vrfKeys}
+}
+
+// Serve accepts connections from `ln` and answers the requests sent over them.
+// It returns when `ln` returns an error from Accept.
+func (s *Server) Serve(ln net.Listener) error {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// serveConn answers requests sent over `conn` until the client closes it.
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		op, fields, err := readFrame(conn)
+		if errors.Is(err, io.EOF) {
+			return
+		} else if err != nil {
+			log.Printf("failed to read request: %v", err)
+			return
+		}
+
+		res, err := s.handle(op, fields)
+		if err != nil {
+			err = writeFrame(conn, StatusError, []byte(err.Error()))
+		} else {
+			err = writeFrame(conn, StatusOK, res...)
+		}
+		if err != nil {
+			log.Printf("failed to write response: %v", err)
+			return
+		}
+	}
+}
+
+// handle returns the fields of the response to a request for operation `op`.
+func (s *Server) handle(op byte, fields [][]byte) ([][]byte, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("key name not provided")
+	}
+	name, args := string(fields[0]), fields[1:]
+
+	switch op {
+	case OpSigningPublicKey, OpSign:
+		key, ok := s.signingKeys[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key: %q", name)
+		} else if op == OpSigningPublicKey {
+			return [][]byte{key.Public().Bytes()}, nil
+		} else if len(args) != 1 {
+			return nil, errors.New("unexpected number of fields in request")
+		}
+		sig, err := key.Sign(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{sig}, nil
+
+	case OpVRFPublicKey, OpProve:
+		key, ok := s.vrfKeys[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown vrf key: %q", name)
+		} else if op == OpVRFPublicKey {
+			return [][]byte{key.PublicKey().Bytes()}, nil
+		} else if len(args) != 1 {
+			return nil, errors.New("unexpected number of fields in request")
+		}
+		output, proof, err := key.Prove(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return [][]byte{output, proof}, nil
+
+	default:
+		return nil, errors.New("unknown operation")
+	}
+}
@@ -0,0 +1,43 @@
+package suites
+
+import (
+	"crypto/sha256"
+	"hash"
+
+	"github.com/Bren2010/katie/crypto/vrf"
+	"github.com/Bren2010/katie/crypto/vrf/edwards25519"
+)
+
+// KTSha256Ed25519Ell2 implements the KT cipher suite using SHA-256 for hashing
+// and ed25519 for signatures. It's the same as KTSha256Ed25519, except that
+// the VRF is ECVRF-EDWARDS25519-SHA512-ELL2, which hashes labels to the curve
+// in constant time.
+type KTSha256Ed25519Ell2 struct{}
+
+var _ CipherSuite = KTSha256Ed25519Ell2{}
+
+func (s KTSha256Ed25519Ell2) Id() uint16                 { return 0x03 }
+func (s KTSha256Ed25519Ell2) Hash() hash.Hash            { return sha256.New() }
+func (s KTSha256Ed25519Ell2) HashSize() int              { return 32 }
+func (s KTSha256Ed25519Ell2) CommitmentOpeningSize() int { return 16 }
+func (s KTSha256Ed25519Ell2) VrfProofSize() int          { return 80 }
+
+func (s KTSha256Ed25519Ell2) CommitmentFixedBytes() []byte {
+	return KTSha256Ed25519{}.CommitmentFixedBytes()
+}
+
+func (s KTSha256Ed25519Ell2) ParseSigningPrivateKey(raw []byte) (SigningPrivateKey, error) {
+	return KTSha256Ed25519{}.ParseSigningPrivateKey(raw)
+}
+
+func (s KTSha256Ed25519Ell2) ParseSigningPublicKey(raw []byte) (SigningPublicKey, error) {
+	return KTSha256Ed25519{}.ParseSigningPublicKey(raw)
+}
+
+func (s KTSha256Ed25519Ell2) ParseVRFPrivateKey(raw []byte) (vrf.PrivateKey, error) {
+	return edwards25519.NewELL2PrivateKey(raw)
+}
+
+func (s KTSha256Ed25519Ell2) ParseVRFPublicKey(raw []byte) (vrf.PublicKey, error) {
+	return edwards25519.NewELL2PublicKey(raw)
+}
@@ -0,0 +1,111 @@
+package suites
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha512"
+	"hash"
+	"math/big"
+
+	"github.com/Bren2010/katie/crypto/vrf"
+	"github.com/Bren2010/katie/crypto/vrf/p384"
+)
+
+// KTSha384P384 implements the KT cipher suite using SHA-384 for hashing and
+// P-384 for signatures and the VRF, for 192-bit security. The VRF is a
+// non-standard suite that won't interoperate with other implementations; see
+// package p384 for details.
+type KTSha384P384 struct{}
+
+var _ CipherSuite = KTSha384P384{}
+
+func (s KTSha384P384) Id() uint16                 { return 0x04 }
+func (s KTSha384P384) Hash() hash.Hash            { return sha512.New384() }
+func (s KTSha384P384) HashSize() int              { return 48 }
+func (s KTSha384P384) CommitmentOpeningSize() int { return 24 }
+func (s KTSha384P384) VrfProofSize() int          { return 121 }
+
+func (s KTSha384P384) CommitmentFixedBytes() []byte {
+	return []byte{
+		0xd8, 0x21, 0xf8, 0x79, 0x0d, 0x97, 0x70, 0x97,
+		0x96, 0xb4, 0xd7, 0x90, 0x33, 0x57, 0xc3, 0xf5,
+	}
+}
+
+func (s KTSha384P384) ParseSigningPrivateKey(raw []byte) (SigningPrivateKey, error) {
+	priv, err := ecdsa.ParseRawPrivateKey(elliptic.P384(), raw)
+	if err != nil {
+		return nil, err
+	}
+	return p384PrivateKey{priv}, nil
+}
+
+func (s KTSha384P384) ParseSigningPublicKey(raw []byte) (SigningPublicKey, error) {
+	pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P384(), raw)
+	if err != nil {
+		return nil, err
+	}
+	return p384PublicKey{pub}, nil
+}
+
+func (s KTSha384P384) ParseVRFPrivateKey(raw []byte) (vrf.PrivateKey, error) {
+	return p384.NewPrivateKey(raw)
+}
+
+func (s KTSha384P384) ParseVRFPublicKey(raw []byte) (vrf.PublicKey, error) {
+	return p384.NewPublicKey(raw)
+}
+
+// p384PrivateKey implements the SigningPrivateKey interface for a P-384 ECDSA
+// private key.
+type p384PrivateKey struct {
+	inner *ecdsa.PrivateKey
+}
+
+func (k p384PrivateKey) Sign(message []byte) ([]byte, error) {
+	digest := sha512.Sum384(message)
+
+	r, s, err := ecdsa.Sign(rand.Reader, k.inner, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	rBytes, sBytes := r.Bytes(), s.Bytes()
+
+	sig := make([]byte, 96)
+	copy(sig[48-len(rBytes):], rBytes)
+	copy(sig[96-len(sBytes):], sBytes)
+
+	return sig, nil
+}
+
+func (k p384PrivateKey) Public() SigningPublicKey {
+	return p384PublicKey{inner: &k.inner.PublicKey}
+}
+
+// p384PublicKey implements the SigningPublicKey interface for a P-384 ECDSA
+// public key.
+type p384PublicKey struct {
+	inner *ecdsa.PublicKey
+}
+
+func (k p384PublicKey) Verify(message, sig []byte) bool {
+	digest := sha512.Sum384(message)
+
+	if len(sig) != 96 {
+		return false
+	}
+	r, s := new(big.Int), new(big.Int)
+	r.SetBytes(sig[:48])
+	s.SetBytes(sig[48:])
+
+	return ecdsa.Verify(k.inner, digest[:], r, s)
+}
+
+func (k p384PublicKey) Bytes() []byte {
+	out, err := k.inner.Bytes()
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
@@ -27,6 +27,22 @@ type CipherSuite interface {
 	ParseVRFPublicKey(raw []byte) (vrf.PublicKey, error)
 }
 
+// byName maps the name of each supported cipher suite to its implementation.
+var byName = map[string]CipherSuite{
+	"KTSha256P256":        KTSha256P256{},
+	"KTSha256Ed25519":     KTSha256Ed25519{},
+	"KTSha256Ed25519Ell2": KTSha256Ed25519Ell2{},
+	"KTSha384P384":        KTSha384P384{},
+}
+
+// ByName returns the cipher suite with the given name, like "KTSha256P256", as
+// it's written in configuration files. It returns false if the name is not
+// recognized.
+func ByName(name string) (CipherSuite, bool) {
+	cs, ok := byName[name]
+	return cs, ok
+}
+
 // SigningPrivateKey is the interface implemented by signature private keys.
 type SigningPrivateKey interface {
 	Sign(message []byte) ([]byte, error)
@@ -1,5 +1,6 @@
-// Package edwards25519 implements the ECVRF-EDWARDS25519-SHA512-TAI cipher
-// suite from RFC 9381, with the VRF output truncated from 64 to 32 bytes.
+// Package edwards25519 implements the ECVRF-EDWARDS25519-SHA512-TAI and
+// ECVRF-EDWARDS25519-SHA512-ELL2 cipher suites from RFC 9381, with the VRF
+// output truncated from 64 to 32 bytes.
 package edwards25519
 
 import (
@@ -12,9 +13,20 @@ import (
 	"github.com/Bren2010/katie/crypto/vrf"
 )
 
-// encodeToCurve implements the trial-and-increment algorithm for encoding a
-// byte string to a curve point.
-func encodeToCurve(salt, m []byte) *edwards25519.Point {
+// suite contains the parts of the VRF that differ between cipher suites.
+type suite struct {
+	id     byte // Suite string
+	encode func(salt, m []byte) *edwards25519.Point
+}
+
+var (
+	tai  = &suite{id: 0x03, encode: encodeToCurveTAI}
+	ell2 = &suite{id: 0x04, encode: encodeToCurveELL2}
+)
+
+// encodeToCurveTAI implements the trial-and-increment algorithm for encoding a
+// byte string to a curve point. It is not constant time.
+func encodeToCurveTAI(salt, m []byte) *edwards25519.Point {
 	counter := 0
 
 	for {
@@ -58,9 +70,9 @@ func generateNonce(lower, hStr []byte) *edwards25519.Scalar {
 
 // generateChallenge deterministically generates the proof challenge from the
 // given elliptic curve points.
-func generateChallenge(p1, p2, p3, p4, p5 *edwards25519.Point) []byte {
+func (s *suite) generateChallenge(p1, p2, p3, p4, p5 *edwards25519.Point) []byte {
 	buf := &bytes.Buffer{}
-	buf.WriteByte(0x03) // Suite string
+	buf.WriteByte(s.id) // Suite string
 	buf.WriteByte(0x02) // Front domain separator
 	buf.Write(p1.Bytes())
 	buf.Write(p2.Bytes())
@@ -78,9 +90,9 @@ func generateChallenge(p1, p2, p3, p4, p5 *edwards25519.Point) []byte {
 }
 
 // proofToHash converts the VRF proof into the VRF output.
-func proofToHash(Gamma *edwards25519.Point) []byte {
+func (s *suite) proofToHash(Gamma *edwards25519.Point) []byte {
 	buf := &bytes.Buffer{}
-	buf.WriteByte(0x03) // Suite string
+	buf.WriteByte(s.id) // Suite string
 	buf.WriteByte(0x03) // Front domain separator
 	buf.Write(new(edwards25519.Point).MultByCofactor(Gamma).Bytes())
 	buf.WriteByte(0x00) // Back domain separator
@@ -91,6 +103,7 @@ func proofToHash(Gamma *edwards25519.Point) []byte {
 }
 
 type PrivateKey struct {
+	suite  *suite
 	scalar *edwards25519.Scalar
 	point  *edwards25519.Point
 	upper  []byte
@@ -102,7 +115,17 @@ func GeneratePrivateKey() []byte {
 	return k
 }
 
+// NewPrivateKey returns a private key for ECVRF-EDWARDS25519-SHA512-TAI.
 func NewPrivateKey(raw []byte) (*PrivateKey, error) {
+	return newPrivateKey(tai, raw)
+}
+
+// NewELL2PrivateKey returns a private key for ECVRF-EDWARDS25519-SHA512-ELL2.
+func NewELL2PrivateKey(raw []byte) (*PrivateKey, error) {
+	return newPrivateKey(ell2, raw)
+}
+
+func newPrivateKey(s *suite, raw []byte) (*PrivateKey, error) {
 	if len(raw) != 32 {
 		return nil, errors.New("vrf private key is unexpected length")
 	}
@@ -114,11 +137,11 @@ func NewPrivateKey(raw []byte) (*PrivateKey, error) {
 	}
 	point := new(edwards25519.Point).ScalarBaseMult(scalar)
 
-	return &PrivateKey{scalar: scalar, point: point, upper: h[32:]}, nil
+	return &PrivateKey{suite: s, scalar: scalar, point: point, upper: h[32:]}, nil
 }
 
-func (p *PrivateKey) Prove(m []byte) (output, proof []byte) {
-	H := encodeToCurve(p.point.Bytes(), m)
+func (p *PrivateKey) Prove(m []byte) (output, proof []byte, err error) {
+	H := p.suite.encode(p.point.Bytes(), m)
 	hStr := H.Bytes()
 
 	Gamma := new(edwards25519.Point).ScalarMult(p.scalar, H)
@@ -127,7 +150,7 @@ func (p *PrivateKey) Prove(m []byte) (output, proof []byte) {
 	kB := new(edwards25519.Point).ScalarBaseMult(k)
 	kH := new(edwards25519.Point).ScalarMult(k, H)
 
-	c := generateChallenge(p.point, H, Gamma, kB, kH)
+	c := p.suite.generateChallenge(p.point, H, Gamma, kB, kH)
 
 	s, err := new(edwards25519.Scalar).SetCanonicalBytes(c)
 	if err != nil {
@@ -140,20 +163,31 @@ func (p *PrivateKey) Prove(m []byte) (output, proof []byte) {
 	copy(proof[32:48], c[:16])
 	copy(proof[48:], s.Bytes())
 
-	output = proofToHash(Gamma)
+	output = p.suite.proofToHash(Gamma)
 
-	return
+	return output, proof, nil
 }
 
 func (p *PrivateKey) PublicKey() vrf.PublicKey {
-	return &PublicKey{point: p.point}
+	return &PublicKey{suite: p.suite, point: p.point}
 }
 
 type PublicKey struct {
+	suite *suite
 	point *edwards25519.Point
 }
 
+// NewPublicKey returns a public key for ECVRF-EDWARDS25519-SHA512-TAI.
 func NewPublicKey(raw []byte) (*PublicKey, error) {
+	return newPublicKey(tai, raw)
+}
+
+// NewELL2PublicKey returns a public key for ECVRF-EDWARDS25519-SHA512-ELL2.
+func NewELL2PublicKey(raw []byte) (*PublicKey, error) {
+	return newPublicKey(ell2, raw)
+}
+
+func newPublicKey(s *suite, raw []byte) (*PublicKey, error) {
 	// Notes on point validation:
 	// - Non-canonical encodings are accepted but have no affect on protocol.
 	// - SetBytes verifies that the point is on the curve.
@@ -166,7 +200,7 @@ func NewPublicKey(raw []byte) (*PublicKey, error) {
 	if edwards25519.NewIdentityPoint().Equal(temp) == 1 {
 		return nil, errors.New("public key is malformed")
 	}
-	return &PublicKey{point: point}, nil
+	return &PublicKey{suite: s, point: point}, nil
 }
 
 func (p *PublicKey) Verify(m, proof []byte) (output []byte, err error) {
@@ -197,7 +231,7 @@ func (p *PublicKey) Verify(m, proof []byte) (output []byte, err error) {
 	}
 
 	// Verify proof.
-	H := encodeToCurve(p.point.Bytes(), m)
+	H := p.suite.encode(p.point.Bytes(), m)
 
 	U := new(edwards25519.Point).ScalarBaseMult(s)
 	temp := new(edwards25519.Point).ScalarMult(c, p.point)
@@ -208,12 +242,12 @@ func (p *PublicKey) Verify(m, proof []byte) (output []byte, err error) {
 	temp.ScalarMult(c, Gamma).Negate(temp)
 	V.Add(V, temp)
 
-	cPrime := generateChallenge(p.point, H, Gamma, U, V)
+	cPrime := p.suite.generateChallenge(p.point, H, Gamma, U, V)
 	if !bytes.Equal(cBytes, cPrime) {
 		return nil, errors.New("vrf proof verification failed")
 	}
 
-	return proofToHash(Gamma), nil
+	return p.suite.proofToHash(Gamma), nil
 }
 
 func (p *PublicKey) Bytes() []byte { return p.point.Bytes() }
@@ -15,7 +15,10 @@ func TestCorrectness(t *testing.T) {
 	}
 	pub := priv.PublicKey()
 
-	output1, proof := priv.Prove([]byte("Hello, World!"))
+	output1, proof, err := priv.Prove([]byte("Hello, World!"))
+	if err != nil {
+		t.Fatal(err)
+	}
 	t.Logf("%x", output1)
 	t.Logf("%x", proof)
 
@@ -73,8 +76,40 @@ func TestVectors(t *testing.T) {
 		},
 	}
 
+	checkVectors(t, vectors, NewPrivateKey)
+}
+
+func TestELL2Vectors(t *testing.T) {
+	vectors := []TestVector{
+		{
+			Priv:    "9d61b19deffd5a60ba844af492ec2cc44449c5697b326919703bac031cae7f60",
+			Pub:     "d75a980182b10ab7d54bfed3c964073a0ee172f3daa62325af021a68f707511a",
+			Message: "",
+			Index:   "9d574bf9b8302ec0fc1e21c3ec5368269527b87b462ce36dab2d14ccf80c53cc", // cf6758f058c5b1c856b116388152bbe509ee3b9ecfe63d93c3b4346c1fbc6c54
+			Proof:   "7d9c633ffeee27349264cf5c667579fc583b4bda63ab71d001f89c10003ab46f14adf9a3cd8b8412d9038531e865c341cafa73589b023d14311c331a9ad15ff2fb37831e00f0acaa6d73bc9997b06501",
+		},
+		{
+			Priv:    "4ccd089b28ff96da9db6c346ec114e0f5b8a319f35aba624da8cf6ed4fb8a6fb",
+			Pub:     "3d4017c3e843895a92b70aa74d1b7ebc9c982ccf2ec4968cc0cd55f12af4660c",
+			Message: "72",
+			Index:   "38561d6b77b71d30eb97a062168ae12b667ce5c28caccdf76bc88e093e463598", // 7cd96814ce55b4689b3dd2947f80e59aac7b7675f8083865b46c89b2ce9cc735
+			Proof:   "47b327393ff2dd81336f8a2ef10339112401253b3c714eeda879f12c509072ef055b48372bb82efbdce8e10c8cb9a2f9d60e93908f93df1623ad78a86a028d6bc064dbfc75a6a57379ef855dc6733801",
+		},
+		{
+			Priv:    "c5aa8df43f9f837bedb7442f31dcb7b166d38535076f094b85ce3a2e0b4458f7",
+			Pub:     "fc51cd8e6218a1a38da47ed00230f0580816ed13ba3303ac5deb911548908025",
+			Message: "af82",
+			Index:   "121b7f9b9aaaa29099fc04a94ba52784d44eac976dd1a3cca458733be5cd090a", // 7b5fbd148444f17f8daf1fb55cb04b1ae85a626e30a54b4b0f8abf4a43314a58
+			Proof:   "926e895d308f5e328e7aa159c06eddbe56d06846abf5d98c2512235eaa57fdce35b46edfc655bc828d44ad09d1150f31374e7ef73027e14760d42e77341fe05467bb286cc2c9d7fde29120a0b2320d04",
+		},
+	}
+
+	checkVectors(t, vectors, NewELL2PrivateKey)
+}
+
+func checkVectors(t *testing.T, vectors []TestVector, newPrivateKey func([]byte) (*PrivateKey, error)) {
 	for _, vector := range vectors {
-		priv, err := NewPrivateKey(hexDecode(vector.Priv))
+		priv, err := newPrivateKey(hexDecode(vector.Priv))
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -82,7 +117,10 @@ func TestVectors(t *testing.T) {
 		if fmt.Sprintf("%x", pub.Bytes()) != vector.Pub {
 			t.Fatal("unexpected public key computed")
 		}
-		output1, proof := priv.Prove(hexDecode(vector.Message))
+		output1, proof, err := priv.Prove(hexDecode(vector.Message))
+		if err != nil {
+			t.Fatal(err)
+		}
 		if fmt.Sprintf("%x", output1) != vector.Index {
 			t.Fatal("unexpected index computed")
 		} else if fmt.Sprintf("%x", proof) != vector.Proof {
@@ -96,3 +134,19 @@ func TestVectors(t *testing.T) {
 		}
 	}
 }
+
+// TestHashToCurve checks hashToCurve against the test vectors for
+// edwards25519_XMD:SHA-512_ELL2_NU_ from Appendix J.5.2 of RFC 9380.
+func TestHashToCurve(t *testing.T) {
+	dst := []byte("QUUX-V01-CS02-with-edwards25519_XMD:SHA-512_ELL2_NU_")
+	vectors := map[string]string{
+		"":                 "9b0f7f682dabce2190b14e21a175f39eb6a6b29fff2a9f5e72d5a4044d312e22",
+		"abc":              "42fa27c8f5a1ae0aa38bb59d5938e5145622ba5dedd11d11736fa2f9502d7367",
+		"abcdef0123456789": "fb861a8e0a5a954a5c6836d379f1b07775134a6adaca0939e7dd1add246c8aaf",
+	}
+	for msg, expected := range vectors {
+		if point := hashToCurve([]byte(msg), dst); fmt.Sprintf("%x", point.Bytes()) != expected {
+			t.Fatalf("unexpected point computed for message %q", msg)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package edwards25519
+
+import (
+	"crypto/sha512"
+	"slices"
+
+	"filippo.io/edwards25519"
+	"filippo.io/edwards25519/field"
+)
+
+// h2cSuiteId is the h2c_suite_ID_string of the hash-to-curve suite that's used
+// by ECVRF-EDWARDS25519-SHA512-ELL2.
+const h2cSuiteId = "edwards25519_XMD:SHA-512_ELL2_NU_"
+
+// elligatorConstants contains the field elements that are needed to compute
+// the Elligator 2 map.
+type elligatorConstants struct {
+	zero, one *field.Element
+
+	a             *field.Element // The Montgomery curve parameter, 486662.
+	minusA        *field.Element // -486662
+	sqrtM1        *field.Element // sqrt(-1)
+	twoPow        *field.Element // 2^((p+3)/8)
+	sqrtMinusApp2 *field.Element // sqrt(-486664), with sgn0 equal to 0.
+}
+
+var constants = func() *elligatorConstants {
+	zero, one := new(field.Element).Zero(), new(field.Element).One()
+	two := new(field.Element).Add(one, one)
+	a := new(field.Element).Mult32(one, 486662)
+	minusA := new(field.Element).Negate(a)
+
+	// SqrtRatio always returns the non-negative square root.
+	sqrtM1, _ := new(field.Element).SqrtRatio(new(field.Element).Negate(one), one)
+	sqrtMinusApp2, _ := new(field.Element).SqrtRatio(new(field.Element).Subtract(minusA, two), one)
+
+	// 2^((p+3)/8) = 2 * 2^((p-5)/8)
+	twoPow := new(field.Element).Pow22523(two)
+	twoPow.Multiply(twoPow, two)
+
+	return &elligatorConstants{
+		zero: zero,
+		one:  one,
+
+		a:             a,
+		minusA:        minusA,
+		sqrtM1:        sqrtM1,
+		twoPow:        twoPow,
+		sqrtMinusApp2: sqrtMinusApp2,
+	}
+}()
+
+// expandMessageXMD implements expand_message_xmd from Section 5.3.1 of RFC
+// 9380 with SHA-512, for outputs of at most 255 blocks.
+func expandMessageXMD(msg, dst []byte, length int) []byte {
+	const blockSize, inputSize = 64, 128
+	dstPrime := append(slices.Clone(dst), byte(len(dst)))
+
+	h := sha512.New()
+	h.Write(make([]byte, inputSize))
+	h.Write(msg)
+	h.Write([]byte{byte(length >> 8), byte(length), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	out := make([]byte, 0, length+blockSize)
+	prev := make([]byte, blockSize)
+	for i := 1; len(out) < length; i++ {
+		for j := range prev {
+			prev[j] ^= b0[j]
+		}
+		h.Reset()
+		h.Write(prev)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		prev = h.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:length]
+}
+
+// hashToField implements hash_to_field from Section 5.2 of RFC 9380, returning
+// a single element of the field that edwards25519 is defined over.
+func hashToField(msg, dst []byte) *field.Element {
+	// L = ceil((ceil(log2(p)) + k) / 8) = ceil((255 + 128) / 8) = 48
+	uniform := expandMessageXMD(msg, dst, 48)
+
+	// The bytes are interpreted as a big-endian integer, while SetWideBytes
+	// expects 64 little-endian bytes.
+	wide := make([]byte, 64)
+	for i, b := range uniform {
+		wide[len(uniform)-1-i] = b
+	}
+	u, err := new(field.Element).SetWideBytes(wide)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// mapToCurve implements the Elligator 2 map to edwards25519, as specified by
+// map_to_curve_elligator2_curve25519 and map_to_curve_elligator2_edwards25519
+// in Appendix G.2 of RFC 9380. It runs in constant time.
+func mapToCurve(u *field.Element) *edwards25519.Point {
+	c := constants
+
+	// Map to a point (xMn / xMd, yM) on curve25519, the Montgomery form of the
+	// curve.
+	tv1 := new(field.Element).Square(u)
+	tv1.Add(tv1, tv1)
+	xMd := new(field.Element).Add(tv1, c.one)
+	x1n := new(field.Element).Set(c.minusA)
+	tv2 := new(field.Element).Square(xMd)
+	gxd := new(field.Element).Multiply(tv2, xMd)
+	gx1 := new(field.Element).Multiply(c.a, tv1)
+	gx1.Multiply(gx1, x1n)
+	gx1.Add(gx1, tv2)
+	gx1.Multiply(gx1, x1n)
+	tv3 := new(field.Element).Square(gxd)
+	tv2.Square(tv3)
+	tv3.Multiply(tv3, gxd)
+	tv3.Multiply(tv3, gx1)
+	tv2.Multiply(tv2, tv3)
+	y11 := new(field.Element).Pow22523(tv2)
+	y11.Multiply(y11, tv3)
+	y12 := new(field.Element).Multiply(y11, c.sqrtM1)
+	tv2.Square(y11)
+	tv2.Multiply(tv2, gxd)
+	e1 := tv2.Equal(gx1)
+	y1 := new(field.Element).Select(y11, y12, e1)
+	x2n := new(field.Element).Multiply(x1n, tv1)
+	y21 := new(field.Element).Multiply(y11, u)
+	y21.Multiply(y21, c.twoPow)
+	y22 := new(field.Element).Multiply(y21, c.sqrtM1)
+	gx2 := new(field.Element).Multiply(gx1, tv1)
+	tv2.Square(y21)
+	tv2.Multiply(tv2, gxd)
+	e2 := tv2.Equal(gx2)
+	y2 := new(field.Element).Select(y21, y22, e2)
+	tv2.Square(y1)
+	tv2.Multiply(tv2, gxd)
+	e3 := tv2.Equal(gx1)
+	xMn := new(field.Element).Select(x1n, x2n, e3)
+	yM := new(field.Element).Select(y1, y2, e3)
+	e4 := yM.IsNegative()
+	yM.Select(new(field.Element).Negate(yM), yM, e3^e4)
+
+	// Apply the rational map to a point (xn / xd, yn / yd) on edwards25519.
+	xn := new(field.Element).Multiply(xMn, c.sqrtMinusApp2)
+	xd := new(field.Element).Multiply(xMd, yM)
+	yn := new(field.Element).Subtract(xMn, xMd)
+	yd := new(field.Element).Add(xMn, xMd)
+	e := new(field.Element).Multiply(xd, yd).Equal(c.zero)
+	xn.Select(c.zero, xn, e)
+	xd.Select(c.one, xd, e)
+	yn.Select(c.one, yn, e)
+	yd.Select(c.one, yd, e)
+
+	point, err := new(edwards25519.Point).SetExtendedCoordinates(
+		new(field.Element).Multiply(xn, yd),
+		new(field.Element).Multiply(yn, xd),
+		new(field.Element).Multiply(xd, yd),
+		new(field.Element).Multiply(xn, yn),
+	)
+	if err != nil {
+		panic(err)
+	}
+	return point
+}
+
+// encodeToCurveELL2 implements encode_to_curve from Section 3 of RFC 9380 for
+// the hash-to-curve suite edwards25519_XMD:SHA-512_ELL2_NU_, with the domain
+// separation tag specified for ECVRF-EDWARDS25519-SHA512-ELL2.
+func encodeToCurveELL2(salt, m []byte) *edwards25519.Point {
+	dst := append([]byte("ECVRF_"+h2cSuiteId), 0x04) // Suite string
+	return hashToCurve(append(slices.Clone(salt), m...), dst)
+}
+
+// hashToCurve maps `msg` to a point in the prime-order subgroup of
+// edwards25519, using the domain separation tag `dst`.
+func hashToCurve(msg, dst []byte) *edwards25519.Point {
+	point := mapToCurve(hashToField(msg, dst))
+	return point.MultByCofactor(point)
+}
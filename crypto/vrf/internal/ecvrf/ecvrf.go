@@ -0,0 +1,340 @@
+// Package ecvrf implements the ECVRF-*-TAI construction from RFC 9381 over the
+// NIST curves of the nistec package, with the hash function and the encoding
+// sizes as parameters.
+package ecvrf
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"errors"
+	"hash"
+	"math/big"
+
+	"github.com/Bren2010/katie/crypto/vrf"
+)
+
+// Point is the interface implemented by the point types of the nistec package,
+// like *nistec.P256Point.
+type Point[P any] interface {
+	SetBytes(b []byte) (P, error)
+	BytesCompressed() []byte
+	ScalarBaseMult(scalar []byte) (P, error)
+	ScalarMult(q P, scalar []byte) (P, error)
+	Add(p1, p2 P) P
+	Negate(p P) P
+}
+
+// Suite contains the parameters of an ECVRF-*-TAI cipher suite.
+type Suite[P Point[P]] struct {
+	SuiteString byte             // The suite_string that prefixes every hash input.
+	NewPoint    func() P         // Returns a new point, like nistec.NewP256Point.
+	N           *big.Int         // The order of the curve's base point.
+	Hash        func() hash.Hash // The hash function, which must output qLen bytes.
+
+	CLen  int // The length of the challenge, in bytes.
+	PtLen int // The length of a compressed point, in bytes.
+	QLen  int // The length of a scalar, in bytes.
+}
+
+// encodeToCurve implements the trial-and-increment algorithm for encoding a
+// byte string to a curve point.
+func (s *Suite[P]) encodeToCurve(salt, m []byte) P {
+	counter := 0
+
+	for {
+		hasher := s.Hash()
+		hasher.Write([]byte{s.SuiteString, 0x01}) // Suite string, front domain separator
+		hasher.Write(salt)
+		hasher.Write(m)
+		hasher.Write([]byte{byte(counter), 0x00}) // Counter, back domain separator
+
+		hashStr := hasher.Sum([]byte{0x02})
+
+		// Notes on point validation:
+		// - SetBytes verifies that the scalar is less than p.
+		// - SetBytes verifies that the point is on the curve.
+		// - Point can not be point at infinity because hashStr starts with 2.
+		point, err := s.NewPoint().SetBytes(hashStr)
+		if err == nil {
+			return point
+		} else if counter == 255 {
+			panic("encode to curve failed unexpectedly")
+		}
+
+		counter++
+	}
+}
+
+func (s *Suite[P]) mac(key, message []byte) []byte {
+	hasher := hmac.New(s.Hash, key)
+	hasher.Write(message)
+	return hasher.Sum(nil)
+}
+
+// generateNonce deterministically generates a private key from hStr, as
+// specified in Section 3.2 of RFC 6979.
+func (s *Suite[P]) generateNonce(priv, hStr []byte) []byte {
+	// a. h1 = H(m)
+	//
+	// Every use of h1 below is as bits2octets(h1), which reduces it modulo q
+	// since the hash output and q have the same length.
+	hasher := s.Hash()
+	hasher.Write(hStr)
+	h1Int := new(big.Int).SetBytes(hasher.Sum(nil))
+	h1Int.Mod(h1Int, s.N)
+	h1 := h1Int.FillBytes(make([]byte, s.QLen))
+
+	// b. V = 0x01 0x01 ... 0x01
+	V := bytes.Repeat([]byte{0x01}, s.QLen)
+
+	// c. K = 0x00 0x00 ... 0x00
+	K := make([]byte, s.QLen)
+
+	// d. K = HMAC_K(V || 0x00 || priv || h1)
+	buf := &bytes.Buffer{}
+	buf.Write(V)
+	buf.WriteByte(0x00)
+	buf.Write(priv)
+	buf.Write(h1)
+
+	K = s.mac(K, buf.Bytes())
+
+	// e. V = HMAC_K(V)
+	V = s.mac(K, V)
+
+	// f. K = HMAC_K(V || 0x01 || priv || h1)
+	buf.Reset()
+	buf.Write(V)
+	buf.WriteByte(0x01)
+	buf.Write(priv)
+	buf.Write(h1)
+
+	K = s.mac(K, buf.Bytes())
+
+	// g. V = HMAC_K(v)
+	V = s.mac(K, V)
+
+	// h. Repeat until a proper value is found:
+	for range 256 {
+		// V = HMAC_K(V)
+		V = s.mac(K, V)
+
+		// Return if acceptable.
+		if s.validScalar(V) {
+			return V
+		}
+
+		// K = HMAC_K(V || 0x00)
+		K = s.mac(K, append(V[:len(V):len(V)], 0x00))
+		// V = HMAC_K(V)
+		V = s.mac(K, V)
+	}
+
+	panic("nonce generation failed unexpectedly")
+}
+
+// generateChallenge deterministically generates the proof challenge from the
+// given elliptic curve points.
+func (s *Suite[P]) generateChallenge(p1, p2, p3, p4, p5 P) []byte {
+	hasher := s.Hash()
+	hasher.Write([]byte{s.SuiteString, 0x02}) // Suite string, front domain separator
+	hasher.Write(p1.BytesCompressed())
+	hasher.Write(p2.BytesCompressed())
+	hasher.Write(p3.BytesCompressed())
+	hasher.Write(p4.BytesCompressed())
+	hasher.Write(p5.BytesCompressed())
+	hasher.Write([]byte{0x00}) // Back domain separator
+
+	return hasher.Sum(nil)[:s.CLen]
+}
+
+// proofToHash converts the VRF proof into the VRF output.
+func (s *Suite[P]) proofToHash(gamma []byte) []byte {
+	hasher := s.Hash()
+	hasher.Write([]byte{s.SuiteString, 0x03}) // Suite string, front domain separator
+	hasher.Write(gamma)
+	hasher.Write([]byte{0x00}) // Back domain separator
+
+	return hasher.Sum(nil)
+}
+
+// validScalar returns true if `k` encodes an integer in the range [1, N).
+func (s *Suite[P]) validScalar(k []byte) bool {
+	kInt := new(big.Int).SetBytes(k)
+	return kInt.Sign() == 1 && kInt.Cmp(s.N) == -1
+}
+
+// proofSize returns the length of a proof, in bytes.
+func (s *Suite[P]) proofSize() int { return s.PtLen + s.CLen + s.QLen }
+
+// GeneratePrivateKey returns a new random private key.
+func (s *Suite[P]) GeneratePrivateKey() []byte {
+	for {
+		k := make([]byte, s.QLen)
+		rand.Read(k)
+
+		if s.validScalar(k) {
+			return k
+		}
+	}
+}
+
+// NewPrivateKey parses a private key that was returned by GeneratePrivateKey.
+func (s *Suite[P]) NewPrivateKey(raw []byte) (*PrivateKey[P], error) {
+	if len(raw) != s.QLen {
+		return nil, errors.New("vrf private key is unexpected length")
+	} else if !s.validScalar(raw) {
+		return nil, errors.New("vrf private key is malformed")
+	}
+
+	scalar := make([]byte, len(raw))
+	copy(scalar, raw)
+
+	point, err := s.NewPoint().ScalarBaseMult(scalar)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivateKey[P]{suite: s, scalar: scalar, point: point}, nil
+}
+
+// NewPublicKey parses a compressed public key.
+func (s *Suite[P]) NewPublicKey(raw []byte) (*PublicKey[P], error) {
+	// Notes on point validation:
+	// - SetBytes verifies the scalar(s) are less than p.
+	// - SetBytes verifies that the point is on the curve.
+	// - We manually check that the point is not the point at infinity.
+	if len(raw) == 1 {
+		return nil, errors.New("public key is malformed")
+	}
+	point, err := s.NewPoint().SetBytes(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &PublicKey[P]{suite: s, point: point}, nil
+}
+
+type PrivateKey[P Point[P]] struct {
+	suite  *Suite[P]
+	scalar []byte
+	point  P
+}
+
+func (p *PrivateKey[P]) Prove(m []byte) (output, proof []byte, err error) {
+	s := p.suite
+
+	H := s.encodeToCurve(p.point.BytesCompressed(), m)
+	hStr := H.BytesCompressed()
+
+	Gamma, err := s.NewPoint().ScalarMult(H, p.scalar)
+	if err != nil {
+		panic(err)
+	}
+
+	k := s.generateNonce(p.scalar, hStr)
+	kB, err := s.NewPoint().ScalarBaseMult(k)
+	if err != nil {
+		panic(err)
+	}
+	kH, err := s.NewPoint().ScalarMult(H, k)
+	if err != nil {
+		panic(err)
+	}
+	c := s.generateChallenge(p.point, H, Gamma, kB, kH)
+
+	cInt := new(big.Int).SetBytes(c)
+	xInt := new(big.Int).SetBytes(p.scalar)
+	kInt := new(big.Int).SetBytes(k)
+
+	sInt := new(big.Int).Mul(cInt, xInt)
+	sInt.Add(sInt, kInt).Mod(sInt, s.N)
+
+	proof = make([]byte, s.proofSize())
+	copy(proof[:s.PtLen], Gamma.BytesCompressed())
+	copy(proof[s.PtLen:s.PtLen+s.CLen], c)
+	sInt.FillBytes(proof[s.PtLen+s.CLen:])
+
+	output = s.proofToHash(proof[:s.PtLen])
+
+	return output, proof, nil
+}
+
+func (p *PrivateKey[P]) PublicKey() vrf.PublicKey {
+	return &PublicKey[P]{suite: p.suite, point: p.point}
+}
+
+type PublicKey[P Point[P]] struct {
+	suite *Suite[P]
+	point P
+}
+
+func (p *PublicKey[P]) verify(m, proof []byte) error {
+	s := p.suite
+
+	// Decode proof.
+	if len(proof) != s.proofSize() {
+		return errors.New("vrf proof is invalid size")
+	}
+
+	// Notes on point validation:
+	// - SetBytes verifies that the scalar is less than p.
+	// - SetBytes verifies that the point is on the curve.
+	// - SetBytes will return an error if the first byte isn't 2 or 3 due to the
+	//   input length. As such, the point can not be the point at infinity.
+	Gamma, err := s.NewPoint().SetBytes(proof[:s.PtLen])
+	if err != nil {
+		return err
+	}
+
+	c := make([]byte, s.QLen)
+	copy(c[s.QLen-s.CLen:], proof[s.PtLen:s.PtLen+s.CLen])
+
+	sStr := proof[s.PtLen+s.CLen:]
+	if !s.validScalar(sStr) {
+		return errors.New("vrf proof is malformed")
+	}
+
+	// Verify proof.
+	H := s.encodeToCurve(p.point.BytesCompressed(), m)
+
+	U, err := s.NewPoint().ScalarBaseMult(sStr)
+	if err != nil {
+		return err
+	}
+	temp, err := s.NewPoint().ScalarMult(p.point, c)
+	if err != nil {
+		return err
+	}
+	temp.Negate(temp)
+	U.Add(U, temp)
+
+	V, err := s.NewPoint().ScalarMult(H, sStr)
+	if err != nil {
+		return err
+	}
+	_, err = temp.ScalarMult(Gamma, c)
+	if err != nil {
+		return err
+	}
+	temp.Negate(temp)
+	V.Add(V, temp)
+
+	cPrime := s.generateChallenge(p.point, H, Gamma, U, V)
+	if !bytes.Equal(c[s.QLen-s.CLen:], cPrime) {
+		return errors.New("vrf proof verification failed")
+	}
+
+	return nil
+}
+
+func (p *PublicKey[P]) Verify(m, proof []byte) (output []byte, err error) {
+	err = p.verify(m, proof)
+	if err != nil {
+		return
+	}
+	output = p.suite.proofToHash(proof[:p.suite.PtLen])
+	return
+}
+
+func (p *PublicKey[P]) Bytes() []byte { return p.point.BytesCompressed() }
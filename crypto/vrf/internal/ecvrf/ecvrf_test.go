@@ -0,0 +1,60 @@
+package ecvrf
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"filippo.io/nistec"
+)
+
+func hexDecode(m string) []byte {
+	out, err := hex.DecodeString(m)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// testNonce checks generateNonce against the test vectors for `priv`, which
+// map each message to the expected nonce.
+func testNonce[P Point[P]](t *testing.T, s *Suite[P], priv string, vectors map[string]string) {
+	for message, expected := range vectors {
+		if k := s.generateNonce(hexDecode(priv), []byte(message)); fmt.Sprintf("%x", k) != expected {
+			t.Fatalf("unexpected nonce computed for message %q", message)
+		}
+	}
+}
+
+// TestNonceP256 checks generateNonce against the P-256 and SHA-256 test
+// vectors from Appendix A.2.5 of RFC 6979.
+func TestNonceP256(t *testing.T) {
+	s := &Suite[*nistec.P256Point]{
+		NewPoint: nistec.NewP256Point,
+		N:        elliptic.P256().Params().N,
+		Hash:     sha256.New,
+		QLen:     32,
+	}
+	testNonce(t, s, "c9afa9d845ba75166b5c215767b1d6934e50c3db36e89b127b8a622b120f6721", map[string]string{
+		"sample": "a6e3c57dd01abe90086538398355dd4c3b17aa873382b0f24d6129493d8aad60",
+		"test":   "d16b6ae827f17175e040871a1c7ec3500192c4c92677336ec2537acaee0008e0",
+	})
+}
+
+// TestNonceP384 checks generateNonce against the P-384 and SHA-384 test
+// vectors from Appendix A.2.6 of RFC 6979.
+func TestNonceP384(t *testing.T) {
+	s := &Suite[*nistec.P384Point]{
+		NewPoint: nistec.NewP384Point,
+		N:        elliptic.P384().Params().N,
+		Hash:     sha512.New384,
+		QLen:     48,
+	}
+	testNonce(t, s, "6b9d3dad2e1b8c1c05b19875b6659f4de23c3b667bf297ba9aa47740787137d896d5724e4c70a825f872c9ea60d2edf5", map[string]string{
+		"sample": "94ed910d1a099dad3254e9242ae85abde4ba15168eaf0ca87a555fd56d10fbca2907e3e83ba95368623b8c4686915cf9",
+		"test":   "015ee46a5bf88773ed9123a5ab0807962d193719503c527b031b4c2d225092ada71f4a459bc0da98adb95837db8312ea",
+	})
+}
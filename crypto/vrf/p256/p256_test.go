@@ -15,7 +15,10 @@ func TestCorrectness(t *testing.T) {
 	}
 	pub := priv.PublicKey()
 
-	output1, proof := priv.Prove([]byte("Hello, World!"))
+	output1, proof, err := priv.Prove([]byte("Hello, World!"))
+	if err != nil {
+		t.Fatal(err)
+	}
 	t.Logf("%x", output1)
 	t.Logf("%x", proof)
 
@@ -82,7 +85,10 @@ func TestVectors(t *testing.T) {
 		if fmt.Sprintf("%x", pub.Bytes()) != vector.Pub {
 			t.Fatal("unexpected public key computed")
 		}
-		output1, proof := priv.Prove(hexDecode(vector.Message))
+		output1, proof, err := priv.Prove(hexDecode(vector.Message))
+		if err != nil {
+			t.Fatal(err)
+		}
 		if fmt.Sprintf("%x", output1) != vector.Index {
 			t.Fatal("unexpected output computed")
 		} else if fmt.Sprintf("%x", proof) != vector.Proof {
@@ -0,0 +1,40 @@
+// Package p384 implements ECVRF-P384-SHA384-TAI, a VRF cipher suite that
+// follows the construction of ECVRF-P256-SHA256-TAI from RFC 9381 with P-384
+// and SHA-384, for 192-bit security.
+//
+// This suite is NOT defined by RFC 9381 or any other standard, and will not
+// interoperate with other VRF implementations. Its suite_string, 0x05, was
+// chosen by this package and is not registered anywhere. The other parameters
+// that differ from ECVRF-P256-SHA256-TAI are: cLen is 24, ptLen is 49 and qLen
+// is 48. Nonces are generated with RFC 6979 using SHA-384.
+package p384
+
+import (
+	"crypto/elliptic"
+	"crypto/sha512"
+
+	"filippo.io/nistec"
+	"github.com/Bren2010/katie/crypto/vrf/internal/ecvrf"
+)
+
+var suite = &ecvrf.Suite[*nistec.P384Point]{
+	SuiteString: 0x05,
+	NewPoint:    nistec.NewP384Point,
+	N:           elliptic.P384().Params().N,
+	Hash:        sha512.New384,
+
+	CLen:  24,
+	PtLen: 49,
+	QLen:  48,
+}
+
+type (
+	PrivateKey = ecvrf.PrivateKey[*nistec.P384Point]
+	PublicKey  = ecvrf.PublicKey[*nistec.P384Point]
+)
+
+func GeneratePrivateKey() []byte { return suite.GeneratePrivateKey() }
+
+func NewPrivateKey(raw []byte) (*PrivateKey, error) { return suite.NewPrivateKey(raw) }
+
+func NewPublicKey(raw []byte) (*PublicKey, error) { return suite.NewPublicKey(raw) }
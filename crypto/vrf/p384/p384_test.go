@@ -0,0 +1,96 @@
+package p384
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func TestCorrectness(t *testing.T) {
+	raw := GeneratePrivateKey()
+	priv, err := NewPrivateKey(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.PublicKey()
+
+	output1, proof, err := priv.Prove([]byte("Hello, World!"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%x", output1)
+	t.Logf("%x", proof)
+
+	output2, err := pub.Verify([]byte("Hello, World!"), proof)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(output1, output2) {
+		t.Fatal("computed outputs do not match")
+	} else if len(output1) != 48 || len(proof) != 121 {
+		t.Fatal("unexpected output or proof length")
+	}
+
+	_, err = pub.Verify([]byte("Something else"), proof)
+	if err == nil {
+		t.Fatal("expected verification to fail")
+	}
+
+	pub2, err := NewPublicKey(pub.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := pub2.Verify([]byte("Hello, World!"), proof); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func hexDecode(m string) []byte {
+	out, err := hex.DecodeString(m)
+	if err != nil {
+		panic(err)
+	}
+	return out
+}
+
+// TestVectors checks the VRF against known answers. Since this suite isn't
+// standardized, these vectors were generated by this package; they guard
+// against unintentional changes to its outputs.
+func TestVectors(t *testing.T) {
+	priv, err := NewPrivateKey(hexDecode("6b9d3dad2e1b8c1c05b19875b6659f4de23c3b667bf297ba9aa47740787137d896d5724e4c70a825f872c9ea60d2edf5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub := priv.PublicKey()
+	if fmt.Sprintf("%x", pub.Bytes()) != "02ec3a4e415b4e19a4568618029f427fa5da9a8bc4ae92e02e06aae5286b300c64def8f0ea9055866064a254515480bc13" {
+		t.Fatal("unexpected public key computed")
+	}
+
+	vectors := []struct{ message, index, proof string }{
+		{
+			message: "sample",
+			index:   "e42c96bff2ab419011c096eae195df8c2c037e14f3afb3a5091fa146b5d85f06c8270749ec82b789a0b9225417f3fca5",
+			proof:   "023926c9972b4d303a94d447b909f71fd3db7718a005bc0df2737d3fb1c6673cade9ea38192b31b8406136097cec11de206036dc6b2a2b60b43a85d267b90a1b8e0904f10802aa2ca63405539565971d51e6ed867c491dc738f0c9268b938210508481a0b04c2c514f97709338b198d8ab736a7a14d832c250",
+		},
+		{
+			message: "test",
+			index:   "9a18c7dcf5034c401ce8c077c0ffe33676c334981a1b4f1271ce0e1941f55db5157f55a05909aa928d16c54582f40589",
+			proof:   "03ca3626ce53186309ac57bb6621a0efa4cfa3f497a4e114faf0bd6e28bf3225f054297cd15cb0989ae2b3dd3a89b9c9945e8b131138d6e065dbd8d963c55bac73efa448f1a3cead9bd3fe5d22369743d96118c61f58e44e12633622b3fb906ea2a2c98a0e27c7730a13e4970b424e8dc0e43c4289d33944be",
+		},
+	}
+	for _, vector := range vectors {
+		output1, proof, err := priv.Prove([]byte(vector.message))
+		if err != nil {
+			t.Fatal(err)
+		} else if fmt.Sprintf("%x", output1) != vector.index {
+			t.Fatal("unexpected output computed")
+		} else if fmt.Sprintf("%x", proof) != vector.proof {
+			t.Fatal("unexpected proof computed")
+		}
+		output2, err := pub.Verify([]byte(vector.message), proof)
+		if err != nil {
+			t.Fatal(err)
+		} else if fmt.Sprintf("%x", output2) != vector.index {
+			t.Fatal("unexpected output computed")
+		}
+	}
+}
@@ -3,7 +3,7 @@ package vrf
 
 // PrivateKey represents a VRF private key.
 type PrivateKey interface {
-	Prove(m []byte) (output, proof []byte)
+	Prove(m []byte) (output, proof []byte, err error)
 	PublicKey() PublicKey
 }
 
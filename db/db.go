@@ -14,22 +14,42 @@ type PrefixStore interface {
 	BatchGet(keys []string) (map[string][]byte, error)
 	Put(key string, value []byte) error
 	Delete(key string) error
+	// BatchDelete deletes all of the given keys, or none of them if an error
+	// is returned.
+	BatchDelete(keys []string) error
 }
 
 // TransparencyStore is the interface a Transparency Log implementation uses to
 // communicate with its database.
 type TransparencyStore interface {
-	// Clone returns a read-only clone of the current transparency store,
-	// suitable for distributing to child goroutines.
-	Clone() TransparencyStore
+	// Clone returns a read-only snapshot of the most recently committed state
+	// of the transparency store, suitable for distributing to child
+	// goroutines. Changes committed after the snapshot is taken are not
+	// visible through it. Calling Clone on a snapshot returns a new snapshot
+	// of the most recently committed state. Clone may be called concurrently
+	// with any other method.
+	Clone() (TransparencyStore, error)
+	// Release frees the resources held by a snapshot returned by Clone. The
+	// snapshot must not be used afterwards. Calling Release on a store that
+	// isn't a snapshot has no effect.
+	Release()
 
 	GetTreeHead() (treeHead, auditor []byte, err error)
 	PutTreeHead(raw []byte) error
 	PutAuditorTreeHead(raw []byte) error
 
+	// GetCollected returns the version of the prefix tree that garbage was
+	// last collected up to, or 0 if garbage has never been collected.
+	GetCollected() (uint64, error)
+	PutCollected(ver uint64) error
+
 	BatchGetIndex(labels [][]byte) ([][]byte, error)
 	PutIndex(label, index []byte) error
 	DeleteIndex(label []byte) error
+	// ScanIndex returns up to `limit` labels, and their indices, in increasing
+	// order starting with the first label that is greater than or equal to
+	// `start`. Changes that haven't been committed yet may not be reflected.
+	ScanIndex(start []byte, limit int) (labels, indices [][]byte, err error)
 
 	GetVersion(label []byte, ver uint32) ([]byte, error)
 	PutVersion(label []byte, ver uint32, data []byte) error
@@ -39,6 +59,12 @@ type TransparencyStore interface {
 	Put(key uint64, data []byte) error
 	Delete(key uint64) error
 
+	// AuditorUpdates are stored by the position of the log entry they
+	// correspond to, until the Third-Party Auditor has acknowledged them.
+	BatchGetAuditorUpdate(keys []uint64) (map[uint64][]byte, error)
+	PutAuditorUpdate(key uint64, data []byte) error
+	DeleteAuditorUpdate(key uint64) error
+
 	LogStore() LogStore
 	PrefixStore() PrefixStore
 
@@ -1,13 +1,23 @@
 package db
 
 import (
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"sync"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
-const leveldbTreeHeadKey = "tree-head"
+const (
+	leveldbTreeHeadKey  = "tree-head"
+	leveldbCollectedKey = "collected"
+)
 
 func dup(in []byte) []byte {
 	if in == nil {
@@ -18,20 +28,58 @@ func dup(in []byte) []byte {
 	return out
 }
 
+// openLDB opens the LevelDB database at `file`, attempting to recover it if it
+// is corrupted.
+func openLDB(file string) (*leveldb.DB, error) {
+	conn, err := leveldb.OpenFile(file, nil)
+	if errors.IsCorrupted(err) {
+		conn, err = leveldb.RecoverFile(file, nil)
+	}
+	return conn, err
+}
+
 // ldbConn is a wrapper around a base LevelDB database that handles batching
-// writes between commits transparently.
+// writes between commits transparently. A read-only ldbConn reads from a
+// snapshot of the database instead.
 type ldbConn struct {
 	conn     *leveldb.DB
-	readonly bool
+	snapshot *leveldb.Snapshot
+	released bool
 	batch    map[string][]byte
 }
 
-func newLDBConn(conn *leveldb.DB, readonly bool) *ldbConn {
-	return &ldbConn{conn, readonly, make(map[string][]byte)}
+var errSnapshotReleased = errors.New("leveldb: snapshot has been released")
+
+func newLDBConn(conn *leveldb.DB) *ldbConn {
+	return &ldbConn{conn: conn, batch: make(map[string][]byte)}
+}
+
+// newLDBSnapshot returns a read-only ldbConn over a snapshot of the current
+// state of `conn`. The snapshot must be released with Release once it's no
+// longer needed, since LevelDB can't compact away data that's visible to an
+// open snapshot.
+func newLDBSnapshot(conn *leveldb.DB) (*ldbConn, error) {
+	snapshot, err := conn.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &ldbConn{conn: conn, snapshot: snapshot}, nil
+}
+
+// Release releases the snapshot that a read-only ldbConn reads from.
+func (c *ldbConn) Release() {
+	if c.snapshot != nil {
+		c.snapshot.Release()
+		c.released = true
+	}
 }
 
 func (c *ldbConn) Get(key string) ([]byte, error) {
-	if value, ok := c.batch[key]; ok {
+	if c.released {
+		return nil, errSnapshotReleased
+	} else if c.snapshot != nil {
+		return c.snapshot.Get([]byte(key), nil)
+	} else if value, ok := c.batch[key]; ok {
 		if value == nil {
 			return nil, leveldb.ErrNotFound
 		}
@@ -40,23 +88,34 @@ func (c *ldbConn) Get(key string) ([]byte, error) {
 	return c.conn.Get([]byte(key), nil)
 }
 
+// NewIterator returns an iterator over the committed state of the database, or
+// over the snapshot if the connection is read-only.
+func (c *ldbConn) NewIterator(slice *util.Range) iterator.Iterator {
+	if c.released {
+		return iterator.NewEmptyIterator(errSnapshotReleased)
+	} else if c.snapshot != nil {
+		return c.snapshot.NewIterator(slice, nil)
+	}
+	return c.conn.NewIterator(slice, nil)
+}
+
 func (c *ldbConn) Put(key string, value []byte) {
-	if c.readonly {
+	if c.snapshot != nil {
 		panic("connection is readonly")
 	}
 	c.batch[key] = dup(value)
 }
 
 func (c *ldbConn) Commit() error {
-	if c.readonly {
+	if c.snapshot != nil {
 		panic("connection is readonly")
 	}
 
+	// All changes are written in a single batch, so that snapshots observe
+	// either all of them or none of them.
 	b := new(leveldb.Batch)
 	for key, value := range c.batch {
-		if key == leveldbTreeHeadKey {
-			continue
-		} else if value == nil {
+		if value == nil {
 			b.Delete([]byte(key))
 		} else {
 			b.Put([]byte(key), value)
@@ -65,11 +124,6 @@ func (c *ldbConn) Commit() error {
 	if err := c.conn.Write(b, nil); err != nil {
 		return err
 	}
-	if value, ok := c.batch[leveldbTreeHeadKey]; ok {
-		if err := c.conn.Put([]byte(leveldbTreeHeadKey), value, nil); err != nil {
-			return err
-		}
-	}
 
 	c.batch = make(map[string][]byte)
 	return nil
@@ -82,20 +136,23 @@ type ldbTransparencyStore struct {
 }
 
 func NewLDBTransparencyStore(file string) (TransparencyStore, error) {
-	conn, err := leveldb.OpenFile(file, nil)
-	if errors.IsCorrupted(err) {
-		conn, err = leveldb.RecoverFile(file, nil)
-	}
+	conn, err := openLDB(file)
 	if err != nil {
 		return nil, err
 	}
-	return &ldbTransparencyStore{newLDBConn(conn, false)}, nil
+	return &ldbTransparencyStore{newLDBConn(conn)}, nil
 }
 
-func (ldb *ldbTransparencyStore) Clone() TransparencyStore {
-	return &ldbTransparencyStore{newLDBConn(ldb.conn.conn, true)}
+func (ldb *ldbTransparencyStore) Clone() (TransparencyStore, error) {
+	conn, err := newLDBSnapshot(ldb.conn.conn)
+	if err != nil {
+		return nil, err
+	}
+	return &ldbTransparencyStore{conn}, nil
 }
 
+func (ldb *ldbTransparencyStore) Release() { ldb.conn.Release() }
+
 func (ldb *ldbTransparencyStore) GetTreeHead() ([]byte, []byte, error) {
 	treeHead, err := ldb.conn.Get(leveldbTreeHeadKey)
 	if err == leveldb.ErrNotFound {
@@ -120,6 +177,23 @@ func (ldb *ldbTransparencyStore) PutAuditorTreeHead(raw []byte) error {
 	return nil
 }
 
+func (ldb *ldbTransparencyStore) GetCollected() (uint64, error) {
+	raw, err := ldb.conn.Get(leveldbCollectedKey)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	} else if len(raw) != 8 {
+		return 0, errors.New("leveldb: malformed collected version")
+	}
+	return binary.BigEndian.Uint64(raw), nil
+}
+
+func (ldb *ldbTransparencyStore) PutCollected(ver uint64) error {
+	ldb.conn.Put(leveldbCollectedKey, binary.BigEndian.AppendUint64(nil, ver))
+	return nil
+}
+
 func (ldb *ldbTransparencyStore) BatchGetIndex(labels [][]byte) ([][]byte, error) {
 	out := make([][]byte, len(labels))
 
@@ -149,6 +223,28 @@ func (ldb *ldbTransparencyStore) DeleteIndex(label []byte) error {
 	return nil
 }
 
+func (ldb *ldbTransparencyStore) ScanIndex(start []byte, limit int) ([][]byte, [][]byte, error) {
+	iter := ldb.conn.NewIterator(&util.Range{
+		Start: []byte("i" + fmt.Sprintf("%x", start)),
+		Limit: []byte("j"),
+	})
+	defer iter.Release()
+
+	var labels, indices [][]byte
+	for len(labels) < limit && iter.Next() {
+		label, err := hex.DecodeString(string(iter.Key()[1:]))
+		if err != nil {
+			return nil, nil, err
+		}
+		labels = append(labels, label)
+		indices = append(indices, dup(iter.Value()))
+	}
+	if err := iter.Error(); err != nil {
+		return nil, nil, err
+	}
+	return labels, indices, nil
+}
+
 func (ldb *ldbTransparencyStore) GetVersion(label []byte, ver uint32) ([]byte, error) {
 	raw, err := ldb.conn.Get("v" + fmt.Sprintf("%x:%x", label, ver))
 	if err == leveldb.ErrNotFound {
@@ -201,6 +297,35 @@ func (ldb *ldbTransparencyStore) Delete(key uint64) error {
 	return nil
 }
 
+func (ldb *ldbTransparencyStore) BatchGetAuditorUpdate(keys []uint64) (map[uint64][]byte, error) {
+	out := make(map[uint64][]byte)
+
+	for _, key := range keys {
+		value, err := ldb.conn.Get("a" + fmt.Sprint(key))
+		if err == leveldb.ErrNotFound {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		out[key] = value
+	}
+
+	return out, nil
+}
+
+func (ldb *ldbTransparencyStore) PutAuditorUpdate(key uint64, data []byte) error {
+	if data == nil {
+		return errors.New("leveldb: can not store nil value")
+	}
+	ldb.conn.Put("a"+fmt.Sprint(key), data)
+	return nil
+}
+
+func (ldb *ldbTransparencyStore) DeleteAuditorUpdate(key uint64) error {
+	ldb.conn.Put("a"+fmt.Sprint(key), nil)
+	return nil
+}
+
 func (ldb *ldbTransparencyStore) LogStore() LogStore {
 	return &ldbLogStore{ldb.conn}
 }
@@ -280,3 +405,215 @@ func (ps *ldbPrefixStore) Delete(key string) error {
 	ps.conn.Put("p"+key, nil)
 	return nil
 }
+
+func (ps *ldbPrefixStore) BatchDelete(keys []string) error {
+	for _, key := range keys {
+		ps.conn.Put("p"+key, nil)
+	}
+	return nil
+}
+
+const leveldbClientStateKey = "client-state"
+
+// ldbClientStore implements the ClientStore interface over a LevelDB database.
+//
+// Label-specific state is stored under "s" + hex(label), prefixed with the
+// label's 8-byte big-endian terminal value. A secondary index of empty values
+// is stored under "x" + terminal + label, which LevelDB keeps ordered by
+// terminal, so that GetStaleLabel only needs to look at the first entry.
+type ldbClientStore struct {
+	mu   sync.Mutex
+	conn *leveldb.DB
+}
+
+func NewLDBClientStore(file string) (ClientStore, error) {
+	conn, err := openLDB(file)
+	if err != nil {
+		return nil, err
+	}
+	return &ldbClientStore{conn: conn}, nil
+}
+
+func ldbLabelStateKey(label []byte) []byte {
+	return []byte("s" + fmt.Sprintf("%x", label))
+}
+
+func ldbStaleIndexKey(label []byte, terminal uint64) []byte {
+	key := []byte{'x'}
+	key = binary.BigEndian.AppendUint64(key, terminal)
+	return append(key, label...)
+}
+
+// getLabelState returns the label-specific state for `label` and its terminal
+// value, or nil if none is stored.
+func (ldb *ldbClientStore) getLabelState(label []byte) ([]byte, uint64, error) {
+	raw, err := ldb.conn.Get(ldbLabelStateKey(label), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	} else if len(raw) < 8 {
+		return nil, 0, errors.New("leveldb: malformed label state")
+	}
+	return raw[8:], binary.BigEndian.Uint64(raw[:8]), nil
+}
+
+func (ldb *ldbClientStore) GetState() ([]byte, error) {
+	raw, err := ldb.conn.Get([]byte(leveldbClientStateKey), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (ldb *ldbClientStore) GetLabelState(label []byte) ([]byte, error) {
+	raw, _, err := ldb.getLabelState(label)
+	return raw, err
+}
+
+func (ldb *ldbClientStore) GetStaleLabel(cutoff uint64) ([]byte, []byte, error) {
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	iter := ldb.conn.NewIterator(util.BytesPrefix([]byte{'x'}), nil)
+	defer iter.Release()
+
+	if !iter.First() {
+		return nil, nil, iter.Error()
+	}
+	key := iter.Key()
+	if len(key) < 9 {
+		return nil, nil, errors.New("leveldb: malformed stale label index")
+	} else if binary.BigEndian.Uint64(key[1:9]) > cutoff {
+		return nil, nil, nil
+	}
+	label := dup(key[9:])
+
+	raw, _, err := ldb.getLabelState(label)
+	if err != nil {
+		return nil, nil, err
+	} else if raw == nil {
+		return nil, nil, errors.New("leveldb: label state not found for indexed label")
+	}
+	return label, raw, nil
+}
+
+func (ldb *ldbClientStore) PutState(raw []byte) error {
+	if raw == nil {
+		return errors.New("leveldb: can not store nil value")
+	}
+	return ldb.conn.Put([]byte(leveldbClientStateKey), raw, nil)
+}
+
+func (ldb *ldbClientStore) PutLabelState(raw, label, rawLabel []byte, terminal uint64) error {
+	if raw == nil {
+		return errors.New("leveldb: can not store nil value")
+	}
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	old, oldTerminal, err := ldb.getLabelState(label)
+	if err != nil {
+		return err
+	}
+
+	b := new(leveldb.Batch)
+	b.Put([]byte(leveldbClientStateKey), raw)
+	if old != nil {
+		b.Delete(ldbStaleIndexKey(label, oldTerminal))
+	}
+	if rawLabel == nil {
+		b.Delete(ldbLabelStateKey(label))
+	} else {
+		value := binary.BigEndian.AppendUint64(nil, terminal)
+		b.Put(ldbLabelStateKey(label), append(value, rawLabel...))
+		b.Put(ldbStaleIndexKey(label, terminal), []byte{})
+	}
+	return ldb.conn.Write(b, nil)
+}
+
+const leveldbAuditorStateKey = "auditor-state"
+
+// ldbAuditorStore implements the AuditorStore interface over a LevelDB
+// database.
+type ldbAuditorStore struct {
+	conn *leveldb.DB
+}
+
+func NewLDBAuditorStore(file string) (AuditorStore, error) {
+	conn, err := openLDB(file)
+	if err != nil {
+		return nil, err
+	}
+	return &ldbAuditorStore{conn}, nil
+}
+
+func (ldb *ldbAuditorStore) GetState() ([]byte, error) {
+	raw, err := ldb.conn.Get([]byte(leveldbAuditorStateKey), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (ldb *ldbAuditorStore) PutState(raw []byte) error {
+	if raw == nil {
+		return errors.New("leveldb: can not store nil value")
+	}
+	return ldb.conn.Put([]byte(leveldbAuditorStateKey), raw, &opt.WriteOptions{Sync: true})
+}
+
+// ldbManagedLogStore implements the ManagedLogStore interface over a LevelDB
+// database. The greatest version of each label is stored under "g" +
+// hex(label) as an 8-byte big-endian integer.
+type ldbManagedLogStore struct {
+	mu   sync.Mutex
+	conn *leveldb.DB
+}
+
+func NewLDBManagedLogStore(file string) (ManagedLogStore, error) {
+	conn, err := openLDB(file)
+	if err != nil {
+		return nil, err
+	}
+	return &ldbManagedLogStore{conn: conn}, nil
+}
+
+func (ldb *ldbManagedLogStore) IncrementGreatestVersion(label []byte, count int) (int, error) {
+	if count < 0 {
+		return 0, errors.New("leveldb: count must not be negative")
+	}
+	ldb.mu.Lock()
+	defer ldb.mu.Unlock()
+
+	key := []byte("g" + fmt.Sprintf("%x", label))
+
+	prev := int64(-1)
+	raw, err := ldb.conn.Get(key, nil)
+	if err == nil {
+		if len(raw) != 8 {
+			return 0, errors.New("leveldb: malformed greatest version")
+		}
+		prev = int64(binary.BigEndian.Uint64(raw))
+	} else if err != leveldb.ErrNotFound {
+		return 0, err
+	} else if count == 0 {
+		return int(prev), nil
+	}
+
+	next := prev + int64(count)
+	if next > math.MaxUint32 {
+		return 0, errors.New("leveldb: greatest version would overflow")
+	}
+	// Writes are synced to disk so that a version is never signed twice after
+	// a crash.
+	err = ldb.conn.Put(key, binary.BigEndian.AppendUint64(nil, uint64(next)), &opt.WriteOptions{Sync: true})
+	if err != nil {
+		return 0, err
+	}
+	return int(prev), nil
+}
@@ -0,0 +1,224 @@
+package db
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestLDBClientStore(t *testing.T) {
+	store, err := NewLDBClientStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(cutoff uint64, label, state []byte) {
+		t.Helper()
+		gotLabel, gotState, err := store.GetStaleLabel(cutoff)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(gotLabel, label) || !bytes.Equal(gotState, state) {
+			t.Fatalf("unexpected stale label at cutoff %v: %x %x", cutoff, gotLabel, gotState)
+		}
+	}
+	check(^uint64(0), nil, nil)
+
+	if err := store.PutLabelState([]byte("g1"), []byte("a"), []byte("a1"), 10); err != nil {
+		t.Fatal(err)
+	} else if err := store.PutLabelState([]byte("g2"), []byte("b"), []byte("b1"), 5); err != nil {
+		t.Fatal(err)
+	}
+	check(4, nil, nil)
+	check(5, []byte("b"), []byte("b1"))
+
+	// Moving a label's terminal value forward replaces its index entry.
+	if err := store.PutLabelState([]byte("g3"), []byte("b"), []byte("b2"), 20); err != nil {
+		t.Fatal(err)
+	}
+	check(9, nil, nil)
+	check(10, []byte("a"), []byte("a1"))
+
+	// Deleting a label's state removes it from the index.
+	if err := store.PutLabelState([]byte("g4"), []byte("a"), nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	check(19, nil, nil)
+	check(^uint64(0), []byte("b"), []byte("b2"))
+
+	if raw, err := store.GetLabelState([]byte("a")); err != nil || raw != nil {
+		t.Fatalf("unexpected label state: %x %v", raw, err)
+	} else if raw, err := store.GetState(); err != nil || !bytes.Equal(raw, []byte("g4")) {
+		t.Fatalf("unexpected global state: %x %v", raw, err)
+	}
+}
+
+func TestLDBManagedLogStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLDBManagedLogStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if prev, err := store.IncrementGreatestVersion([]byte("a"), 2); err != nil || prev != -1 {
+		t.Fatalf("unexpected previous version: %v %v", prev, err)
+	}
+
+	// Concurrent callers must each be allocated a distinct range of versions.
+	var wg sync.WaitGroup
+	seen := make([]bool, 2+50)
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prev, err := store.IncrementGreatestVersion([]byte("a"), 1)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			seen[prev+1] = true
+		}()
+	}
+	wg.Wait()
+	for ver := 2; ver < len(seen); ver++ {
+		if !seen[ver] {
+			t.Fatalf("version %v was not allocated", ver)
+		}
+	}
+
+	// The counter survives the database being re-opened.
+	store.(*ldbManagedLogStore).conn.Close()
+	store, err = NewLDBManagedLogStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if prev, err := store.IncrementGreatestVersion([]byte("a"), 1); err != nil || prev != 51 {
+		t.Fatalf("unexpected previous version: %v %v", prev, err)
+	}
+}
+
+func TestLDBAuditorStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLDBAuditorStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh database has no state, which NewAuditor takes to mean that it
+	// should start from an empty tree.
+	if raw, err := store.GetState(); err != nil || raw != nil {
+		t.Fatalf("unexpected state in fresh database: %x %v", raw, err)
+	} else if err := store.PutState(nil); err == nil {
+		t.Fatal("expected error storing nil state")
+	}
+
+	if err := store.PutState([]byte("state 1")); err != nil {
+		t.Fatal(err)
+	} else if err := store.PutState([]byte("state 2")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The latest state survives the database being re-opened.
+	store.(*ldbAuditorStore).conn.Close()
+	store, err = NewLDBAuditorStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if raw, err := store.GetState(); err != nil || !bytes.Equal(raw, []byte("state 2")) {
+		t.Fatalf("unexpected state after re-opening: %x %v", raw, err)
+	}
+}
+
+func TestLDBCollected(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLDBTransparencyStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Garbage has never been collected in a fresh database.
+	if ver, err := store.GetCollected(); err != nil || ver != 0 {
+		t.Fatalf("unexpected collected version in fresh database: %d %v", ver, err)
+	}
+	if err := store.PutCollected(7); err != nil {
+		t.Fatal(err)
+	} else if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The collected version survives the database being re-opened.
+	store.(*ldbTransparencyStore).conn.conn.Close()
+	store, err = NewLDBTransparencyStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	} else if ver, err := store.GetCollected(); err != nil || ver != 7 {
+		t.Fatalf("unexpected collected version after re-opening: %d %v", ver, err)
+	}
+}
+
+func TestLDBScanIndex(t *testing.T) {
+	store, err := NewLDBTransparencyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, label := range []string{"b", "a", "c\xff", "c"} {
+		if err := store.PutIndex([]byte(label), []byte(label)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.PutVersion([]byte("a"), 0, []byte("version")); err != nil {
+		t.Fatal(err)
+	} else if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(start string, limit int, expected ...string) {
+		t.Helper()
+		labels, indices, err := store.ScanIndex([]byte(start), limit)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(labels) != len(expected) || len(indices) != len(expected) {
+			t.Fatalf("unexpected number of labels returned: %d", len(labels))
+		}
+		for i, label := range expected {
+			if string(labels[i]) != label || string(indices[i]) != label {
+				t.Fatalf("unexpected label returned: %q", labels[i])
+			}
+		}
+	}
+	check("", 10, "a", "b", "c", "c\xff")
+	check("", 2, "a", "b")
+	check("b\x00", 2, "c", "c\xff")
+	check("d", 2)
+}
+
+func TestLDBSnapshotRelease(t *testing.T) {
+	store, err := NewLDBTransparencyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PutIndex([]byte("a"), []byte("index")); err != nil {
+		t.Fatal(err)
+	} else if err := store.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot, err := store.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	indices, err := snapshot.BatchGetIndex([][]byte{[]byte("a")})
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(indices[0], []byte("index")) {
+		t.Fatal("unexpected index returned")
+	}
+
+	// The snapshot can't be read from once it's released, while the store
+	// itself is unaffected.
+	snapshot.Release()
+	if _, err := snapshot.BatchGetIndex([][]byte{[]byte("a")}); err == nil {
+		t.Fatal("expected error reading from released snapshot")
+	}
+	store.Release()
+	if _, err := store.BatchGetIndex([][]byte{[]byte("a")}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,12 +2,27 @@
 package memory
 
 import (
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"maps"
+	"sort"
+	"sync"
 
 	"github.com/Bren2010/katie/db"
 )
 
+var errReadOnly = errors.New("unable to modify read-only store")
+
+// own replaces the map `m` with a copy of itself if it's shared with a
+// snapshot, so that it can be modified.
+func own[K comparable, V any](m *map[K]V, shared *bool) {
+	if *shared {
+		*m = maps.Clone(*m)
+		*shared = false
+	}
+}
+
 func dup(b []byte) []byte {
 	if b == nil {
 		return nil
@@ -17,60 +32,124 @@ func dup(b []byte) []byte {
 	return out
 }
 
+// TransparencyStore implements db.TransparencyStore in memory. Changes are
+// visible immediately through the store itself, but are only visible to clones
+// once they've been committed. Clones share maps with the store until the
+// store modifies them, at which point the store makes its own copy.
 type TransparencyStore struct {
 	TreeHead, Auditor []byte
+	Collected         uint64
 	Indices           map[string][]byte
 	Versions          map[string][]byte
 	LogEntries        map[uint64][]byte
+	AuditorUpdates    map[uint64][]byte
 
 	logStore    *LogStore
 	prefixStore *PrefixStore
 
 	ReadOnly bool
+
+	// shared tracks which maps are shared with the committed snapshot.
+	shared struct{ indices, versions, logEntries, auditorUpdates bool }
+
+	parent    *TransparencyStore // Store that a snapshot was cloned from.
+	mu        sync.Mutex         // Protects `committed`.
+	committed *TransparencyStore // Snapshot of the committed state.
 }
 
 func NewTransparencyStore() *TransparencyStore {
-	return &TransparencyStore{
-		Indices:    make(map[string][]byte),
-		Versions:   make(map[string][]byte),
-		LogEntries: make(map[uint64][]byte),
+	ts := &TransparencyStore{
+		Indices:        make(map[string][]byte),
+		Versions:       make(map[string][]byte),
+		LogEntries:     make(map[uint64][]byte),
+		AuditorUpdates: make(map[uint64][]byte),
 
 		logStore:    NewLogStore(),
 		prefixStore: NewPrefixStore(),
 
 		ReadOnly: false,
 	}
+	ts.committed = ts.snapshot()
+	return ts
 }
 
-func (ts *TransparencyStore) Clone() db.TransparencyStore {
-	return &TransparencyStore{
-		TreeHead:   ts.TreeHead,
-		Auditor:    ts.Auditor,
-		Indices:    ts.Indices,
-		Versions:   ts.Versions,
-		LogEntries: ts.LogEntries,
+// snapshot returns a read-only view of the current state of the store, and
+// marks all of the store's maps as shared with it.
+func (ts *TransparencyStore) snapshot() *TransparencyStore {
+	ts.shared.indices, ts.shared.versions = true, true
+	ts.shared.logEntries, ts.shared.auditorUpdates = true, true
+	ts.logStore.shared, ts.prefixStore.shared = true, true
 
-		logStore:    ts.logStore,
-		prefixStore: ts.prefixStore,
+	return ts.view(ts)
+}
 
-		ReadOnly: false,
+// view returns a read-only view of the store's maps, as a snapshot of `parent`.
+func (ts *TransparencyStore) view(parent *TransparencyStore) *TransparencyStore {
+	return &TransparencyStore{
+		TreeHead:       ts.TreeHead,
+		Auditor:        ts.Auditor,
+		Collected:      ts.Collected,
+		Indices:        ts.Indices,
+		Versions:       ts.Versions,
+		LogEntries:     ts.LogEntries,
+		AuditorUpdates: ts.AuditorUpdates,
+
+		logStore:    &LogStore{Data: ts.logStore.Data, readOnly: true},
+		prefixStore: &PrefixStore{Data: ts.prefixStore.Data, readOnly: true},
+
+		ReadOnly: true,
+		parent:   parent,
+	}
+}
+
+func (ts *TransparencyStore) Clone() (db.TransparencyStore, error) {
+	root := ts
+	if ts.parent != nil {
+		root = ts.parent
 	}
+	root.mu.Lock()
+	committed := root.committed
+	root.mu.Unlock()
+
+	return committed.view(root), nil
 }
 
+// Release has no effect, since snapshots of a TransparencyStore don't hold any
+// resources other than memory.
+func (ts *TransparencyStore) Release() {}
+
 func (ts *TransparencyStore) GetTreeHead() ([]byte, []byte, error) {
 	return dup(ts.TreeHead), dup(ts.Auditor), nil
 }
 
 func (ts *TransparencyStore) PutTreeHead(raw []byte) error {
+	if ts.ReadOnly {
+		return errReadOnly
+	}
 	ts.TreeHead = dup(raw)
 	return nil
 }
 
 func (ts *TransparencyStore) PutAuditorTreeHead(raw []byte) error {
+	if ts.ReadOnly {
+		return errReadOnly
+	}
 	ts.Auditor = dup(raw)
 	return nil
 }
 
+func (ts *TransparencyStore) GetCollected() (uint64, error) {
+	return ts.Collected, nil
+}
+
+func (ts *TransparencyStore) PutCollected(ver uint64) error {
+	if ts.ReadOnly {
+		return errReadOnly
+	}
+	ts.Collected = ver
+	return nil
+}
+
 func (ts *TransparencyStore) BatchGetIndex(labels [][]byte) ([][]byte, error) {
 	out := make([][]byte, len(labels))
 	for i, label := range labels {
@@ -80,31 +159,71 @@ func (ts *TransparencyStore) BatchGetIndex(labels [][]byte) ([][]byte, error) {
 }
 
 func (ts *TransparencyStore) PutIndex(label []byte, data []byte) error {
-	if data == nil {
+	if ts.ReadOnly {
+		return errReadOnly
+	} else if data == nil {
 		return errors.New("unable to store nil index")
 	}
+	own(&ts.Indices, &ts.shared.indices)
 	ts.Indices[fmt.Sprintf("%x", label)] = dup(data)
 	return nil
 }
 
 func (ts *TransparencyStore) DeleteIndex(label []byte) error {
+	if ts.ReadOnly {
+		return errReadOnly
+	}
+	own(&ts.Indices, &ts.shared.indices)
 	delete(ts.Indices, fmt.Sprintf("%x", label))
 	return nil
 }
 
+func (ts *TransparencyStore) ScanIndex(start []byte, limit int) ([][]byte, [][]byte, error) {
+	startStr := fmt.Sprintf("%x", start)
+	keys := make([]string, 0)
+	for key := range ts.Indices {
+		if key >= startStr {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	labels := make([][]byte, len(keys))
+	indices := make([][]byte, len(keys))
+	for i, key := range keys {
+		label, err := hex.DecodeString(key)
+		if err != nil {
+			return nil, nil, err
+		}
+		labels[i] = label
+		indices[i] = dup(ts.Indices[key])
+	}
+	return labels, indices, nil
+}
+
 func (ts *TransparencyStore) GetVersion(label []byte, ver uint32) ([]byte, error) {
 	return dup(ts.Versions[fmt.Sprintf("%x:%v", label, ver)]), nil
 }
 
 func (ts *TransparencyStore) PutVersion(label []byte, ver uint32, data []byte) error {
-	if data == nil {
+	if ts.ReadOnly {
+		return errReadOnly
+	} else if data == nil {
 		return errors.New("unable to store nil version")
 	}
+	own(&ts.Versions, &ts.shared.versions)
 	ts.Versions[fmt.Sprintf("%x:%v", label, ver)] = dup(data)
 	return nil
 }
 
 func (ts *TransparencyStore) DeleteVersion(label []byte, ver uint32) error {
+	if ts.ReadOnly {
+		return errReadOnly
+	}
+	own(&ts.Versions, &ts.shared.versions)
 	delete(ts.Versions, fmt.Sprintf("%x:%v", label, ver))
 	return nil
 }
@@ -120,24 +239,75 @@ func (ts *TransparencyStore) BatchGet(keys []uint64) (map[uint64][]byte, error)
 }
 
 func (ts *TransparencyStore) Put(key uint64, data []byte) error {
-	if data == nil {
+	if ts.ReadOnly {
+		return errReadOnly
+	} else if data == nil {
 		return errors.New("unable to store nil log entry")
 	}
+	own(&ts.LogEntries, &ts.shared.logEntries)
 	ts.LogEntries[key] = dup(data)
 	return nil
 }
 
 func (ts *TransparencyStore) Delete(key uint64) error {
+	if ts.ReadOnly {
+		return errReadOnly
+	}
+	own(&ts.LogEntries, &ts.shared.logEntries)
 	delete(ts.LogEntries, key)
 	return nil
 }
 
+func (ts *TransparencyStore) BatchGetAuditorUpdate(keys []uint64) (map[uint64][]byte, error) {
+	out := make(map[uint64][]byte)
+	for _, key := range keys {
+		if val, ok := ts.AuditorUpdates[key]; ok {
+			out[key] = dup(val)
+		}
+	}
+	return out, nil
+}
+
+func (ts *TransparencyStore) PutAuditorUpdate(key uint64, data []byte) error {
+	if ts.ReadOnly {
+		return errReadOnly
+	} else if data == nil {
+		return errors.New("unable to store nil auditor update")
+	}
+	own(&ts.AuditorUpdates, &ts.shared.auditorUpdates)
+	ts.AuditorUpdates[key] = dup(data)
+	return nil
+}
+
+func (ts *TransparencyStore) DeleteAuditorUpdate(key uint64) error {
+	if ts.ReadOnly {
+		return errReadOnly
+	}
+	own(&ts.AuditorUpdates, &ts.shared.auditorUpdates)
+	delete(ts.AuditorUpdates, key)
+	return nil
+}
+
 func (ts *TransparencyStore) LogStore() db.LogStore       { return ts.logStore }
 func (ts *TransparencyStore) PrefixStore() db.PrefixStore { return ts.prefixStore }
-func (ts *TransparencyStore) Commit() error               { return nil }
+
+func (ts *TransparencyStore) Commit() error {
+	if ts.ReadOnly {
+		return errReadOnly
+	}
+	committed := ts.snapshot()
+
+	ts.mu.Lock()
+	ts.committed = committed
+	ts.mu.Unlock()
+
+	return nil
+}
 
 type LogStore struct {
 	Data map[uint64][]byte
+
+	shared, readOnly bool
 }
 
 func NewLogStore() *LogStore {
@@ -157,14 +327,21 @@ func (ls *LogStore) BatchGet(keys []uint64) (map[uint64][]byte, error) {
 }
 
 func (ls *LogStore) Put(key uint64, value []byte) error {
-	if value == nil {
+	if ls.readOnly {
+		return errReadOnly
+	} else if value == nil {
 		return errors.New("unable to store nil value")
 	}
+	own(&ls.Data, &ls.shared)
 	ls.Data[key] = dup(value)
 	return nil
 }
 
 func (ls *LogStore) Delete(key uint64) error {
+	if ls.readOnly {
+		return errReadOnly
+	}
+	own(&ls.Data, &ls.shared)
 	delete(ls.Data, key)
 	return nil
 }
@@ -172,6 +349,8 @@ func (ls *LogStore) Delete(key uint64) error {
 type PrefixStore struct {
 	Data    map[string][]byte
 	Lookups [][]string
+
+	shared, readOnly bool
 }
 
 func NewPrefixStore() *PrefixStore {
@@ -192,18 +371,36 @@ func (ps *PrefixStore) BatchGet(keys []string) (map[string][]byte, error) {
 }
 
 func (ps *PrefixStore) Put(key string, value []byte) error {
-	if value == nil {
+	if ps.readOnly {
+		return errReadOnly
+	} else if value == nil {
 		return errors.New("unable to store nil value")
 	}
+	own(&ps.Data, &ps.shared)
 	ps.Data[key] = dup(value)
 	return nil
 }
 
 func (ps *PrefixStore) Delete(key string) error {
+	if ps.readOnly {
+		return errReadOnly
+	}
+	own(&ps.Data, &ps.shared)
 	delete(ps.Data, key)
 	return nil
 }
 
+func (ps *PrefixStore) BatchDelete(keys []string) error {
+	if ps.readOnly {
+		return errReadOnly
+	}
+	own(&ps.Data, &ps.shared)
+	for _, key := range keys {
+		delete(ps.Data, key)
+	}
+	return nil
+}
+
 type AuditorStore struct {
 	Data []byte
 }
@@ -217,6 +414,108 @@ func (as *AuditorStore) PutState(raw []byte) error {
 	return nil
 }
 
+// ClientStore implements db.ClientStore in memory. It's safe for concurrent
+// use, as long as the exported fields aren't accessed directly.
+type ClientStore struct {
+	mu        sync.Mutex
+	State     []byte
+	Labels    map[string][]byte
+	Terminals map[string]uint64
+}
+
+func NewClientStore() *ClientStore {
+	return &ClientStore{
+		Labels:    make(map[string][]byte),
+		Terminals: make(map[string]uint64),
+	}
+}
+
+func (cs *ClientStore) GetState() ([]byte, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return dup(cs.State), nil
+}
+
+func (cs *ClientStore) GetLabelState(label []byte) ([]byte, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return dup(cs.Labels[fmt.Sprintf("%x", label)]), nil
+}
+
+// StaleLabels returns every label with a terminal value less than or equal to
+// `cutoff`, ordered by terminal value. These are the labels that are due for
+// monitoring.
+func (cs *ClientStore) StaleLabels(cutoff uint64) [][]byte {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.staleLabels(cutoff)
+}
+
+func (cs *ClientStore) staleLabels(cutoff uint64) [][]byte {
+	var keys []string
+	for key, terminal := range cs.Terminals {
+		if terminal <= cutoff {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if cs.Terminals[keys[i]] != cs.Terminals[keys[j]] {
+			return cs.Terminals[keys[i]] < cs.Terminals[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	out := make([][]byte, len(keys))
+	for i, key := range keys {
+		label, err := hex.DecodeString(key)
+		if err != nil {
+			panic(err)
+		}
+		out[i] = label
+	}
+	return out
+}
+
+func (cs *ClientStore) GetStaleLabel(cutoff uint64) ([]byte, []byte, error) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	labels := cs.staleLabels(cutoff)
+	if len(labels) == 0 {
+		return nil, nil, nil
+	}
+	return labels[0], dup(cs.Labels[fmt.Sprintf("%x", labels[0])]), nil
+}
+
+func (cs *ClientStore) PutState(raw []byte) error {
+	if raw == nil {
+		return errors.New("unable to store nil value")
+	}
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.State = dup(raw)
+	return nil
+}
+
+func (cs *ClientStore) PutLabelState(raw, label, rawLabel []byte, terminal uint64) error {
+	if raw == nil {
+		return errors.New("unable to store nil value")
+	}
+	labelStr := fmt.Sprintf("%x", label)
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.State = dup(raw)
+	if rawLabel == nil {
+		delete(cs.Labels, labelStr)
+		delete(cs.Terminals, labelStr)
+	} else {
+		cs.Labels[labelStr] = dup(rawLabel)
+		cs.Terminals[labelStr] = terminal
+	}
+	return nil
+}
+
 type ManagedLogStore struct {
 	Data map[string]int
 }
@@ -229,9 +528,9 @@ func (mls *ManagedLogStore) IncrementGreatestVersion(label []byte, count int) (i
 	labelStr := fmt.Sprintf("%x", label)
 
 	ver, ok := mls.Data[labelStr]
-	mls.Data[labelStr] += count
 	if !ok {
-		return -1, nil
+		ver = -1
 	}
+	mls.Data[labelStr] = ver + count
 	return ver, nil
 }
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestClientStore(t *testing.T) {
+	store := NewClientStore()
+
+	check := func(cutoff uint64, label, state []byte) {
+		t.Helper()
+		gotLabel, gotState, err := store.GetStaleLabel(cutoff)
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(gotLabel, label) || !bytes.Equal(gotState, state) {
+			t.Fatalf("unexpected stale label at cutoff %v: %x %x", cutoff, gotLabel, gotState)
+		}
+	}
+	check(^uint64(0), nil, nil)
+
+	if err := store.PutLabelState([]byte("g1"), []byte("a"), []byte("a1"), 10); err != nil {
+		t.Fatal(err)
+	} else if err := store.PutLabelState([]byte("g2"), []byte("b"), []byte("b1"), 5); err != nil {
+		t.Fatal(err)
+	}
+	check(4, nil, nil)
+	check(5, []byte("b"), []byte("b1"))
+
+	// Moving a label's terminal value forward replaces its stale entry.
+	if err := store.PutLabelState([]byte("g3"), []byte("b"), []byte("b2"), 20); err != nil {
+		t.Fatal(err)
+	}
+	check(9, nil, nil)
+	check(10, []byte("a"), []byte("a1"))
+
+	// Deleting a label's state removes it from the stale labels.
+	if err := store.PutLabelState([]byte("g4"), []byte("a"), nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	check(19, nil, nil)
+	check(^uint64(0), []byte("b"), []byte("b2"))
+
+	if raw, err := store.GetLabelState([]byte("a")); err != nil || raw != nil {
+		t.Fatalf("unexpected label state: %x %v", raw, err)
+	} else if raw, err := store.GetState(); err != nil || !bytes.Equal(raw, []byte("g4")) {
+		t.Fatalf("unexpected global state: %x %v", raw, err)
+	}
+}
@@ -1,16 +1,23 @@
 module github.com/Bren2010/katie
 
-go 1.22
+go 1.25
 
 require (
 	filippo.io/edwards25519 v1.1.0
 	filippo.io/nistec v0.0.3
+	github.com/prometheus/client_golang v1.19.1
 	github.com/syndtr/goleveldb v1.0.0
+	gopkg.in/yaml.v2 v2.4.0
 )
 
 require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/golang/snappy v0.0.0-20180518054509-2e65f85255db // indirect
-	golang.org/x/net v0.0.0-20220225172249-27dd8689420f // indirect
-	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a // indirect
-	gopkg.in/yaml.v2 v2.4.0 // indirect
+	github.com/kr/text v0.2.0 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/sys v0.17.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
 )
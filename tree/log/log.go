@@ -88,6 +88,47 @@ func (t *Tree) GetBatch(entries []uint64, n uint64, nP, m *uint64) ([][]byte, er
 	return t.fetchSpecific(math.BatchCopath(entries, n, nP, m))
 }
 
+// GetConsistency returns a proof that the tree of size `m` is a prefix of the
+// tree of size `n`. `n` must not be greater than the current tree size.
+func (t *Tree) GetConsistency(m, n uint64) ([][]byte, error) {
+	if n == 0 || n > math.MaxTreeSize {
+		return nil, errors.New("invalid value for current tree size")
+	} else if m == 0 || m > n {
+		return nil, errors.New("invalid value for previous tree size")
+	}
+	nodes := math.ConsistencyProof(m, n)
+
+	// The rightmost node of the proof may be the root of a subtree that isn't
+	// full, in which case its value is computed from its full subtrees.
+	needed := make([]uint64, 0, len(nodes))
+	for _, x := range nodes {
+		needed = append(needed, math.FullSubtrees(x, n)...)
+	}
+	set, err := t.fetch(needed)
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]byte, len(nodes))
+	for i, x := range nodes {
+		subtrees := math.FullSubtrees(x, n)
+		values := make([][]byte, len(subtrees))
+		for j, y := range subtrees {
+			values[j] = set.get(y).value
+		}
+		out[i] = foldSubtrees(t.cs, subtrees, values).value
+	}
+	return out, nil
+}
+
+// GetFullSubtrees returns the full subtree values of the tree as of when it
+// had `n` entries. `n` must not be greater than the current tree size.
+func (t *Tree) GetFullSubtrees(n uint64) ([][]byte, error) {
+	if n == 0 || n > math.MaxTreeSize {
+		return nil, errors.New("invalid value for tree size")
+	}
+	return t.fetchSpecific(math.FullSubtrees(math.Root(n), n))
+}
+
 // Append adds a new element to the end of the log and returns the new full
 // subtrees. n is the current value; after this operation is complete, methods
 // to this class should be called with n+1.
@@ -179,19 +220,24 @@ func Root(cs suites.CipherSuite, n uint64, fullSubtrees [][]byte) ([]byte, error
 		}
 	}
 
-	// Roll-up full subtree values into singular root hash.
+	return foldSubtrees(cs, subtrees, fullSubtrees).value, nil
+}
+
+// foldSubtrees rolls up the values of the full subtrees `subtrees` of a node
+// into the value of the node itself.
+func foldSubtrees(cs suites.CipherSuite, subtrees []uint64, values [][]byte) *nodeData {
 	acc := &nodeData{
 		leaf:  math.IsLeaf(subtrees[len(subtrees)-1]),
-		value: fullSubtrees[len(fullSubtrees)-1],
+		value: values[len(values)-1],
 	}
-	for i := len(fullSubtrees) - 2; i >= 0; i-- {
+	for i := len(values) - 2; i >= 0; i-- {
 		acc = treeHash(
 			cs,
-			&nodeData{leaf: math.IsLeaf(subtrees[i]), value: fullSubtrees[i]},
+			&nodeData{leaf: math.IsLeaf(subtrees[i]), value: values[i]},
 			acc,
 		)
 	}
-	return acc.value, nil
+	return acc
 }
 
 // Append returns the new `fullSubtrees` slice after a new leaf with value
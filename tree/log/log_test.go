@@ -186,3 +186,187 @@ func TestAppend(t *testing.T) {
 		}
 	}
 }
+
+func TestGetFullSubtrees(t *testing.T) {
+	cs := suites.KTSha256P256{}
+	tree := NewTree(cs, memory.NewLogStore())
+
+	var fullSubtrees [][][]byte
+	for i := range uint64(300) {
+		subtrees, err := tree.Append(i, random())
+		if err != nil {
+			t.Fatal(err)
+		}
+		fullSubtrees = append(fullSubtrees, subtrees)
+	}
+
+	for i, expected := range fullSubtrees {
+		subtrees, err := tree.GetFullSubtrees(uint64(i + 1))
+		if err != nil {
+			t.Fatal(err)
+		} else if len(subtrees) != len(expected) {
+			t.Fatal("unexpected number of full subtrees")
+		}
+		for j := range subtrees {
+			if !bytes.Equal(subtrees[j], expected[j]) {
+				t.Fatalf("unexpected full subtree value for tree size %v", i+1)
+			}
+		}
+	}
+}
+
+func TestConsistency(t *testing.T) {
+	cs := suites.KTSha256P256{}
+	tree := NewTree(cs, memory.NewLogStore())
+
+	var roots [][]byte
+	for i := range uint64(300) {
+		subtrees, err := tree.Append(i, random())
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := Root(cs, i+1, subtrees)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots = append(roots, root)
+	}
+
+	verifier := NewVerifier(cs)
+	for m := uint64(1); m <= 300; m++ {
+		for _, n := range []uint64{m, m + 1, m + 37, 300} {
+			if n > 300 {
+				continue
+			}
+			proof, err := tree.GetConsistency(m, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := verifier.VerifyConsistency(m, n, roots[m-1], roots[n-1], proof); err != nil {
+				t.Fatalf("failed to verify proof between %v and %v: %v", m, n, err)
+			}
+
+			// Modifying any element of the proof, or either root, causes
+			// verification to fail.
+			for i := range proof {
+				tampered := slices.Clone(proof)
+				tampered[i] = random()
+				if err := verifier.VerifyConsistency(m, n, roots[m-1], roots[n-1], tampered); err == nil {
+					t.Fatal("expected error verifying tampered proof")
+				}
+			}
+			if err := verifier.VerifyConsistency(m, n, random(), roots[n-1], proof); err == nil {
+				t.Fatal("expected error verifying with wrong previous root")
+			} else if err := verifier.VerifyConsistency(m, n, roots[m-1], random(), proof); err == nil {
+				t.Fatal("expected error verifying with wrong current root")
+			}
+		}
+	}
+
+	if _, err := tree.GetConsistency(0, 10); err == nil {
+		t.Fatal("expected error for previous tree size of zero")
+	} else if _, err := tree.GetConsistency(11, 10); err == nil {
+		t.Fatal("expected error for previous tree size greater than current")
+	}
+}
+
+// TestConsistencyExample checks the consistency proofs from the example in
+// Section 2.1.5 of RFC 9162.
+func TestConsistencyExample(t *testing.T) {
+	cs := suites.KTSha256P256{}
+	tree := NewTree(cs, memory.NewLogStore())
+
+	var d []*nodeData
+	for i := range uint64(7) {
+		d = append(d, &nodeData{leaf: true, value: random()})
+		if _, err := tree.Append(i, d[i].value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g, h := treeHash(cs, d[0], d[1]), treeHash(cs, d[2], d[3])
+	i, j := treeHash(cs, d[4], d[5]), d[6]
+	k, l := treeHash(cs, g, h), treeHash(cs, i, j)
+
+	testCases := []struct {
+		m        uint64
+		expected []*nodeData
+	}{
+		{3, []*nodeData{d[2], d[3], g, l}},
+		{4, []*nodeData{l}},
+		{6, []*nodeData{i, j, k}},
+	}
+	for _, tc := range testCases {
+		proof, err := tree.GetConsistency(tc.m, 7)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(proof) != len(tc.expected) {
+			t.Fatalf("unexpected proof length between %v and 7: %v", tc.m, len(proof))
+		}
+		for x, nd := range tc.expected {
+			if !bytes.Equal(proof[x], nd.value) {
+				t.Fatalf("unexpected proof element %v between %v and 7", x, tc.m)
+			}
+		}
+	}
+}
+
+// TestHashSize checks that inclusion and consistency proofs work with a cipher
+// suite whose hash size isn't 32 bytes.
+func TestHashSize(t *testing.T) {
+	cs := suites.KTSha384P384{}
+	tree := NewTree(cs, memory.NewLogStore())
+
+	var (
+		leaves       [][]byte
+		fullSubtrees [][][]byte
+		roots        [][]byte
+	)
+	for i := range uint64(100) {
+		leaf := make([]byte, cs.HashSize())
+		rand.Read(leaf)
+		leaves = append(leaves, leaf)
+
+		subtrees, err := tree.Append(i, leaf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root, err := Root(cs, i+1, subtrees)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fullSubtrees = append(fullSubtrees, subtrees)
+		roots = append(roots, root)
+	}
+	if _, err := tree.Append(100, random()); err == nil {
+		t.Fatal("expected error appending leaf of wrong size")
+	}
+
+	entries := []uint64{3, 50, 99}
+	values := [][]byte{leaves[3], leaves[50], leaves[99]}
+	m := uint64(37)
+	proof, err := tree.GetBatch(entries, 100, nil, &m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewVerifier(cs)
+	if err := verifier.Retain(m, fullSubtrees[m-1]); err != nil {
+		t.Fatal(err)
+	}
+	subtrees, _, err := verifier.Evaluate(entries, 100, nil, values, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := Root(cs, 100, subtrees)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(root, roots[99]) {
+		t.Fatal("root hash does not match")
+	}
+
+	proof, err = tree.GetConsistency(m, 100)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := verifier.VerifyConsistency(m, 100, roots[m-1], roots[99], proof); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -189,6 +189,43 @@ func BatchCopath(leaves []uint64, n uint64, nP, m *uint64) []uint64 {
 	return batchCopath(Root(n), n, nodes, include)
 }
 
+// ConsistencyProof returns the nodes of a consistency proof between tree sizes
+// `m` and `n`, where 0 < m <= n, as computed by PROOF(m, D[n]) in Section
+// 2.1.4.1 of RFC 9162.
+func ConsistencyProof(m, n uint64) []uint64 {
+	return subProof(m, 0, n, true)
+}
+
+// subProof returns the nodes computed by SUBPROOF(m, D[start:start+n], b) in
+// Section 2.1.4.1 of RFC 9162, where D[start:start+n] is a subtree of a larger
+// tree.
+func subProof(m, start, n uint64, b bool) []uint64 {
+	if m == n {
+		if b {
+			return []uint64{}
+		}
+		return []uint64{subtree(start, n)}
+	}
+	k := Split(n)
+	if m <= k {
+		return append(subProof(m, start, k, b), subtree(start+k, n-k))
+	}
+	return append(subProof(m-k, start+k, n-k, false), subtree(start, k))
+}
+
+// Split returns the number of leaves in the left subtree of a tree with `n`
+// leaves, where n > 1. This is the largest power of two less than `n`.
+func Split(n uint64) uint64 {
+	return 1 << log2(n-1)
+}
+
+// subtree returns the id of the root node of the subtree that contains the `n`
+// leaves starting at `start`, where D[start:start+n] is a subtree of a larger
+// tree.
+func subtree(start, n uint64) uint64 {
+	return 2*start + Root(n)
+}
+
 func batchCopath(x, n uint64, nodes []uint64, include map[uint64]struct{}) []uint64 {
 	if len(nodes) == 0 {
 		return FullSubtrees(x, n)
@@ -62,4 +62,16 @@ func TestMath(t *testing.T) {
 
 	thirtyNine := uint64(39)
 	assert(slicesEq(BatchCopath([]uint64{38}, 39, nil, &thirtyNine), []uint64{}))
+
+	// Example consistency proofs from Section 2.1.5 of RFC 9162, for a tree
+	// with seven leaves: c=4, d=6, g=1, i=9, j=12, k=3, and l=11.
+	assert(slicesEq(ConsistencyProof(3, 7), []uint64{4, 6, 1, 11}))
+	assert(slicesEq(ConsistencyProof(4, 7), []uint64{11}))
+	assert(slicesEq(ConsistencyProof(6, 7), []uint64{9, 12, 3}))
+	assert(slicesEq(ConsistencyProof(7, 7), []uint64{}))
+
+	assert(slicesEq(ConsistencyProof(4, 8), []uint64{11}))
+	assert(slicesEq(ConsistencyProof(6, 8), []uint64{9, 13, 3}))
+	assert(slicesEq(ConsistencyProof(1, 8), []uint64{2, 5, 11}))
+	assert(subtree(4, 3) == 11)
 }
@@ -117,6 +117,109 @@ func (v *Verifier) Evaluate(entries []uint64, n uint64, nP *uint64, values [][]b
 	return fullSubtrees, additional, nil
 }
 
+// VerifyConsistency checks that `proof` is a valid consistency proof between
+// the tree of size `m` with root `oldRoot`, and the tree of size `n` with root
+// `newRoot`. It neither uses nor modifies the verifier's retained state.
+func (v *Verifier) VerifyConsistency(m, n uint64, oldRoot, newRoot []byte, proof [][]byte) error {
+	root, err := v.EvaluateConsistency(m, n, oldRoot, proof)
+	if err != nil {
+		return err
+	} else if !bytes.Equal(root, newRoot) {
+		return errors.New("current root does not match proof")
+	}
+	return nil
+}
+
+// EvaluateConsistency checks that `proof` is a valid consistency proof from the
+// tree of size `m` with root `oldRoot`, and returns the root of the tree of
+// size `n` that it proves consistency with. The proof is in the format of
+// Section 2.1.4 of RFC 9162. It neither uses nor modifies the verifier's
+// retained state.
+//
+// Intermediate hashes in a Log Tree also depend on whether each child is a
+// leaf, so the proof is evaluated by following the recursive structure of
+// SUBPROOF, rather than with the iterative algorithm in Section 2.1.4.2.
+func (v *Verifier) EvaluateConsistency(m, n uint64, oldRoot []byte, proof [][]byte) ([]byte, error) {
+	if n == 0 || n > math.MaxTreeSize {
+		return nil, errors.New("invalid value for current tree size")
+	} else if m == 0 || m > n {
+		return nil, errors.New("invalid value for previous tree size")
+	} else if len(oldRoot) != v.cs.HashSize() {
+		return nil, errors.New("hash has wrong size")
+	}
+	for _, val := range proof {
+		if len(val) != v.cs.HashSize() {
+			return nil, errors.New("hash has wrong size")
+		}
+	}
+
+	cp := &consistencyProof{cs: v.cs, oldRoot: oldRoot, proof: proof}
+	old, current, err := cp.evaluate(m, n, true)
+	if err != nil {
+		return nil, err
+	} else if len(cp.proof) != 0 {
+		return nil, errors.New("malformed proof")
+	} else if !bytes.Equal(old.value, oldRoot) {
+		return nil, errors.New("previous root does not match proof")
+	}
+	return current.value, nil
+}
+
+// consistencyProof wraps the remaining elements of a consistency proof while
+// it's being evaluated.
+type consistencyProof struct {
+	cs      suites.CipherSuite
+	oldRoot []byte
+	proof   [][]byte
+}
+
+// next consumes the next element of the proof, which is the value of a leaf if
+// `leaf` is true and an intermediate hash otherwise.
+func (cp *consistencyProof) next(leaf bool) (*nodeData, error) {
+	if len(cp.proof) == 0 {
+		return nil, errors.New("malformed proof")
+	}
+	nd := &nodeData{leaf: leaf, value: cp.proof[0]}
+	cp.proof = cp.proof[1:]
+	return nd, nil
+}
+
+// evaluate consumes the elements of SUBPROOF(m, D[n], b) from the proof, where
+// D[n] is a subtree with `n` leaves. It returns the value of the subtree as of
+// when it had `m` leaves, and with all `n` leaves.
+func (cp *consistencyProof) evaluate(m, n uint64, b bool) (*nodeData, *nodeData, error) {
+	if m == n {
+		if b {
+			nd := &nodeData{leaf: n == 1, value: cp.oldRoot}
+			return nd, nd, nil
+		}
+		nd, err := cp.next(n == 1)
+		return nd, nd, err
+	}
+
+	k := math.Split(n)
+	if m <= k {
+		old, current, err := cp.evaluate(m, k, b)
+		if err != nil {
+			return nil, nil, err
+		}
+		right, err := cp.next(n-k == 1)
+		if err != nil {
+			return nil, nil, err
+		}
+		return old, treeHash(cp.cs, current, right), nil
+	}
+	old, current, err := cp.evaluate(m-k, n-k, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	left, err := cp.next(k == 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	return treeHash(cp.cs, left, old), treeHash(cp.cs, left, current), nil
+}
+
 func (v *Verifier) addToMap(m map[uint64]*nodeData, x uint64, val []byte) error {
 	if len(val) != v.cs.HashSize() {
 		return errors.New("value is unexpected size")
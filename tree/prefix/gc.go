@@ -0,0 +1,309 @@
+package prefix
+
+import "errors"
+
+// GCStats reports how much storage was reclaimed by CollectGarbage.
+type GCStats struct {
+	Tiles int // Number of tiles deleted.
+	Bytes int // Total size of the deleted tiles, in bytes.
+}
+
+// CollectGarbage deletes the tiles that are only needed to search versions of
+// the tree before `end`, which no longer need to be searchable, and returns how
+// much storage was reclaimed. Version `end` and every later version stay
+// searchable, and the tree can continue to be mutated.
+//
+// Each version of the tree replaces some of the nodes of the previous version
+// with new ones, and a node is never used again once it's been replaced. The
+// tiles that are only needed for versions before `end` are therefore the ones
+// whose nodes were all replaced by versions up to and including `end`. These
+// are found by comparing each version with the one before it, which only
+// requires loading the tiles along the paths that changed between them. The
+// rest of the tree is never loaded.
+//
+// The versions before `start` must have been collected already, meaning that
+// `start` is the value of `end` from the previous call, or 0 if this is the
+// first call.
+//
+// All of the tiles are deleted in a single batch, after the tiles to delete
+// have been found.
+func (t *Tree) CollectGarbage(start, end uint64) (*GCStats, error) {
+	if start > end {
+		return nil, errors.New("unable to collect garbage from versions that were already collected")
+	}
+
+	stats := &GCStats{}
+	var keys []string
+	for ver := max(start+1, 2); ver <= end; ver++ {
+		err := t.replaced(ver, func(id tileId, raw []byte) {
+			keys = append(keys, id.String())
+			stats.Tiles++
+			stats.Bytes += len(raw)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(keys) == 0 {
+		return stats, nil
+	} else if err := t.tx.BatchDelete(keys); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// gcCursor is a position in the tree that's being compared between two
+// versions, or between a tile and a version.
+type gcCursor struct {
+	prev, next node   // The nodes being compared at this position, or nil.
+	path       []byte // The bits leading to this position.
+	depth      int    // The depth of this position.
+}
+
+// replaced calls `f` on each tile of version `ver-1` of the tree that has no
+// nodes left in version `ver`.
+//
+// Both versions are walked together, starting at their roots. Wherever version
+// `ver` has an external node that refers to a tile from a previous version,
+// the subtree is the same in both versions and isn't walked any further.
+// Everywhere else, the node from version `ver-1` was replaced, and the tile it
+// came from is recorded.
+//
+// A recorded tile may still have nodes elsewhere in version `ver`, since parts
+// of a tile can be replaced while other parts are still referenced, possibly
+// through newer tiles. Each recorded tile that version `ver` doesn't refer to
+// directly is compared with version `ver` to check for this.
+func (t *Tree) replaced(ver uint64, f func(id tileId, raw []byte)) error {
+	type changedTile struct {
+		raw  []byte
+		path []byte // The path to a position where the tile was entered.
+	}
+	kept := make(map[tileId]struct{})
+	changed := make(map[tileId]changedTile)
+	cache := make(map[tileId]tile)
+
+	queue := []gcCursor{{
+		prev: externalNode{id: tileId{ver: ver - 1, ctr: 0}},
+		next: externalNode{id: tileId{ver: ver, ctr: 0}},
+		path: make([]byte, t.cs.HashSize()),
+	}}
+	for len(queue) > 0 {
+		// Skip the subtrees that are the same in both versions, and load the
+		// tiles needed to continue the walk everywhere else.
+		cursors := queue[:0]
+		var ids []tileId
+		for _, c := range queue {
+			if ext, ok := c.next.(externalNode); ok && ext.id.ver < ver {
+				kept[ext.id] = struct{}{}
+				continue
+			}
+			cursors = append(cursors, c)
+			for _, n := range []node{c.prev, c.next} {
+				if ext, ok := n.(externalNode); ok {
+					ids = append(ids, ext.id)
+				}
+			}
+		}
+		raws, err := t.loadTiles(ids, cache)
+		if err != nil {
+			return err
+		}
+
+		queue = nil
+		for _, c := range cursors {
+			if ext, ok := c.prev.(externalNode); ok {
+				if _, ok := changed[ext.id]; !ok {
+					changed[ext.id] = changedTile{raw: raws[ext.id], path: c.path}
+				}
+			}
+			prev, err := resolve(c.prev, c, cache)
+			if err != nil {
+				return err
+			}
+			next, err := resolve(c.next, c, cache)
+			if err != nil {
+				return err
+			}
+			queue = append(queue, children(prev, next, c)...)
+		}
+	}
+
+	for id, ct := range changed {
+		if _, ok := kept[id]; ok {
+			continue
+		} else if used, err := t.used(ver, id, ct.path, cache); err != nil {
+			return err
+		} else if !used {
+			f(id, ct.raw)
+		}
+	}
+	return nil
+}
+
+// used returns true if version `ver` of the tree has any of the nodes of the
+// tile `id`. The tile must be in `cache`, and `path` must lead to a position
+// that's in the tile.
+func (t *Tree) used(ver uint64, id tileId, path []byte, cache map[tileId]tile) (bool, error) {
+	target := cache[id]
+
+	// Find the node of version `ver` at the position of the tile's root.
+	root := make([]byte, len(path))
+	for i := range target.depth {
+		if getBit(path, i) {
+			setBit(root, i)
+		}
+	}
+	c := gcCursor{next: externalNode{id: tileId{ver: ver, ctr: 0}}, path: root}
+	for {
+		n, owner, err := t.resolveOwner(c.next, c, cache)
+		if err != nil {
+			return false, err
+		} else if c.depth == target.depth {
+			return t.usedSubtree(id, target.root, n, owner, c, cache)
+		}
+		p, ok := n.(*parentNode)
+		if !ok {
+			return false, nil
+		} else if getBit(root, c.depth) {
+			c.next = p.right
+		} else {
+			c.next = p.left
+		}
+		c.depth++
+	}
+}
+
+// usedSubtree returns true if any node of the subtree `n` of the tile `id` is
+// also in the subtree `other`, which is stored in the tile `owner`.
+func (t *Tree) usedSubtree(id tileId, n, other node, owner tileId, c gcCursor, cache map[tileId]tile) (bool, error) {
+	if owner == id {
+		return true, nil
+	}
+	p, ok := n.(*parentNode)
+	if !ok {
+		return false, nil
+	}
+	q, ok := other.(*parentNode)
+	if !ok {
+		return false, nil
+	}
+
+	cursors := children(p, q, c)
+	for _, child := range cursors {
+		next, nextOwner, err := t.resolveOwner(child.next, child, cache)
+		if err != nil {
+			return false, err
+		} else if _, ok := child.next.(externalNode); !ok {
+			nextOwner = *q.id
+		}
+		used, err := t.usedSubtree(id, child.prev, next, nextOwner, child, cache)
+		if err != nil || used {
+			return used, err
+		}
+	}
+	return false, nil
+}
+
+// resolveOwner returns the node at the position of `c`, loading the tile it's
+// stored in if `n` is an external node. It also returns the id of the tile the
+// node is stored in, if `n` is an external node or a parent node.
+func (t *Tree) resolveOwner(n node, c gcCursor, cache map[tileId]tile) (node, tileId, error) {
+	switch m := n.(type) {
+	case externalNode:
+		if _, err := t.loadTiles([]tileId{m.id}, cache); err != nil {
+			return nil, tileId{}, err
+		}
+		resolved, err := resolve(n, c, cache)
+		return resolved, m.id, err
+	case *parentNode:
+		return n, *m.id, nil
+	default:
+		return n, tileId{}, nil
+	}
+}
+
+// loadTiles loads the tiles in `ids` that aren't already in `cache` and adds
+// them to it. It returns the serialized tiles, keyed by id.
+func (t *Tree) loadTiles(ids []tileId, cache map[tileId]tile) (map[tileId][]byte, error) {
+	var keys []string
+	for _, id := range ids {
+		if _, ok := cache[id]; !ok {
+			keys = append(keys, id.String())
+		}
+	}
+	data, err := t.tx.BatchGet(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[tileId][]byte, len(ids))
+	for _, id := range ids {
+		raw, ok := data[id.String()]
+		if _, cached := cache[id]; cached {
+			continue
+		} else if !ok {
+			return nil, errors.New("not all expected data was found")
+		}
+		parsed, err := unmarshalTile(t.cs, id, raw)
+		if err != nil {
+			return nil, err
+		}
+		cache[id] = parsed
+		out[id] = raw
+	}
+	return out, nil
+}
+
+// resolve returns the node at the position of `c`, looking up the subtree in
+// `cache` if `n` is an external node.
+func resolve(n node, c gcCursor, cache map[tileId]tile) (node, error) {
+	ext, ok := n.(externalNode)
+	if !ok {
+		return n, nil
+	}
+	t := cache[ext.id]
+
+	// Recurse down within the tile until we reach the desired depth.
+	n = t.root
+	for i := range c.depth - t.depth {
+		p, ok := n.(*parentNode)
+		if !ok {
+			return nil, errors.New("unexpected node found in search path")
+		} else if getBit(c.path, t.depth+i) {
+			n = p.right
+		} else {
+			n = p.left
+		}
+	}
+	return n, nil
+}
+
+// children returns the cursors for the children of the position of `c`, given
+// the nodes at that position in each version.
+func children(prev, next node, c gcCursor) []gcCursor {
+	prevParent, _ := prev.(*parentNode)
+	nextParent, _ := next.(*parentNode)
+	if prevParent == nil && nextParent == nil {
+		return nil
+	}
+
+	right := make([]byte, len(c.path))
+	copy(right, c.path)
+	setBit(right, c.depth)
+
+	out := []gcCursor{
+		{path: c.path, depth: c.depth + 1},
+		{path: right, depth: c.depth + 1},
+	}
+	if prevParent != nil {
+		out[0].prev, out[1].prev = prevParent.left, prevParent.right
+	}
+	if nextParent != nil {
+		out[0].next, out[1].next = nextParent.left, nextParent.right
+	}
+	return out
+}
+
+func setBit(data []byte, bit int) {
+	data[bit/8] |= 1 << (7 - (bit % 8))
+}
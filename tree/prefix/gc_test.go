@@ -0,0 +1,134 @@
+package prefix
+
+import (
+	"testing"
+
+	"github.com/Bren2010/katie/crypto/suites"
+	"github.com/Bren2010/katie/db/memory"
+)
+
+func TestCollectGarbage(t *testing.T) {
+	cs := suites.KTSha256P256{}
+	store := memory.NewPrefixStore()
+	tree := NewTree(cs, store)
+
+	var vrfOutputs [][]byte
+	mutate := func(ver uint64) {
+		t.Helper()
+		entries := make([]Entry, 0)
+		for range 50 {
+			vrfOutput, commitment := randomBytes(), randomBytes()
+			entries = append(entries, Entry{vrfOutput[:], commitment[:]})
+			vrfOutputs = append(vrfOutputs, vrfOutput[:])
+		}
+		var remove [][]byte
+		if ver > 0 {
+			remove = append(remove, vrfOutputs[0])
+			vrfOutputs = vrfOutputs[1:]
+		}
+		if _, _, _, err := tree.Mutate(ver, entries, remove); err != nil {
+			t.Fatal(err)
+		}
+	}
+	search := func(ver uint64) error {
+		_, err := tree.Search([]PrefixSearch{{ver, vrfOutputs[:len(vrfOutputs)-50]}})
+		return err
+	}
+	// live returns the set of tiles that contain a node of any version from
+	// `start` through `end`, found by loading every one of them.
+	live := func(start, end uint64) map[string]struct{} {
+		t.Helper()
+		out := make(map[string]struct{})
+		cache := make(map[tileId]tile)
+		var visit func(n node, c gcCursor)
+		visit = func(n node, c gcCursor) {
+			if ext, ok := n.(externalNode); ok {
+				if _, err := tree.loadTiles([]tileId{ext.id}, cache); err != nil {
+					t.Fatal(err)
+				}
+				out[ext.id.String()] = struct{}{}
+			}
+			n, err := resolve(n, c, cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, child := range children(n, nil, c) {
+				visit(child.prev, child)
+			}
+		}
+		for ver := start; ver <= end; ver++ {
+			root := externalNode{id: tileId{ver: ver, ctr: 0}}
+			visit(root, gcCursor{path: make([]byte, cs.HashSize())})
+		}
+		return out
+	}
+	collect := func(start, end, latest uint64) {
+		t.Helper()
+		size := func() (out int) {
+			for _, raw := range store.Data {
+				out += len(raw)
+			}
+			return
+		}
+		tiles, bytes := len(store.Data), size()
+		expected := live(end, latest)
+
+		stats, err := tree.CollectGarbage(start, end)
+		if err != nil {
+			t.Fatal(err)
+		} else if stats.Tiles == 0 {
+			t.Fatal("expected tiles to be deleted")
+		} else if stats.Tiles != tiles-len(store.Data) || stats.Bytes != bytes-size() {
+			t.Fatal("unexpected amount of storage reclaimed")
+		}
+
+		// Exactly the tiles that are reachable from the remaining versions are
+		// left in the database.
+		if len(store.Data) != len(expected) {
+			t.Fatal("unexpected number of tiles left in database")
+		}
+		for key := range expected {
+			if _, ok := store.Data[key]; !ok {
+				t.Fatal("reachable tile was deleted")
+			}
+		}
+	}
+	for ver := range uint64(10) {
+		mutate(ver)
+	}
+
+	// Collect every version before 5. Versions 5 and later can still be
+	// searched, and the tree can still be mutated.
+	collect(0, 5, 10)
+	for ver := uint64(5); ver <= 10; ver++ {
+		if err := search(ver); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := search(4); err == nil {
+		t.Fatal("expected search of collected version to fail")
+	}
+	mutate(10)
+	if err := search(11); err != nil {
+		t.Fatal(err)
+	}
+
+	// Continue collecting from where the previous call stopped.
+	collect(5, 10, 11)
+	if err := search(10); err != nil {
+		t.Fatal(err)
+	} else if err := search(11); err != nil {
+		t.Fatal(err)
+	}
+
+	// Collecting up to the same version again has no effect.
+	if stats, err := tree.CollectGarbage(10, 10); err != nil {
+		t.Fatal(err)
+	} else if stats.Tiles != 0 {
+		t.Fatal("unexpected tiles deleted")
+	}
+
+	if _, err := tree.CollectGarbage(10, 9); err == nil {
+		t.Fatal("expected error when collecting versions that were already collected")
+	}
+}
@@ -0,0 +1,83 @@
+package prefix
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Names of each type of search result in the JSON encoding of a PrefixProof.
+const (
+	inclusionResultName          = "inclusion"
+	nonInclusionLeafResultName   = "non_inclusion_leaf"
+	nonInclusionParentResultName = "non_inclusion_parent"
+)
+
+// searchResultJSON is the JSON encoding of a PrefixSearchResult. The VrfOutput
+// and Commitment fields are only present for non-inclusion leaf results.
+type searchResultJSON struct {
+	Type       string `json:"type"`
+	VrfOutput  []byte `json:"vrf_output,omitempty"`
+	Commitment []byte `json:"commitment,omitempty"`
+	Depth      int    `json:"depth"`
+}
+
+type prefixProofJSON struct {
+	Results  []searchResultJSON `json:"results,omitempty"`
+	Elements [][]byte           `json:"elements,omitempty"`
+}
+
+func (pp PrefixProof) MarshalJSON() ([]byte, error) {
+	out := prefixProofJSON{Elements: pp.Elements}
+	for _, res := range pp.Results {
+		switch res := res.(type) {
+		case inclusionProof:
+			out.Results = append(out.Results, searchResultJSON{Type: inclusionResultName, Depth: res.depth})
+		case nonInclusionLeafProof:
+			out.Results = append(out.Results, searchResultJSON{
+				Type:       nonInclusionLeafResultName,
+				VrfOutput:  res.leaf.vrfOutput,
+				Commitment: res.leaf.commitment,
+				Depth:      res.depth,
+			})
+		case nonInclusionParentProof:
+			out.Results = append(out.Results, searchResultJSON{Type: nonInclusionParentResultName, Depth: res.depth})
+		default:
+			return nil, errors.New("unexpected prefix search result type")
+		}
+	}
+	return json.Marshal(out)
+}
+
+func (pp *PrefixProof) UnmarshalJSON(raw []byte) error {
+	var in prefixProofJSON
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return err
+	}
+
+	var results []PrefixSearchResult
+	for _, res := range in.Results {
+		if res.Depth < 0 || res.Depth > 255 {
+			return errors.New("prefix search result depth is out of range")
+		}
+		if res.Type != nonInclusionLeafResultName && (res.VrfOutput != nil || res.Commitment != nil) {
+			return errors.New("unexpected leaf in prefix search result")
+		}
+
+		switch res.Type {
+		case inclusionResultName:
+			results = append(results, inclusionProof{res.Depth})
+		case nonInclusionLeafResultName:
+			results = append(results, nonInclusionLeafProof{
+				leaf:  leafNode{res.VrfOutput, res.Commitment},
+				depth: res.Depth,
+			})
+		case nonInclusionParentResultName:
+			results = append(results, nonInclusionParentProof{res.Depth})
+		default:
+			return errors.New("invalid prefix search result type read")
+		}
+	}
+
+	*pp = PrefixProof{Results: results, Elements: in.Elements}
+	return nil
+}
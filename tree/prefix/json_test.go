@@ -0,0 +1,49 @@
+package prefix
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/Bren2010/katie/crypto/suites"
+)
+
+func TestPrefixProofJSON(t *testing.T) {
+	cs := suites.KTSha256P256{}
+	tree, _, allEntries := buildRandomTree(t, cs)
+
+	// Search for a mix of VRF outputs that are and are not in the tree, so that
+	// the proof contains more than one type of search result.
+	missing := randomBytes()
+	search := PrefixSearch{
+		Version:    uint64(len(allEntries)),
+		VrfOutputs: [][]byte{allEntries[0][0].VrfOutput, missing[:]},
+	}
+	res, err := tree.Search([]PrefixSearch{search})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof := res[0].Proof
+
+	raw, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed PrefixProof
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, actual := &bytes.Buffer{}, &bytes.Buffer{}
+	if err := proof.Marshal(expected); err != nil {
+		t.Fatal(err)
+	} else if err := parsed.Marshal(actual); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+		t.Fatal("proof changed after round trip through JSON")
+	}
+
+	if err := json.Unmarshal([]byte(`{"results":[{"type":"unknown","depth":1}]}`), &parsed); err == nil {
+		t.Fatal("expected error for unknown result type")
+	}
+}
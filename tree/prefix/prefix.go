@@ -72,7 +72,8 @@ func (t *Tree) Search(searches []PrefixSearch) ([]SearchResult, error) {
 
 // Entry contains a new entry to be added to the tree.
 type Entry struct {
-	VrfOutput, Commitment []byte
+	VrfOutput  []byte `json:"vrf_output,omitempty"`
+	Commitment []byte `json:"commitment,omitempty"`
 }
 
 // Mutate adds a set of new entries to the tree, removes the requested entries,
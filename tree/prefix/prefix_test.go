@@ -193,8 +193,10 @@ func buildRandomTree(t *testing.T, cs suites.CipherSuite) (*Tree, [][]byte, [][]
 	for ver := range uint64(10) {
 		entries := make([]Entry, 0)
 		for range 10 {
-			vrfOutput, commitment := randomBytes(), randomBytes()
-			entries = append(entries, Entry{vrfOutput[:], commitment[:]})
+			vrfOutput, commitment := make([]byte, cs.HashSize()), make([]byte, cs.HashSize())
+			rand.Read(vrfOutput)
+			rand.Read(commitment)
+			entries = append(entries, Entry{vrfOutput, commitment})
 		}
 		root, _, commitments, err := tree.Mutate(ver, entries, nil)
 		if err != nil {
@@ -210,7 +212,16 @@ func buildRandomTree(t *testing.T, cs suites.CipherSuite) (*Tree, [][]byte, [][]
 }
 
 func TestSearchOneVersion(t *testing.T) {
-	cs := suites.KTSha256P256{}
+	testSearchOneVersion(t, suites.KTSha256P256{})
+}
+
+// TestSearchHashSize checks that the tree works with a cipher suite whose hash
+// size isn't 32 bytes.
+func TestSearchHashSize(t *testing.T) {
+	testSearchOneVersion(t, suites.KTSha384P384{})
+}
+
+func testSearchOneVersion(t *testing.T, cs suites.CipherSuite) {
 	tree, roots, allEntries := buildRandomTree(t, cs)
 	ver := uint64(len(roots))
 
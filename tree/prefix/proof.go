@@ -25,6 +25,8 @@ func NewPrefixProof(cs suites.CipherSuite, buf *bytes.Buffer) (*PrefixProof, err
 	numResults, err := buf.ReadByte()
 	if err != nil {
 		return nil, err
+	} else if int(numResults) > buf.Len() {
+		return nil, errors.New("prefix proof results are longer than remaining data")
 	}
 	results := make([]PrefixSearchResult, numResults)
 	for i := range int(numResults) {
@@ -38,6 +40,8 @@ func NewPrefixProof(cs suites.CipherSuite, buf *bytes.Buffer) (*PrefixProof, err
 	var numElements uint16
 	if err := binary.Read(buf, binary.BigEndian, &numElements); err != nil {
 		return nil, err
+	} else if int(numElements) > buf.Len()/cs.HashSize() {
+		return nil, errors.New("prefix proof elements are longer than remaining data")
 	}
 	elements := make([][]byte, numElements)
 	for i := range int(numElements) {
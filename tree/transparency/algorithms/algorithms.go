@@ -2,7 +2,6 @@ package algorithms
 
 import (
 	"errors"
-	"time"
 
 	"github.com/Bren2010/katie/tree/transparency/math"
 	"github.com/Bren2010/katie/tree/transparency/structs"
@@ -130,7 +129,7 @@ func UpdateView(config *structs.PublicConfig, n uint64, m *uint64, provider *Dat
 		}
 	}
 
-	now := uint64(time.Now().UnixMilli())
+	now := config.Now()
 	ts, err := provider.GetTimestamp(n - 1)
 	if err != nil {
 		return err
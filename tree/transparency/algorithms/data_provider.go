@@ -254,6 +254,14 @@ func (dp *DataProvider) Output(n uint64, nP, m *uint64) (*structs.CombinedTreePr
 	return dp.handle.Output(positions, n, nP, m)
 }
 
+// SelectLabel changes which label subsequent binary ladders are for, when a
+// single proof covers several labels. Timestamps and prefix tree roots are
+// shared by all labels, so log entries inspected while searching for one label
+// are not requested again for another.
+func (dp *DataProvider) SelectLabel(i int) error {
+	return dp.handle.SelectLabel(i)
+}
+
 func (dp *DataProvider) StopCondition(x uint64, ver int) bool {
 	return dp.handle.StopCondition(x, ver)
 }
@@ -1,6 +1,7 @@
 package algorithms
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/Bren2010/katie/crypto/suites"
@@ -42,3 +43,30 @@ func TestTimestampsRetained(t *testing.T) {
 		t.Fatalf("unexpected result: %v, %v", ts, err)
 	}
 }
+
+func TestAddRetainedVersion(t *testing.T) {
+	cs := suites.KTSha256P256{}
+	handle := NewReceivedProofHandle(cs, structs.CombinedTreeProof{})
+	vrfOutput, commitment := make([]byte, cs.HashSize()), make([]byte, cs.HashSize())
+	other := bytes.Repeat([]byte{1}, cs.HashSize())
+
+	// A version from the response may be retained already, in which case the
+	// retained commitment fills in the one that wasn't provided.
+	if err := handle.AddVersion(0, vrfOutput, nil); err != nil {
+		t.Fatal(err)
+	} else if err := handle.AddRetainedVersion(0, vrfOutput, commitment); err != nil {
+		t.Fatal(err)
+	} else if entry, _ := handle.GetVersion(0); !bytes.Equal(entry.Commitment, commitment) {
+		t.Fatal("retained commitment was not used")
+	} else if err := handle.AddRetainedVersion(0, vrfOutput, commitment); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := handle.AddRetainedVersion(0, other, nil); err == nil {
+		t.Fatal("expected error for different vrf output")
+	} else if err := handle.AddRetainedVersion(0, vrfOutput, other); err == nil {
+		t.Fatal("expected error for different commitment")
+	} else if err := handle.AddVersion(0, vrfOutput, nil); err == nil {
+		t.Fatal("expected error for duplicate version")
+	}
+}
@@ -71,6 +71,26 @@ type ProofHandle interface {
 
 	// Tracker returns the tracker used for version lookup omission.
 	Tracker() *math.VersionTracker
+
+	// SelectLabel changes which label subsequent binary ladders are for, when
+	// a single proof covers several labels. Labels are identified by their
+	// position in the request.
+	SelectLabel(i int) error
+}
+
+// selectLabel returns an error if `i` is not the position of one of `n`
+// labels.
+func selectLabel(i, n int) error {
+	if i < 0 || i >= n {
+		return errors.New("label selected out of range")
+	}
+	return nil
+}
+
+// receivedLabel is the label-specific state of a ReceivedProofHandle.
+type receivedLabel struct {
+	versions map[uint32]prefix.Entry
+	tracker  math.VersionTracker
 }
 
 // ReceivedProofHandle implements the proofHandle interface over a
@@ -79,25 +99,61 @@ type ReceivedProofHandle struct {
 	cs    suites.CipherSuite
 	inner structs.CombinedTreeProof
 
-	versions map[uint32]prefix.Entry
-	tracker  math.VersionTracker
+	labels []receivedLabel
+	*receivedLabel
 }
 
 func NewReceivedProofHandle(cs suites.CipherSuite, inner structs.CombinedTreeProof) *ReceivedProofHandle {
+	return NewBatchReceivedProofHandle(cs, inner, 1)
+}
+
+// NewBatchReceivedProofHandle returns a ReceivedProofHandle for a
+// CombinedTreeProof that covers `n` labels, where `n` is at least one. The
+// first label is selected initially.
+func NewBatchReceivedProofHandle(cs suites.CipherSuite, inner structs.CombinedTreeProof, n int) *ReceivedProofHandle {
+	labels := make([]receivedLabel, n)
+	for i := range labels {
+		labels[i].versions = make(map[uint32]prefix.Entry)
+	}
 	return &ReceivedProofHandle{
 		cs:    cs,
 		inner: inner,
 
-		versions: make(map[uint32]prefix.Entry),
+		labels:        labels,
+		receivedLabel: &labels[0],
 	}
 }
 
-// AddVersion adds the VRF output and commitment corresponding to a version of a
-// label to the ReceivedProofHandle.
+// AddVersion adds the VRF output and commitment corresponding to a version of
+// the selected label to the ReceivedProofHandle.
 func (rph *ReceivedProofHandle) AddVersion(ver uint32, vrfOutput, commitment []byte) error {
 	return addVersion(rph.cs, rph.versions, ver, vrfOutput, commitment)
 }
 
+// AddRetainedVersion is the same as AddVersion, except that the version may
+// already be known from the proof being evaluated. In that case, the VRF output
+// and commitment must be consistent with what is already known.
+//
+// This is used for versions that the user retained from previous queries, which
+// are often provided again in the binary ladder of the response to a new query
+// for the same label.
+func (rph *ReceivedProofHandle) AddRetainedVersion(ver uint32, vrfOutput, commitment []byte) error {
+	entry, ok := rph.versions[ver]
+	if !ok {
+		return rph.AddVersion(ver, vrfOutput, commitment)
+	} else if !bytes.Equal(entry.VrfOutput, vrfOutput) {
+		return errors.New("retained vrf output does not match provided value")
+	} else if commitment == nil {
+		return nil
+	} else if entry.Commitment == nil {
+		rph.versions[ver] = prefix.Entry{VrfOutput: entry.VrfOutput, Commitment: commitment}
+		return nil
+	} else if !bytes.Equal(entry.Commitment, commitment) {
+		return errors.New("retained commitment does not match provided value")
+	}
+	return nil
+}
+
 func (rph *ReceivedProofHandle) GetVersion(ver uint32) (prefix.Entry, bool) {
 	entry, ok := rph.versions[ver]
 	return entry, ok
@@ -256,21 +312,38 @@ func (rph *ReceivedProofHandle) StopCondition(_ uint64, _ int) bool {
 
 func (rph *ReceivedProofHandle) Tracker() *math.VersionTracker { return &rph.tracker }
 
+func (rph *ReceivedProofHandle) SelectLabel(i int) error {
+	if err := selectLabel(i, len(rph.labels)); err != nil {
+		return err
+	}
+	rph.receivedLabel = &rph.labels[i]
+	return nil
+}
+
 type requiredProof struct {
-	pos  uint64
-	vers []uint32
+	label int
+	pos   uint64
+	vers  []uint32
+}
+
+// producedLabel is the label-specific state of a ProducedProofHandle.
+type producedLabel struct {
+	index    []uint64
+	versions map[uint32]prefix.Entry
+	tracker  math.VersionTracker
 }
 
 // ProducedProofHandle implements the proofHandle interface such that it can
 // output the corresponding CombinedTreeProof.
 type ProducedProofHandle struct {
-	cs    suites.CipherSuite
-	tx    db.TransparencyStore
-	index []uint64
+	cs suites.CipherSuite
+	tx db.TransparencyStore
+
+	labels   []producedLabel
+	selected int
+	*producedLabel
 
 	logEntries map[uint64]structs.LogEntry
-	versions   map[uint32]prefix.Entry
-	tracker    math.VersionTracker
 
 	timestamps []uint64
 	proofs     []requiredProof
@@ -282,22 +355,43 @@ func NewProducedProofHandle(
 	tx db.TransparencyStore,
 	index []uint64,
 ) *ProducedProofHandle {
+	return NewBatchProducedProofHandle(cs, tx, [][]uint64{index})
+}
+
+// NewBatchProducedProofHandle returns a ProducedProofHandle for a
+// CombinedTreeProof that covers several labels, where `indices` contains the
+// index of each label. At least one label must be given, and the first label is
+// selected initially.
+func NewBatchProducedProofHandle(
+	cs suites.CipherSuite,
+	tx db.TransparencyStore,
+	indices [][]uint64,
+) *ProducedProofHandle {
+	labels := make([]producedLabel, len(indices))
+	for i, index := range indices {
+		labels[i] = producedLabel{index: index, versions: make(map[uint32]prefix.Entry)}
+	}
 	return &ProducedProofHandle{
-		cs:    cs,
-		tx:    tx,
-		index: index,
+		cs: cs,
+		tx: tx,
+
+		labels:        labels,
+		producedLabel: &labels[0],
 
 		logEntries: make(map[uint64]structs.LogEntry),
-		versions:   make(map[uint32]prefix.Entry),
 	}
 }
 
-// RequiredVersions returns the set of versions for which AddVersion must be
-// called with the corresponding VRF output for Output to return successfully.
+// RequiredVersions returns the set of versions of the selected label for which
+// AddVersion must be called with the corresponding VRF output for Output to
+// return successfully.
 func (pph *ProducedProofHandle) RequiredVersions() map[uint32]struct{} {
 	accumulator := make(map[uint32]struct{})
 
 	for _, proof := range pph.proofs {
+		if proof.label != pph.selected {
+			continue
+		}
 		for _, ver := range proof.vers {
 			accumulator[ver] = struct{}{}
 		}
@@ -306,14 +400,14 @@ func (pph *ProducedProofHandle) RequiredVersions() map[uint32]struct{} {
 	return accumulator
 }
 
-// AddVersion adds the VRF output corresponding to a version of a label to the
-// ProducedProofHandle.
+// AddVersion adds the VRF output corresponding to a version of the selected
+// label to the ProducedProofHandle.
 func (pph *ProducedProofHandle) AddVersion(ver uint32, vrfOutput []byte) error {
 	return addVersion(pph.cs, pph.versions, ver, vrfOutput, nil)
 }
 
 // GetCommitment returns the commitment associated with the requested version of
-// the label, or nil if it is not known.
+// the selected label, or nil if it is not known.
 func (pph *ProducedProofHandle) GetCommitment(ver uint32) []byte {
 	return pph.versions[ver].Commitment
 }
@@ -339,7 +433,10 @@ func (pph *ProducedProofHandle) getLogEntry(x uint64) (*structs.LogEntry, error)
 		return nil, errors.New("requested log entry not found")
 	}
 
-	buf := bytes.NewBuffer(raw)
+	buf, err := structs.NewDecoder(raw, structs.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
 	entry, err := structs.NewLogEntry(pph.cs, buf)
 	if err != nil {
 		return nil, err
@@ -384,7 +481,7 @@ func (pph *ProducedProofHandle) GetSearchBinaryLadder(x uint64, ver uint32, omit
 		res = 1
 	}
 
-	pph.proofs = append(pph.proofs, requiredProof{pos: x, vers: ladder})
+	pph.proofs = append(pph.proofs, requiredProof{label: pph.selected, pos: x, vers: ladder})
 	pph.tracker.AddLadder(x, omit, greatest, ladder)
 	return entry.PrefixTree, res, nil
 }
@@ -395,7 +492,7 @@ func (pph *ProducedProofHandle) GetMonitoringBinaryLadder(x uint64, ver uint32)
 		return nil, err
 	}
 	ladder := math.MonitoringBinaryLadder(ver)
-	pph.proofs = append(pph.proofs, requiredProof{pos: x, vers: ladder})
+	pph.proofs = append(pph.proofs, requiredProof{label: pph.selected, pos: x, vers: ladder})
 	return entry.PrefixTree, nil
 }
 
@@ -404,7 +501,7 @@ func (pph *ProducedProofHandle) GetInclusionProof(x uint64, vers []uint32) ([]by
 	if err != nil {
 		return nil, err
 	}
-	pph.proofs = append(pph.proofs, requiredProof{pos: x, vers: slices.Clone(vers)})
+	pph.proofs = append(pph.proofs, requiredProof{label: pph.selected, pos: x, vers: slices.Clone(vers)})
 	return entry.PrefixTree, nil
 }
 
@@ -429,9 +526,10 @@ func (pph *ProducedProofHandle) Output(leaves []uint64, n uint64, nP, m *uint64)
 	// Construct the list of prefix tree searches to execute.
 	searches := make([]prefix.PrefixSearch, len(pph.proofs))
 	for i, proof := range pph.proofs {
+		versions := pph.labels[proof.label].versions
 		vrfOutputs := make([][]byte, len(proof.vers))
 		for j, ver := range proof.vers {
-			entry, ok := pph.versions[ver]
+			entry, ok := versions[ver]
 			if !ok {
 				return nil, errors.New("required version not known")
 			}
@@ -456,10 +554,11 @@ func (pph *ProducedProofHandle) Output(leaves []uint64, n uint64, nP, m *uint64)
 			if commitment == nil {
 				continue
 			}
+			versions := pph.labels[pph.proofs[i].label].versions
 			ver := pph.proofs[i].vers[j]
-			entry := pph.versions[ver]
+			entry := versions[ver]
 			if entry.Commitment == nil {
-				pph.versions[ver] = prefix.Entry{VrfOutput: entry.VrfOutput, Commitment: commitment}
+				versions[ver] = prefix.Entry{VrfOutput: entry.VrfOutput, Commitment: commitment}
 			} else if !bytes.Equal(commitment, entry.Commitment) {
 				return nil, errors.New("different values for same commitment found")
 			}
@@ -485,3 +584,12 @@ func (pph *ProducedProofHandle) StopCondition(x uint64, ver int) bool {
 }
 
 func (pph *ProducedProofHandle) Tracker() *math.VersionTracker { return &pph.tracker }
+
+func (pph *ProducedProofHandle) SelectLabel(i int) error {
+	if err := selectLabel(i, len(pph.labels)); err != nil {
+		return err
+	}
+	pph.selected = i
+	pph.producedLabel = &pph.labels[i]
+	return nil
+}
@@ -164,3 +164,38 @@ func FixedVersionSearch(config *structs.PublicConfig, ver uint32, n uint64, prov
 		return 0, ErrLabelExpired
 	}
 }
+
+// VersionPosition proves that version `ver` of the label was added in the log
+// entry at position `x`: that `ver` exists in log entry `x`, and that the
+// greatest version of the label in log entry `x-1`, if there is one, is less
+// than `ver`. The size of the tree is `n`.
+//
+// Like a search, it returns ErrLabelExpired if the log entry at position `x`
+// is expired, since the prefix trees of expired log entries are not searched.
+func VersionPosition(config *structs.PublicConfig, ver uint32, x, n uint64, provider *DataProvider) error {
+	if x >= n {
+		return errors.New("version position is beyond the end of the tree")
+	}
+	rightmost, err := provider.GetTimestamp(n - 1)
+	if err != nil {
+		return err
+	}
+	timestamp, err := provider.GetTimestamp(x)
+	if err != nil {
+		return err
+	} else if config.IsExpired(timestamp, rightmost) {
+		return ErrLabelExpired
+	}
+	if err := provider.GetInclusionProof(x, []uint32{ver}); err != nil {
+		return err
+	} else if x == 0 {
+		return nil
+	}
+	res, err := provider.GetSearchBinaryLadder(x-1, ver, false)
+	if err != nil {
+		return err
+	} else if res != -1 {
+		return errors.New("version exists before claimed position")
+	}
+	return nil
+}
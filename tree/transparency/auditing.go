@@ -0,0 +1,170 @@
+package transparency
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Bren2010/katie/tree/log"
+	"github.com/Bren2010/katie/tree/transparency/algorithms"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+)
+
+// ErrAuditorBehind is returned by Mutate when the Third-Party Auditor has
+// fallen so far behind that clients would reject a new tree head.
+var ErrAuditorBehind = errors.New("refusing to issue tree head: auditor is too far behind")
+
+// SetAuditorTreeHead verifies a new tree head from the Third-Party Auditor and
+// stores it, after which it is provided to clients. The stored AuditorUpdate
+// structures that are covered by the new tree head are deleted.
+func (t *Tree) SetAuditorTreeHead(head *structs.AuditorTreeHead) error {
+	if t.config.Mode != structs.ThirdPartyAuditing {
+		return errors.New("transparency log is not configured with third party auditor")
+	} else if t.treeHead == nil {
+		return errors.New("can not operate on an empty tree")
+	}
+	n := t.treeHead.TreeSize
+
+	// Verify that the new tree head is consistent with the previous one and
+	// with the current tree.
+	if head.TreeSize == 0 {
+		return errors.New("auditor tree size is zero")
+	} else if head.TreeSize > n {
+		return errors.New("auditor tree size is greater than transparency log tree size")
+	} else if head.TreeSize <= t.config.AuditorStartPos {
+		return errors.New("auditor tree size does not cover auditor start position")
+	}
+	start := t.config.AuditorStartPos
+	if prev := t.auditorHead; prev != nil {
+		if head.TreeSize < prev.TreeSize {
+			return errors.New("auditor tree size is less than previous auditor tree size")
+		} else if head.Timestamp < prev.Timestamp {
+			return errors.New("auditor timestamp is less than previous auditor timestamp")
+		}
+		start = prev.TreeSize
+	}
+	handle := algorithms.NewProducedProofHandle(t.config.Suite, t.tx, nil)
+	provider := algorithms.NewDataProvider(t.config.Suite, handle)
+	rightmost, err := provider.GetTimestamp(n - 1)
+	if err != nil {
+		return err
+	} else if head.Timestamp > rightmost {
+		return errors.New("auditor timestamp is greater than rightmost log entry timestamp")
+	}
+
+	// Verify the auditor's signature over the root of the tree at the size
+	// that it audited.
+	fullSubtrees, err := log.NewTree(t.config.Suite, t.tx.LogStore()).GetFullSubtrees(head.TreeSize)
+	if err != nil {
+		return err
+	}
+	root, err := log.Root(t.config.Suite, head.TreeSize, fullSubtrees)
+	if err != nil {
+		return err
+	}
+	tbs, err := structs.Marshal(&structs.AuditorTreeHeadTBS{
+		Config:    t.config.Public(),
+		Timestamp: head.Timestamp,
+		TreeSize:  head.TreeSize,
+		Root:      root,
+	})
+	if err != nil {
+		return err
+	} else if !t.config.AuditorPublicKey.Verify(tbs, head.Signature) {
+		return errors.New("failed to verify auditor signature")
+	}
+
+	// Store the new tree head and delete the updates that the auditor has
+	// acknowledged.
+	raw, err := structs.Marshal(head)
+	if err != nil {
+		return err
+	} else if err := t.tx.PutAuditorTreeHead(raw); err != nil {
+		return err
+	}
+	for pos := start; pos < head.TreeSize; pos++ {
+		if err := t.tx.DeleteAuditorUpdate(pos); err != nil {
+			return err
+		}
+	}
+	if err := t.tx.Commit(); err != nil {
+		return err
+	}
+	t.auditorHead = head
+
+	return nil
+}
+
+// checkAuditorLag returns ErrAuditorBehind if adding a log entry with the
+// given timestamp to a tree of size `n` would put the rightmost log entry's
+// timestamp more than MaxAuditorLag ahead of the auditor's timestamp, while the
+// auditor has not yet processed every log entry. Once the auditor has caught
+// up, the tree is always allowed to grow, since the auditor can't sign a log
+// entry that doesn't exist yet.
+//
+// Before the auditor has provided its first tree head, the lag is measured
+// from the timestamp of the log entry at AuditorStartPos, which is the first
+// log entry that the auditor is responsible for.
+func (t *Tree) checkAuditorLag(provider *algorithms.DataProvider, n, timestamp uint64) error {
+	if t.config.Mode != structs.ThirdPartyAuditing {
+		return nil
+	}
+	var audited, auditedTimestamp uint64
+	if t.auditorHead != nil {
+		audited, auditedTimestamp = t.auditorHead.TreeSize, t.auditorHead.Timestamp
+	} else {
+		audited = t.config.AuditorStartPos
+		if audited < n {
+			var err error
+			auditedTimestamp, err = provider.GetTimestamp(audited)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if audited >= n {
+		return nil
+	} else if timestamp-auditedTimestamp > t.config.MaxAuditorLag {
+		return ErrAuditorBehind
+	}
+	return nil
+}
+
+// AuditorUpdates returns the stored AuditorUpdate structures for the log
+// entries in the range [start, end). An error is returned if any update in the
+// range is not stored, either because it has already been pruned or because
+// the log entry doesn't exist.
+func (t *Tree) AuditorUpdates(start, end uint64) ([]*structs.AuditorUpdate, error) {
+	if t.config.Mode != structs.ThirdPartyAuditing {
+		return nil, errors.New("transparency log is not configured with third party auditor")
+	} else if start > end {
+		return nil, errors.New("invalid range of auditor updates requested")
+	}
+
+	keys := make([]uint64, 0, end-start)
+	for pos := start; pos < end; pos++ {
+		keys = append(keys, pos)
+	}
+	raw, err := t.tx.BatchGetAuditorUpdate(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*structs.AuditorUpdate, len(keys))
+	for i, pos := range keys {
+		data, ok := raw[pos]
+		if !ok {
+			return nil, fmt.Errorf("auditor update not found: %d", pos)
+		}
+		buf, err := structs.NewDecoder(data, structs.DefaultLimits)
+		if err != nil {
+			return nil, err
+		}
+		out[i], err = structs.NewAuditorUpdate(t.config.Suite, buf)
+		if err != nil {
+			return nil, err
+		} else if buf.Len() != 0 {
+			return nil, errors.New("unexpected data appended to auditor update")
+		}
+	}
+	return out, nil
+}
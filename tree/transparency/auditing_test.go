@@ -0,0 +1,202 @@
+package transparency
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Bren2010/katie/db/memory"
+	"github.com/Bren2010/katie/tree/transparency/auditor"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/test"
+)
+
+func TestAuditorUpdates(t *testing.T) {
+	config, _ := test.ConfigWithAuditor(t)
+	store := memory.NewTransparencyStore()
+	tree, err := NewTree(config, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := make([]*structs.AuditorUpdate, 2)
+	for i := range expected {
+		expected[i], err = tree.Mutate([]LabelValue{
+			{Label: []byte{byte(i)}, Value: structs.UpdateValue{Value: []byte("value")}},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	updates, err := tree.AuditorUpdates(0, 2)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(updates) != len(expected) {
+		t.Fatal("unexpected number of auditor updates returned")
+	}
+	for i, update := range updates {
+		got, err := structs.Marshal(update)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := structs.Marshal(expected[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatal("stored auditor update does not match the one returned by Mutate")
+		}
+	}
+	if _, err := tree.AuditorUpdates(1, 3); err == nil {
+		t.Fatal("expected error for auditor update that doesn't exist")
+	}
+}
+
+// newAuditedTree returns a tree configured with a Third-Party Auditor, and a
+// function that adds a new log entry to the tree and has it processed by the
+// auditor, returning the auditor's new tree head.
+func newAuditedTree(t *testing.T) (*memory.TransparencyStore, *Tree, func() *structs.AuditorTreeHead) {
+	config, auditorKey := test.ConfigWithAuditor(t)
+	store := memory.NewTransparencyStore()
+	tree, err := NewTree(config, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := auditor.NewAuditor(config.Public(), auditorKey, memory.NewAuditorStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := 0
+	return store, tree, func() *structs.AuditorTreeHead {
+		t.Helper()
+		update, err := tree.Mutate([]LabelValue{
+			{Label: []byte{byte(i)}, Value: structs.UpdateValue{Value: []byte("value")}},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		i++
+		if err := a.Process(update); err != nil {
+			t.Fatal(err)
+		}
+		head, err := a.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &structs.AuditorTreeHead{
+			Timestamp: head.Timestamp,
+			TreeSize:  head.TreeSize,
+			Signature: head.Signature,
+		}
+	}
+}
+
+func TestSetAuditorTreeHead(t *testing.T) {
+	store, tree, next := newAuditedTree(t)
+	first, second := next(), next()
+
+	// Tree heads with an invalid signature are rejected.
+	invalid := *second
+	invalid.Timestamp--
+	if err := tree.SetAuditorTreeHead(&invalid); err == nil {
+		t.Fatal("expected error for invalid signature")
+	}
+
+	// Updates are deleted once the auditor has acknowledged them.
+	if err := tree.SetAuditorTreeHead(second); err != nil {
+		t.Fatal(err)
+	} else if len(store.AuditorUpdates) != 0 {
+		t.Fatal("auditor updates not deleted")
+	} else if tree.AuditorTreeHead() != second {
+		t.Fatal("unexpected auditor tree head")
+	}
+
+	// Tree heads must not go backwards.
+	if err := tree.SetAuditorTreeHead(first); err == nil {
+		t.Fatal("expected error for old auditor tree head")
+	}
+
+	// The new tree head is persisted.
+	reloaded, err := NewTree(tree.config, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(reloaded.AuditorTreeHead(), second) {
+		t.Fatal("auditor tree head not persisted")
+	}
+}
+
+func TestAuditorLag(t *testing.T) {
+	clock := test.NewClock()
+	_, tree, next := newAuditedTree(t)
+	tree.config.Clock = clock
+
+	// The tree can grow while no auditor tree head has been received.
+	first := next()
+	head := next()
+	if err := tree.SetAuditorTreeHead(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the auditor has fallen too far behind, new tree heads are refused.
+	clock.Advance(2 * time.Minute)
+	if _, err := tree.Mutate(nil, nil); err != ErrAuditorBehind {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The tree can grow again once the auditor has caught up.
+	if err := tree.SetAuditorTreeHead(head); err != nil {
+		t.Fatal(err)
+	}
+	next()
+}
+
+func TestAuditorLagWithoutTreeHead(t *testing.T) {
+	clock := test.NewClock()
+	config, _ := test.ConfigWithAuditor(t)
+	config.Clock = clock
+	config.AuditorStartPos = 2
+	store := memory.NewTransparencyStore()
+	tree, err := NewTree(config, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := 0
+	mutate := func() error {
+		i++
+		_, err := tree.Mutate([]LabelValue{
+			{Label: []byte{byte(i)}, Value: structs.UpdateValue{Value: []byte("value")}},
+		}, nil)
+		return err
+	}
+
+	// Log entries before the auditor's start position are not subject to the
+	// lag, and are not stored for the auditor.
+	for range 2 {
+		clock.Advance(2 * time.Minute)
+		if err := mutate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(2 * time.Minute)
+	if err := mutate(); err != nil {
+		t.Fatal(err)
+	} else if len(store.AuditorUpdates) != 1 {
+		t.Fatalf("unexpected number of stored auditor updates: %d", len(store.AuditorUpdates))
+	} else if _, err := tree.AuditorUpdates(2, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// The lag is measured from the log entry at the start position until the
+	// auditor provides a tree head.
+	clock.Advance(30 * time.Second)
+	if err := mutate(); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	if err := mutate(); err != ErrAuditorBehind {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -44,7 +44,10 @@ func NewAuditor(
 	}
 	var state *AuditorState
 	if raw != nil {
-		buf := bytes.NewBuffer(raw)
+		buf, err := structs.NewDecoder(raw, structs.DefaultLimits)
+		if err != nil {
+			return nil, err
+		}
 		state, err = NewAuditorState(config.Suite, buf)
 		if err != nil {
 			return nil, err
@@ -62,6 +65,14 @@ func NewAuditor(
 	}, nil
 }
 
+// treeSize returns the number of log entries that have been processed.
+func (a *Auditor) treeSize() uint64 {
+	if a.state == nil {
+		return 0
+	}
+	return a.state.TreeHead.TreeSize
+}
+
 func (a *Auditor) previousRightmost(added uint64) (*uint64, *algorithms.DataProvider, error) {
 	// Build the set of relevant log entry timestamps (= the frontier timestamps
 	// we've retained + the new rightmost log entry timestamp).
@@ -1,8 +1,12 @@
 package auditor
 
 import (
+	"bytes"
 	"crypto/rand"
+	"encoding/json"
 	"reflect"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/Bren2010/katie/db"
@@ -139,3 +143,52 @@ func TestAuditorPersistent(t *testing.T) {
 		t.Fatal("loaded state is different than persisted state")
 	}
 }
+
+func TestAuditorStateJSON(t *testing.T) {
+	config, _, _, auditor := makeAuditor(t)
+
+	raw, err := json.Marshal(auditor.state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := NewAuditorStateFromJSON(config.Suite, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := auditor.state.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := parsed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(expected, actual) {
+		t.Fatal("state changed after round trip through JSON")
+	}
+
+	// States that don't match their binary encoding are rejected: a prefix
+	// tree root of the wrong length, a full subtree that the tree size doesn't
+	// have room for, an unknown field, and trailing data.
+	shortRoot := *auditor.state
+	shortRoot.PrefixTree = shortRoot.PrefixTree[1:]
+	extraSubtree := *auditor.state
+	extraSubtree.FullSubtrees = append(slices.Clone(extraSubtree.FullSubtrees), make([]byte, 32))
+	var invalid [][]byte
+	for _, state := range []*AuditorState{&shortRoot, &extraSubtree} {
+		raw, err := json.Marshal(state)
+		if err != nil {
+			t.Fatal(err)
+		}
+		invalid = append(invalid, raw)
+	}
+	invalid = append(invalid,
+		[]byte(strings.Replace(string(raw), `"tree_head"`, `"tree_heads"`, 1)),
+		append(slices.Clone(raw), raw...),
+	)
+	for _, raw := range invalid {
+		if _, err := NewAuditorStateFromJSON(config.Suite, raw); err == nil {
+			t.Fatalf("expected error decoding invalid auditor state: %s", raw)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package auditor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/wire"
+)
+
+var (
+	// ErrUpdateRejected is returned when an AuditorUpdate fails verification,
+	// which indicates that the Transparency Log has misbehaved.
+	ErrUpdateRejected = errors.New("auditor update rejected")
+
+	// ErrUnexpectedPosition is returned when an AuditorUpdate is provided out
+	// of order.
+	ErrUnexpectedPosition = errors.New("auditor update provided at unexpected position")
+)
+
+// Service wraps an Auditor to implement wire.AuditorInterface. It is safe for
+// concurrent use.
+type Service struct {
+	mu      sync.Mutex
+	auditor *Auditor
+}
+
+var _ wire.AuditorInterface = &Service{}
+
+// NewService returns a new Service that processes updates with `auditor`.
+func NewService(auditor *Auditor) *Service {
+	return &Service{auditor: auditor}
+}
+
+// TreeSize returns the number of log entries that have been processed.
+func (s *Service) TreeSize() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.auditor.treeSize()
+}
+
+// Audit processes and commits the AuditorUpdate for the log entry at position
+// `pos`. If the update fails verification, an error wrapping
+// ErrUpdateRejected is returned. If `pos` is not the next expected position,
+// an error wrapping ErrUnexpectedPosition is returned.
+func (s *Service) Audit(ctx context.Context, pos uint64, update *structs.AuditorUpdate) (*structs.AuditorTreeHead, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := s.auditor.treeSize()
+	if pos+1 == n {
+		// The update was already processed, but the operator may not have
+		// received the response. Commit is a no-op if the tree head has
+		// already been signed.
+		return s.auditor.Commit()
+	} else if pos != n {
+		return nil, fmt.Errorf("%w: expected %d, got %d", ErrUnexpectedPosition, n, pos)
+	}
+
+	if err := s.auditor.Process(update); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUpdateRejected, err)
+	}
+	return s.auditor.Commit()
+}
@@ -0,0 +1,77 @@
+package auditor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Bren2010/katie/db/memory"
+	"github.com/Bren2010/katie/tree/transparency"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/test"
+)
+
+func TestService(t *testing.T) {
+	ctx := context.Background()
+	config, auditorKey := test.ConfigWithAuditor(t)
+
+	tree, err := transparency.NewTree(config, memory.NewTransparencyStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	updates := make([]*structs.AuditorUpdate, 3)
+	for i := range updates {
+		updates[i], err = tree.Mutate([]transparency.LabelValue{{
+			Label: []byte{byte(i)},
+			Value: structs.UpdateValue{Value: []byte("value")},
+		}}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	auditor, err := NewAuditor(config.Public(), auditorKey, memory.NewAuditorStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewService(auditor)
+
+	head, err := s.Audit(ctx, 0, updates[0])
+	if err != nil {
+		t.Fatal(err)
+	} else if head.TreeSize != 1 || head.Signature == nil {
+		t.Fatalf("unexpected auditor tree head: %+v", head)
+	}
+
+	// Re-sending the most recent update returns the same tree head.
+	head2, err := s.Audit(ctx, 0, updates[0])
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(head, head2) {
+		t.Fatal("unexpected auditor tree head returned for repeated update")
+	}
+
+	// Updates must be provided in order.
+	if _, err := s.Audit(ctx, 2, updates[2]); !errors.Is(err, ErrUnexpectedPosition) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Invalid updates are rejected without changing the auditor's state.
+	invalid := *updates[1]
+	invalid.Timestamp = 0
+	if _, err := s.Audit(ctx, 1, &invalid); !errors.Is(err, ErrUpdateRejected) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if s.TreeSize() != 1 {
+		t.Fatalf("unexpected tree size: %v", s.TreeSize())
+	}
+
+	for i := 1; i < len(updates); i++ {
+		head, err := s.Audit(ctx, uint64(i), updates[i])
+		if err != nil {
+			t.Fatal(err)
+		} else if head.TreeSize != uint64(i+1) {
+			t.Fatalf("unexpected tree size: %v", head.TreeSize)
+		}
+	}
+}
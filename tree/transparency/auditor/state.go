@@ -3,6 +3,7 @@ package auditor
 import (
 	"bytes"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"io"
 	"math/bits"
@@ -18,21 +19,21 @@ import (
 // audited transparency log's prefix tree with the position in the log where it
 // was inserted.
 type InsertedVrfOutput struct {
-	Pos       uint64
-	VrfOutput []byte
+	Pos       uint64 `json:"pos"`
+	VrfOutput []byte `json:"vrf_output,omitempty"`
 }
 
 // AuditorState is the state of an Auditor that's persisted to a database.
 type AuditorState struct {
-	TreeHead     structs.AuditorTreeHead // Last tree head issued by auditor.
-	FullSubtrees [][]byte                // Full subtrees of the log tree.
-	Timestamps   []uint64                // Timestamps of the log entries along the frontier.
-	PrefixTree   []byte                  // Prefix tree root hash of the rightmost log entry.
+	TreeHead     structs.AuditorTreeHead `json:"tree_head"`               // Last tree head issued by auditor.
+	FullSubtrees [][]byte                `json:"full_subtrees,omitempty"` // Full subtrees of the log tree.
+	Timestamps   []uint64                `json:"timestamps,omitempty"`    // Timestamps of the log entries along the frontier.
+	PrefixTree   []byte                  `json:"prefix_tree,omitempty"`   // Prefix tree root hash of the rightmost log entry.
 
-	Inserted []InsertedVrfOutput // List of recently-inserted VRF outputs.
+	Inserted []InsertedVrfOutput `json:"inserted,omitempty"` // List of recently-inserted VRF outputs.
 }
 
-func NewAuditorState(cs suites.CipherSuite, buf *bytes.Buffer) (*AuditorState, error) {
+func NewAuditorState(cs suites.CipherSuite, buf *structs.Decoder) (*AuditorState, error) {
 	treeHead, err := structs.NewAuditorTreeHead(buf)
 	if err != nil {
 		return nil, err
@@ -114,6 +115,47 @@ func (as *AuditorState) Marshal() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// NewAuditorStateFromJSON decodes the JSON encoding of an AuditorState. Like
+// structs.UnmarshalJSON, it returns an error if the state can not be converted
+// to its binary encoding and back again without changing.
+func NewAuditorStateFromJSON(cs suites.CipherSuite, raw []byte) (*AuditorState, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	var parsed AuditorState
+	if err := dec.Decode(&parsed); err != nil {
+		return nil, err
+	} else if dec.More() {
+		return nil, errors.New("unexpected data appended to auditor state")
+	}
+	encoded, err := parsed.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	buf, err := structs.NewDecoder(encoded, structs.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
+	out, err := NewAuditorState(cs, buf)
+	if err != nil {
+		return nil, err
+	} else if buf.Len() != 0 {
+		return nil, errors.New("unexpected data appended to auditor state")
+	}
+
+	expected, err := json.Marshal(&parsed)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := json.Marshal(out)
+	if err != nil {
+		return nil, err
+	} else if !bytes.Equal(expected, actual) {
+		return nil, errors.New("auditor state does not match its binary encoding")
+	}
+	return out, nil
+}
+
 // addedSince returns true if `vrfOutput` was added to the prefix tree after the
 // log entry `x` was published.
 func (as *AuditorState) addedSince(x uint64, vrfOutput []byte) bool {
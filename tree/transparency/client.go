@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/Bren2010/katie/db"
+	"github.com/Bren2010/katie/tree/log"
 	"github.com/Bren2010/katie/tree/transparency/algorithms"
 	"github.com/Bren2010/katie/tree/transparency/math"
 	"github.com/Bren2010/katie/tree/transparency/structs"
@@ -53,7 +54,10 @@ func (c *Client) getState() (*structs.ClientState, error) {
 		return nil, nil
 	}
 
-	buf := bytes.NewBuffer(raw)
+	buf, err := structs.NewDecoder(raw, structs.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
 	state, err := structs.NewClientState(c.config, buf)
 	if err != nil {
 		return nil, err
@@ -144,60 +148,118 @@ func (c *Client) FixedVersionSearch(label []byte, ver uint32) (
 	return req, c.search(state, req), nil
 }
 
+// BatchSearch returns a BatchSearchRequest for the given entries and a
+// function to verify the corresponding BatchSearchResponse. Entries without a
+// version are searched for the greatest version of the label.
+func (c *Client) BatchSearch(entries []structs.BatchSearchEntry) (
+	*structs.BatchSearchRequest,
+	VerifyFunc[*structs.BatchSearchResponse],
+	error,
+) {
+	if len(entries) == 0 {
+		return nil, nil, errors.New("no labels provided for batch search")
+	}
+	state, err := c.getState()
+	if err != nil {
+		return nil, nil, err
+	}
+	req := &structs.BatchSearchRequest{Last: getLast(state), Entries: entries}
+	return req, c.batchSearch(state, req), nil
+}
+
 func (c *Client) search(
 	state *structs.ClientState,
 	req *structs.SearchRequest,
 ) VerifyFunc[*structs.SearchResponse] {
+	// A search for a single label is verified as a batch of one, since the
+	// proofs are the same.
+	verify := c.batchSearch(state, &structs.BatchSearchRequest{
+		Last:    req.Last,
+		Entries: []structs.BatchSearchEntry{{Label: req.Label, Version: req.Version}},
+	})
 	return func(res *structs.SearchResponse) error {
-		v, err := newVerifier(c.config, state, req.Last, res.FullTreeHead, res.Search)
-		if err != nil {
-			return err
-		}
+		return verify(&structs.BatchSearchResponse{
+			FullTreeHead: res.FullTreeHead,
+			Results: []structs.BatchSearchResult{{
+				Version:      res.Version,
+				Opening:      res.Opening,
+				Value:        res.Value,
+				BinaryLadder: res.BinaryLadder,
+			}},
+			Search: res.Search,
+		})
+	}
+}
 
-		// Determine the target version for the search.
-		var target uint32
-		if req.Version != nil {
-			target = *req.Version
-		} else if res.Version != nil {
-			target = *res.Version
-		} else {
-			// This will never happen if the SearchResponse was properly
-			// decoded, but it might not have been.
-			return errors.New("unexpected error occurred")
+func (c *Client) batchSearch(
+	state *structs.ClientState,
+	req *structs.BatchSearchRequest,
+) VerifyFunc[*structs.BatchSearchResponse] {
+	return func(res *structs.BatchSearchResponse) error {
+		if len(res.Results) != len(req.Entries) {
+			return errors.New("unexpected number of search results provided")
 		}
-
-		// If a Third-Party Manager is being used, verify `value`.
-		err = verifyUpdateValue(c.config, req.Label, target, res.Value)
+		v, err := newBatchVerifier(c.config, state, req.Last, res.FullTreeHead, res.Search, len(req.Entries))
 		if err != nil {
 			return err
 		}
 
-		// Verify that the expected number of entries is present in
-		// `binary_ladder` and compute the VRF output for each version.
-		ladder := math.SearchBinaryLadder(target, target, nil, nil)
-		commitment, err := computeCommitment(c.config, res.Opening, req.Label, target, res.Value)
-		if err != nil {
-			return err
-		}
-		err = v.processLadder(req.Label, res.BinaryLadder, ladder, map[uint32][]byte{
-			target: commitment,
-		})
-		if err != nil {
-			return err
+		targets := make([]uint32, len(req.Entries))
+		for i, entry := range req.Entries {
+			result := res.Results[i]
+			if err := v.selectLabel(i); err != nil {
+				return err
+			}
+
+			// Determine the target version for the search.
+			if entry.Version != nil {
+				targets[i] = *entry.Version
+			} else if result.Version != nil {
+				targets[i] = *result.Version
+			} else {
+				// This will never happen if the BatchSearchResponse was
+				// properly decoded, but it might not have been.
+				return errors.New("unexpected error occurred")
+			}
+
+			// If a Third-Party Manager is being used, verify `value`.
+			err = verifyUpdateValue(c.config, entry.Label, targets[i], result.Value)
+			if err != nil {
+				return err
+			}
+
+			// Verify that the expected number of entries is present in
+			// `binary_ladder` and compute the VRF output for each version.
+			ladder := math.SearchBinaryLadder(targets[i], targets[i], nil, nil)
+			commitment, err := computeCommitment(c.config, result.Opening, entry.Label, targets[i], result.Value)
+			if err != nil {
+				return err
+			}
+			err = v.processLadder(entry.Label, result.BinaryLadder, ladder, map[uint32][]byte{
+				targets[i]: commitment,
+			})
+			if err != nil {
+				return err
+			}
 		}
 
 		// Verify the proof.
 		if err := v.updateView(); err != nil {
 			return err
 		}
-		var terminal uint64
-		if req.Version == nil {
-			terminal, err = v.greatestVersionSearch(target)
-		} else {
-			terminal, err = v.fixedVersionSearch(target)
-		}
-		if err != nil {
-			return err
+		terminals := make([]uint64, len(req.Entries))
+		for i, entry := range req.Entries {
+			if err := v.selectLabel(i); err != nil {
+				return err
+			}
+			if entry.Version == nil {
+				terminals[i], err = v.greatestVersionSearch(targets[i])
+			} else {
+				terminals[i], err = v.fixedVersionSearch(targets[i])
+			}
+			if err != nil {
+				return err
+			}
 		}
 		updated, err := v.finish()
 		if err != nil {
@@ -211,24 +273,122 @@ func (c *Client) search(
 		rightmostDLE, err := v.rightmostDistinguished()
 		if err != nil {
 			return err
-		} else if rightmostDLE != nil && terminal <= *rightmostDLE {
+		}
+
+		// Updating label-specific state is necessary for any label where the
+		// search terminated to the right of the rightmost distinguished log
+		// entry.
+		stored := false
+		for i, entry := range req.Entries {
+			if rightmostDLE != nil && terminals[i] <= *rightmostDLE {
+				continue
+			} else if err := v.selectLabel(i); err != nil {
+				return err
+			}
+			labelState, err := c.getLabelState(entry.Label)
+			if err != nil {
+				return err
+			} else if err := v.addLabelState(labelState); err != nil {
+				return err
+			}
+			terminal, err := updateContactState(labelState, terminals[i], v.n, targets[i])
+			if err != nil {
+				return err
+			} else if err := updateRetainedVersions(labelState, v.handle); err != nil {
+				return err
+			} else if err := c.putLabelState(updated, entry.Label, labelState, terminal); err != nil {
+				return err
+			}
+			stored = true
+		}
+		if !stored {
 			return c.putState(updated)
 		}
+		return nil
+	}
+}
 
-		// Updating label-specific state is necessary.
-		labelState, err := c.getLabelState(req.Label)
+// History returns a HistoryRequest for the versions of `label` starting from
+// `start`, and a function to verify the corresponding HistoryResponse. The
+// response may contain only some of the remaining versions, in which case
+// History should be called again to request the next page.
+func (c *Client) History(label []byte, start uint32) (
+	*structs.HistoryRequest,
+	VerifyFunc[*structs.HistoryResponse],
+	error,
+) {
+	state, err := c.getState()
+	if err != nil {
+		return nil, nil, err
+	}
+	req := &structs.HistoryRequest{Last: getLast(state), Label: label, Start: start}
+	return req, c.history(state, req), nil
+}
+
+func (c *Client) history(
+	state *structs.ClientState,
+	req *structs.HistoryRequest,
+) VerifyFunc[*structs.HistoryResponse] {
+	return func(res *structs.HistoryResponse) error {
+		if req.Start > res.Version {
+			return errors.New("start version is greater than greatest version of label")
+		} else if len(res.Entries) == 0 || len(res.Entries) > int(res.Version-req.Start)+1 {
+			return errors.New("unexpected number of history entries provided")
+		}
+		v, err := newBatchVerifier(c.config, state, req.Last, res.FullTreeHead, res.Search, len(res.Entries)+1)
 		if err != nil {
 			return err
-		} else if err := v.addLabelState(labelState); err != nil {
+		}
+
+		// Compute the VRF output for each version in the binary ladder of the
+		// greatest-version search, and of each entry.
+		ladder := math.SearchBinaryLadder(res.Version, res.Version, nil, nil)
+		if err := v.processLadder(req.Label, res.BinaryLadder, ladder, nil); err != nil {
 			return err
 		}
-		terminal, err = updateContactState(labelState, terminal, v.n, target)
-		if err != nil {
+		for i, entry := range res.Entries {
+			ver := req.Start + uint32(i)
+			if err := v.selectLabel(i + 1); err != nil {
+				return err
+			}
+
+			err = verifyUpdateValue(c.config, req.Label, ver, entry.Value)
+			if err != nil {
+				return err
+			}
+			commitment, err := computeCommitment(c.config, entry.Opening, req.Label, ver, entry.Value)
+			if err != nil {
+				return err
+			}
+			ladder := math.SearchBinaryLadder(ver, ver, nil, nil)
+			err = v.processLadder(req.Label, entry.BinaryLadder, ladder, map[uint32][]byte{
+				ver: commitment,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		// Verify the proof.
+		if err := v.updateView(); err != nil {
 			return err
-		} else if err := updateRetainedVersions(labelState, v.handle); err != nil {
+		} else if err := v.selectLabel(0); err != nil {
+			return err
+		} else if _, err := v.greatestVersionSearch(res.Version); err != nil {
+			return err
+		}
+		for i, entry := range res.Entries {
+			if err := v.selectLabel(i + 1); err != nil {
+				return err
+			} else if err := v.versionPosition(req.Start+uint32(i), entry.Position); err != nil {
+				return err
+			}
+		}
+		updated, err := v.finish()
+		if err != nil {
 			return err
 		}
-		return c.putLabelState(updated, req.Label, labelState, terminal)
+		return c.putState(updated)
 	}
 }
 
@@ -544,6 +704,24 @@ func (sv *StreamVerifier) verifyValues(
 	return startVer, commitments, nil
 }
 
+// verifySubmitted checks that, if the UpdateResponse claims to contain the
+// versions submitted in the request, the values of those versions match.
+func (sv *StreamVerifier) verifySubmitted(res *structs.UpdateResponse) error {
+	if res.Submitted == nil {
+		return nil
+	}
+	start := int(*res.Submitted)
+	if start+len(sv.req.Values) > len(res.Values) {
+		return errors.New("submitted versions are out of range of update response")
+	}
+	for i, val := range sv.req.Values {
+		if !bytes.Equal(res.Values[start+i].Value, val.Value) {
+			return errors.New("update response does not contain submitted values")
+		}
+	}
+	return nil
+}
+
 // Verify processes the given UpdateResponse. If it returns an error, the
 // StreamVerifier is invalidated and should no longer be used.
 func (sv *StreamVerifier) Verify(res *structs.UpdateResponse) error {
@@ -554,7 +732,7 @@ func (sv *StreamVerifier) Verify(res *structs.UpdateResponse) error {
 	)
 	if len(res.Values) == 0 {
 		startVer, commitments, err = sv.verifyValues(sv.req.Values, res)
-	} else {
+	} else if err = sv.verifySubmitted(res); err == nil {
 		startVer, commitments, err = sv.verifyValues(res.Values, res)
 	}
 	if err != nil {
@@ -570,6 +748,14 @@ func (sv *StreamVerifier) Verify(res *structs.UpdateResponse) error {
 		}
 	}
 
+	// Versions that were retained before they were created have no
+	// commitment. Fill in the commitments that were just computed.
+	for i, entry := range sv.labelState.Versions {
+		if commitment, ok := commitments[entry.Version]; ok && entry.Commitment == nil {
+			sv.labelState.Versions[i].Commitment = commitment
+		}
+	}
+
 	// Verify the expected number of entries is present in res.BinaryLadder.
 	v, err := newVerifier(sv.client.config, sv.state, getLast(sv.state), res.FullTreeHead, res.Update)
 	if err != nil {
@@ -610,3 +796,39 @@ func (sv *StreamVerifier) Verify(res *structs.UpdateResponse) error {
 
 	return sv.client.putLabelState(updated, sv.req.Label, sv.labelState, sv.labelState.Owner.Starting)
 }
+
+// VerifyConsistency verifies the response to a ConsistencyRequest, where
+// `oldRoot` is the root of the Log Tree of size `req.Older`. It checks the
+// signatures on both tree heads, and returns the root of the Log Tree of size
+// `req.Newer`. It doesn't depend on any client state.
+func VerifyConsistency(
+	config *structs.PublicConfig,
+	req *structs.ConsistencyRequest,
+	res *structs.ConsistencyResponse,
+	oldRoot []byte,
+) ([]byte, error) {
+	if res.Older.TreeSize != req.Older || res.Newer.TreeSize != req.Newer {
+		return nil, errors.New("tree heads do not match requested tree sizes")
+	}
+	newRoot, err := log.NewVerifier(config.Suite).EvaluateConsistency(req.Older, req.Newer, oldRoot, res.Proof)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, th := range []struct {
+		treeHead *structs.TreeHead
+		root     []byte
+	}{{&res.Older, oldRoot}, {&res.Newer, newRoot}} {
+		tbs, err := structs.Marshal(&structs.TreeHeadTBS{
+			Config:   config,
+			TreeSize: th.treeHead.TreeSize,
+			Root:     th.root,
+		})
+		if err != nil {
+			return nil, err
+		} else if !config.SignatureKey.Verify(tbs, th.treeHead.Signature) {
+			return nil, errors.New("failed to verify tree head signature")
+		}
+	}
+	return newRoot, nil
+}
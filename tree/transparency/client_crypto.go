@@ -1,7 +1,6 @@
 package transparency
 
 import (
-	"bytes"
 	"errors"
 
 	"github.com/Bren2010/katie/crypto/commitments"
@@ -32,9 +31,22 @@ func newVerifier(
 	last *uint64,
 	fth structs.FullTreeHead,
 	proof structs.CombinedTreeProof,
+) (*verifier, error) {
+	return newBatchVerifier(config, state, last, fth, proof, 1)
+}
+
+// newBatchVerifier returns a verifier for a CombinedTreeProof that covers
+// `labels` different labels.
+func newBatchVerifier(
+	config *structs.PublicConfig,
+	state *structs.ClientState,
+	last *uint64,
+	fth structs.FullTreeHead,
+	proof structs.CombinedTreeProof,
+	labels int,
 ) (*verifier, error) {
 	// Set up ProofHandle and DataProvider.
-	handle := algorithms.NewReceivedProofHandle(config.Suite, proof)
+	handle := algorithms.NewBatchReceivedProofHandle(config.Suite, proof, labels)
 	provider := algorithms.NewDataProvider(config.Suite, handle)
 	if state != nil {
 		err := provider.AddRetained(state.FullSubtrees, state.LogEntries)
@@ -61,6 +73,12 @@ func newVerifier(
 	return &verifier{config, state, fth, handle, provider, n, nP, last}, nil
 }
 
+// selectLabel changes which label the proof is being verified for, when the
+// proof covers several labels.
+func (v *verifier) selectLabel(i int) error {
+	return v.provider.SelectLabel(i)
+}
+
 func (v *verifier) updateView() error {
 	return algorithms.UpdateView(v.config, v.n, v.m, v.provider)
 }
@@ -73,6 +91,10 @@ func (v *verifier) fixedVersionSearch(ver uint32) (uint64, error) {
 	return algorithms.FixedVersionSearch(v.config, ver, v.n, v.provider)
 }
 
+func (v *verifier) versionPosition(ver uint32, x uint64) error {
+	return algorithms.VersionPosition(v.config, ver, x, v.n, v.provider)
+}
+
 func (v *verifier) monitor() (*algorithms.Monitor, error) {
 	return algorithms.NewMonitor(v.config, v.n, v.provider)
 }
@@ -87,7 +109,7 @@ func (v *verifier) rightmostDistinguished() (*uint64, error) {
 // those are consistent with what we've retained.
 func (v *verifier) addLabelState(state *structs.ClientLabelState) error {
 	for _, entry := range state.Versions {
-		err := v.handle.AddVersion(entry.Version, entry.VrfOutput, entry.Commitment)
+		err := v.handle.AddRetainedVersion(entry.Version, entry.VrfOutput, entry.Commitment)
 		if err != nil {
 			return err
 		}
@@ -144,7 +166,7 @@ func (v *verifier) verifyTreeHead(root, rootP []byte) error {
 	}
 
 	// Verify the size and signature on the tree head.
-	if v.state != nil && v.state.TreeHead.TreeSize <= v.n {
+	if v.state != nil && v.n <= v.state.TreeHead.TreeSize {
 		return errors.New("provided tree size is not greater than advertised")
 	}
 	tbs, err := structs.Marshal(&structs.TreeHeadTBS{
@@ -231,13 +253,14 @@ func (v *verifier) finish() (*structs.ClientState, error) {
 
 	// Compute and return the updated client state.
 	updated := &structs.ClientState{
-		TreeHead:        v.state.TreeHead,
 		AuditorTreeHead: v.fth.AuditorTreeHead,
 		FullSubtrees:    result.FullSubtrees,
 		LogEntries:      result.LogEntries,
 	}
 	if v.fth.TreeHead != nil {
 		updated.TreeHead = *v.fth.TreeHead
+	} else {
+		updated.TreeHead = v.state.TreeHead
 	}
 	return updated, nil
 }
@@ -280,7 +303,10 @@ func parseLabelState(config *structs.PublicConfig, raw []byte) (*structs.ClientL
 		return &structs.ClientLabelState{}, nil
 	}
 
-	buf := bytes.NewBuffer(raw)
+	buf, err := structs.NewDecoder(raw, structs.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
 	state, err := structs.NewClientLabelState(config.Suite, buf)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,181 @@
+package transparency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Bren2010/katie/db"
+	"github.com/Bren2010/katie/db/memory"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/test"
+)
+
+// testConcurrentSearch mutates a tree backed by `store` while other goroutines
+// search snapshots of it, and checks that every search response verifies.
+func testConcurrentSearch(t *testing.T, store db.TransparencyStore) {
+	const (
+		rounds  = 30
+		readers = 4
+	)
+	config := test.Config(t)
+	labels := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+
+	tree, err := NewTree(config, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutate := func(round int) error {
+		add := make([]LabelValue, len(labels))
+		for i, label := range labels {
+			add[i] = LabelValue{Label: label, Value: structs.UpdateValue{Value: []byte{byte(round)}}}
+		}
+		_, err := tree.Mutate(add, nil)
+		return err
+	}
+	if err := mutate(0); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+		errs = make(chan error, readers)
+	)
+	for i := range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- searchUntil(done, config, store, labels[i%len(labels)])
+		}()
+	}
+	for round := 1; round < rounds; round++ {
+		if err := mutate(round); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// searchUntil repeatedly searches for the greatest version of `label` in a new
+// snapshot of `store`, until `done` is closed. It returns an error if any
+// response fails to verify, or if the greatest version of the label ever goes
+// backwards.
+func searchUntil(done <-chan struct{}, config structs.PrivateConfig, store db.TransparencyStore, label []byte) error {
+	client, err := NewClient(config.Public(), memory.NewClientStore())
+	if err != nil {
+		return err
+	}
+
+	greatest := uint32(0)
+	for {
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+
+		snapshot, err := store.Clone()
+		if err != nil {
+			return err
+		}
+		tree, err := NewTree(config, snapshot, nil)
+		if err != nil {
+			return err
+		}
+		req, verify, err := client.GreatestVersionSearch(label)
+		if err != nil {
+			return err
+		}
+		res, err := tree.Search(context.Background(), req)
+		snapshot.Release()
+		if err != nil {
+			return err
+		} else if err := verify(res); err != nil {
+			return fmt.Errorf("search response failed to verify: %w", err)
+		}
+
+		if *res.Version < greatest {
+			return errors.New("greatest version of label decreased")
+		} else if !bytes.Equal(res.Value.Value, []byte{byte(*res.Version)}) {
+			return errors.New("unexpected value returned")
+		}
+		greatest = *res.Version
+	}
+}
+
+func TestConcurrentSearchMemory(t *testing.T) {
+	testConcurrentSearch(t, memory.NewTransparencyStore())
+}
+
+func TestConcurrentSearchLevelDB(t *testing.T) {
+	store, err := db.NewLDBTransparencyStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testConcurrentSearch(t, store)
+}
+
+func TestCloneIsSnapshot(t *testing.T) {
+	store := memory.NewTransparencyStore()
+	tree, err := NewTree(test.Config(t), store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutateLabels(t, tree, "a")
+
+	snapshot, err := store.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutateLabels(t, tree, "b")
+
+	// The snapshot doesn't reflect the second mutation, and can't be written
+	// to.
+	raw, _, err := snapshot.GetTreeHead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := structs.NewDecoder(raw, structs.DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	treeHead, err := structs.NewTreeHead(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if treeHead.TreeSize != 1 {
+		t.Fatal("snapshot reflects changes made after it was taken")
+	}
+	indices, err := snapshot.BatchGetIndex([][]byte{[]byte("b")})
+	if err != nil {
+		t.Fatal(err)
+	} else if indices[0] != nil {
+		t.Fatal("snapshot reflects changes made after it was taken")
+	}
+	if err := snapshot.PutIndex([]byte("c"), []byte{0}); err == nil {
+		t.Fatal("expected error writing to snapshot")
+	}
+
+	// Cloning the snapshot returns the most recently committed state.
+	snapshot, err = snapshot.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	indices, err = snapshot.BatchGetIndex([][]byte{[]byte("b")})
+	if err != nil {
+		t.Fatal(err)
+	} else if indices[0] == nil {
+		t.Fatal("snapshot does not reflect committed changes")
+	}
+}
@@ -25,28 +25,37 @@ func (t *Tree) batchGetIndex(labels [][]byte) ([][]uint64, error) {
 
 	out := make([][]uint64, len(rawIndices))
 	for i, raw := range rawIndices {
-		buf := bytes.NewBuffer(raw)
-		index := make([]uint64, 0)
-
-		for {
-			pos, err := binary.ReadUvarint(buf)
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				return nil, err
-			}
-			index = append(index, pos)
+		index, err := decodeIndex(raw)
+		if err != nil {
+			return nil, err
 		}
-		for i := 1; i < len(index); i++ {
-			index[i] += index[i-1]
-		}
-
 		out[i] = index
 	}
 
 	return out, nil
 }
 
+// decodeIndex decodes a label's index from its stored form.
+func decodeIndex(raw []byte) ([]uint64, error) {
+	buf := bytes.NewBuffer(raw)
+	index := make([]uint64, 0)
+
+	for {
+		pos, err := binary.ReadUvarint(buf)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		index = append(index, pos)
+	}
+	for i := 1; i < len(index); i++ {
+		index[i] += index[i-1]
+	}
+
+	return index, nil
+}
+
 // putIndex updates the stored index of the label.
 func (t *Tree) putIndex(label []byte, index []uint64) error {
 	compressed := make([]uint64, len(index))
@@ -81,7 +90,10 @@ func (t *Tree) getVersion(label []byte, ver uint32) (*structs.OpeningAndValue, e
 		size := t.config.Suite.CommitmentOpeningSize()
 		return &structs.OpeningAndValue{Opening: make([]byte, size)}, nil
 	}
-	buf := bytes.NewBuffer(raw)
+	buf, err := structs.NewDecoder(raw, structs.DefaultLimits)
+	if err != nil {
+		return nil, err
+	}
 	labelValue, err := structs.NewOpeningAndValue(t.config.Public(), buf)
 	if err != nil {
 		return nil, err
@@ -123,6 +135,5 @@ func (t *Tree) computeVrfOutput(label []byte, ver uint32) (vrfOutput, proof []by
 	if err != nil {
 		return nil, nil, err
 	}
-	vrfOutput, proof = t.config.VrfKey.Prove(input)
-	return
+	return t.config.VrfKey.Prove(input)
 }
@@ -0,0 +1,319 @@
+package transparency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/wire"
+)
+
+// KTClientConfig controls how a KTClient retries failed requests.
+type KTClientConfig struct {
+	// MaxAttempts is the maximum number of times a request is sent before
+	// giving up. If zero, requests are sent only once.
+	MaxAttempts int
+
+	// RetryDelay is how long to wait before the first retry. The delay doubles
+	// after each subsequent failure.
+	RetryDelay time.Duration
+}
+
+// KTClient pairs a Client with a wire.Interface, sending requests to the
+// Transparency Log and verifying the responses.
+type KTClient struct {
+	client *Client
+	log    wire.Interface
+	config KTClientConfig
+}
+
+// NewKTClient returns a new KTClient that generates and verifies requests with
+// `client` and sends them to `log`.
+func NewKTClient(client *Client, log wire.Interface, config KTClientConfig) *KTClient {
+	return &KTClient{client: client, log: log, config: config}
+}
+
+// LookupResult is the output of a successful Lookup or LookupVersion.
+type LookupResult struct {
+	Version  uint32 // Version is the version of the label that was found.
+	Value    []byte // Value is the label's value at Version.
+	TreeSize uint64 // TreeSize is the size of the tree the lookup was verified in.
+}
+
+// ClaimResult is the output of a successful Claim.
+type ClaimResult struct {
+	// GreatestVersion is the greatest version of the label that exists, or
+	// nil if the label has no versions yet.
+	GreatestVersion *uint32
+	TreeSize        uint64
+}
+
+// PublishResult is the output of a successful Publish.
+type PublishResult struct {
+	Position     uint64 // Position is the log entry where the new versions were added.
+	FirstVersion uint32 // FirstVersion is the version assigned to the first new value.
+	LastVersion  uint32 // LastVersion is the version assigned to the last new value.
+	TreeSize     uint64
+}
+
+// MonitorResult is the output of a successful MonitorAll.
+type MonitorResult struct {
+	Labels   int // Labels is the number of labels that were monitored.
+	TreeSize uint64
+}
+
+// retryable returns true if `err` is a transient transport error, after which
+// a request may be sent again. Requests that aren't `idempotent` are only
+// retried if they provably never reached the server, because a connection to
+// it couldn't be established. Other errors, like a gateway timeout, don't prove
+// that the server hasn't acted on the request.
+func retryable(ctx context.Context, err error, idempotent bool) bool {
+	if ctx.Err() != nil {
+		return false
+	} else if !idempotent {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	var target interface{ Retryable() bool }
+	if errors.As(err, &target) {
+		return target.Retryable()
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// retry calls `f` until it succeeds, it returns an error that is not
+// retryable, or the configured number of attempts is exhausted.
+func (kc *KTClient) retry(ctx context.Context, idempotent bool, f func() error) error {
+	delay := kc.config.RetryDelay
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil {
+			return nil
+		} else if attempt >= kc.config.MaxAttempts || !retryable(ctx, err, idempotent) {
+			return err
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		delay *= 2
+	}
+}
+
+// treeSize returns the size of the tree in the client's verified state.
+func (kc *KTClient) treeSize() (uint64, error) {
+	state, err := kc.client.getState()
+	if err != nil {
+		return 0, err
+	} else if state == nil {
+		return 0, errors.New("no client state stored")
+	}
+	return state.TreeHead.TreeSize, nil
+}
+
+// Lookup searches for the greatest version of `label`.
+func (kc *KTClient) Lookup(ctx context.Context, label []byte) (*LookupResult, error) {
+	req, verify, err := kc.client.GreatestVersionSearch(label)
+	if err != nil {
+		return nil, err
+	}
+	return kc.lookup(ctx, req, verify)
+}
+
+// LookupVersion searches for a specific version of `label`.
+func (kc *KTClient) LookupVersion(ctx context.Context, label []byte, ver uint32) (*LookupResult, error) {
+	req, verify, err := kc.client.FixedVersionSearch(label, ver)
+	if err != nil {
+		return nil, err
+	}
+	return kc.lookup(ctx, req, verify)
+}
+
+func (kc *KTClient) lookup(
+	ctx context.Context,
+	req *structs.SearchRequest,
+	verify VerifyFunc[*structs.SearchResponse],
+) (*LookupResult, error) {
+	var res *structs.SearchResponse
+	err := kc.retry(ctx, true, func() (err error) {
+		res, err = kc.log.Search(ctx, req)
+		return
+	})
+	if err != nil {
+		return nil, err
+	} else if err := verify(res); err != nil {
+		return nil, err
+	}
+
+	ver := res.Version
+	if req.Version != nil {
+		ver = req.Version
+	}
+	treeSize, err := kc.treeSize()
+	if err != nil {
+		return nil, err
+	}
+	return &LookupResult{Version: *ver, Value: res.Value.Value, TreeSize: treeSize}, nil
+}
+
+// Claim initializes ownership of `label`, after which it will be monitored as
+// an owned label and new versions may be published with Publish.
+func (kc *KTClient) Claim(ctx context.Context, label []byte) (*ClaimResult, error) {
+	req, verify, err := kc.client.OwnerInit(label)
+	if err != nil {
+		return nil, err
+	}
+	var res *structs.OwnerInitResponse
+	err = kc.retry(ctx, true, func() (err error) {
+		res, err = kc.log.OwnerInit(ctx, req)
+		return
+	})
+	if err != nil {
+		return nil, err
+	} else if err := verify(res); err != nil {
+		return nil, err
+	}
+
+	labelState, err := kc.client.getLabelState(label)
+	if err != nil {
+		return nil, err
+	}
+	treeSize, err := kc.treeSize()
+	if err != nil {
+		return nil, err
+	}
+	return &ClaimResult{
+		GreatestVersion: greatestVersion(labelState.Owner),
+		TreeSize:        treeSize,
+	}, nil
+}
+
+// Publish creates new versions of `label` with the given values. The label
+// must have been claimed with Claim first.
+func (kc *KTClient) Publish(ctx context.Context, label []byte, values [][]byte) (*PublishResult, error) {
+	if len(values) == 0 {
+		return nil, errors.New("no values given to publish")
+	}
+	req, verifier, err := kc.client.Update(label, values)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only failures that occur before the server has acted on the request are
+	// retried, since the request is not idempotent.
+	var ch <-chan wire.UpdateResponse
+	err = kc.retry(ctx, false, func() (err error) {
+		ch, err = kc.log.Update(ctx, req)
+		return
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for range ch {
+		}
+	}()
+
+	// The stream may contain versions created by other writers, before or
+	// after the versions that were submitted, or in the same log entry. The
+	// submitted versions are either in the response without Values, or in the
+	// response that marks where they start with Submitted. Either way, the
+	// verifier checks them against the values in the request.
+	var out *PublishResult
+	for res := range ch {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		startVer := uint32(0)
+		if greatest := greatestVersion(verifier.labelState.Owner); greatest != nil {
+			startVer = *greatest + 1
+		}
+		if err := verifier.Verify(res.Out); err != nil {
+			return nil, err
+		} else if len(res.Out.Values) != 0 && res.Out.Submitted == nil {
+			continue
+		} else if out != nil {
+			return nil, errors.New("submitted versions were confirmed more than once")
+		}
+		if res.Out.Submitted != nil {
+			startVer += uint32(*res.Out.Submitted)
+		}
+		out = &PublishResult{
+			Position:     res.Out.Position,
+			FirstVersion: startVer,
+			LastVersion:  startVer + uint32(len(values)) - 1,
+		}
+	}
+	if out == nil {
+		return nil, errors.New("stream ended before new versions were confirmed")
+	}
+	out.TreeSize = verifier.state.TreeHead.TreeSize
+	return out, nil
+}
+
+// MonitorAll monitors every label where monitoring is currently recommended,
+// until there are none left.
+func (kc *KTClient) MonitorAll(ctx context.Context) (*MonitorResult, error) {
+	seen := make(map[string]struct{})
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		req, verify, err := kc.client.Monitor()
+		if err != nil {
+			return nil, err
+		} else if req == nil {
+			break
+		}
+
+		// Stop if a label comes up a second time, which means it can't be
+		// brought up-to-date until the tree grows.
+		var label []byte
+		switch req := req.(type) {
+		case *structs.ContactMonitorRequest:
+			label = req.Label
+		case *structs.OwnerMonitorRequest:
+			label = req.Label
+		}
+		labelStr := fmt.Sprintf("%x", label)
+		if _, ok := seen[labelStr]; ok {
+			break
+		}
+		seen[labelStr] = struct{}{}
+
+		var res structs.Marshaller
+		err = kc.retry(ctx, true, func() (err error) {
+			switch req := req.(type) {
+			case *structs.ContactMonitorRequest:
+				res, err = kc.log.ContactMonitor(ctx, req)
+			case *structs.OwnerMonitorRequest:
+				res, err = kc.log.OwnerMonitor(ctx, req)
+			default:
+				err = errors.New("unexpected monitoring request type")
+			}
+			return
+		})
+		if err != nil {
+			return nil, err
+		} else if err := verify(res); err != nil {
+			return nil, err
+		}
+	}
+
+	res := &MonitorResult{Labels: len(seen)}
+	if len(seen) > 0 {
+		treeSize, err := kc.treeSize()
+		if err != nil {
+			return nil, err
+		}
+		res.TreeSize = treeSize
+	}
+	return res, nil
+}
@@ -0,0 +1,392 @@
+package transparency
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Bren2010/katie/db/memory"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/test"
+	"github.com/Bren2010/katie/tree/transparency/wire"
+)
+
+// newTestLog starts a Transparency Tree with a background sequencer, where
+// `label` has been created with a single version. It returns a function that
+// loads the current state of the tree.
+func newTestLog(t *testing.T, label []byte) (*structs.PublicConfig, func() *Tree) {
+	return newHookedTestLog(t, label, SequencerConfig{}, nil)
+}
+
+// newHookedTestLog is the same as newTestLog, except that the sequencer uses
+// `seqConfig` and, if `hook` is not nil, each UpdateRequest submitted by the
+// loaded trees is passed to `hook` along with the sequencer's channel, instead
+// of to the sequencer directly.
+func newHookedTestLog(
+	t *testing.T,
+	label []byte,
+	seqConfig SequencerConfig,
+	hook func(req UpdateRequest, seq chan<- UpdateRequest),
+) (*structs.PublicConfig, func() *Tree) {
+	config := test.Config(t)
+	store := memory.NewTransparencyStore()
+	ch := make(chan UpdateRequest)
+	t.Cleanup(func() { close(ch) })
+
+	seqTree, err := NewTree(config, store, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = seqTree.Mutate([]LabelValue{
+		{Label: label, Value: structs.UpdateValue{Value: []byte("version 0")}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := NewSequencer(seqTree, ch, seqConfig)
+	go func() {
+		for {
+			if _, err := seq.Next(context.Background()); err != nil {
+				return
+			}
+		}
+	}()
+
+	updates := ch
+	if hook != nil {
+		updates = make(chan UpdateRequest)
+		t.Cleanup(func() { close(updates) })
+		go func() {
+			for req := range updates {
+				hook(req, ch)
+			}
+		}()
+	}
+
+	return config.Public(), func() *Tree {
+		snapshot, err := store.Clone()
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(snapshot.Release)
+		tree, err := NewTree(config, snapshot, updates)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tree
+	}
+}
+
+func newTestKTClient(t *testing.T, config *structs.PublicConfig, log wire.Interface, ktConfig KTClientConfig) *KTClient {
+	client, err := NewClient(config, memory.NewClientStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewKTClient(client, log, ktConfig)
+}
+
+func TestKTClientLookup(t *testing.T) {
+	ctx := context.Background()
+	label := []byte("label")
+	config, load := newTestLog(t, label)
+	kc := newTestKTClient(t, config, load(), KTClientConfig{})
+
+	res, err := kc.Lookup(ctx, label)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Version != 0 || !bytes.Equal(res.Value, []byte("version 0")) || res.TreeSize != 1 {
+		t.Fatalf("unexpected lookup result: %+v", res)
+	}
+
+	res, err = kc.LookupVersion(ctx, label, 0)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Version != 0 || !bytes.Equal(res.Value, []byte("version 0")) {
+		t.Fatalf("unexpected lookup result: %+v", res)
+	}
+}
+
+func TestKTClientPublish(t *testing.T) {
+	ctx := context.Background()
+	label := []byte("label")
+	config, load := newTestLog(t, label)
+	store := memory.NewClientStore()
+	client, err := NewClient(config, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kc := NewKTClient(client, load(), KTClientConfig{})
+
+	// Claiming a label requires some state to already be established.
+	if _, err := kc.Lookup(ctx, label); err != nil {
+		t.Fatal(err)
+	}
+	claim, err := kc.Claim(ctx, label)
+	if err != nil {
+		t.Fatal(err)
+	} else if claim.GreatestVersion == nil || *claim.GreatestVersion != 0 {
+		t.Fatalf("unexpected claim result: %+v", claim)
+	} else if stale := store.StaleLabels(math.MaxUint64); len(stale) != 1 || !bytes.Equal(stale[0], label) {
+		t.Fatalf("unexpected labels stored: %x", stale)
+	}
+
+	pub, err := kc.Publish(ctx, label, [][]byte{[]byte("version 1"), []byte("version 2")})
+	if err != nil {
+		t.Fatal(err)
+	} else if pub.Position != 1 || pub.FirstVersion != 1 || pub.LastVersion != 2 || pub.TreeSize != 2 {
+		t.Fatalf("unexpected publish result: %+v", pub)
+	}
+
+	// Check that the new version is visible, and that the label can be
+	// monitored, once the tree is reloaded.
+	kc = NewKTClient(kc.client, load(), KTClientConfig{})
+	res, err := kc.Lookup(ctx, label)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Version != 2 || !bytes.Equal(res.Value, []byte("version 2")) {
+		t.Fatalf("unexpected lookup result: %+v", res)
+	}
+	if _, err := kc.MonitorAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKTClientPublishConcurrent(t *testing.T) {
+	ctx := context.Background()
+	label := []byte("label")
+
+	// Another writer creates a new version of the label right after the
+	// submitted versions are sequenced, and before the tree is reloaded.
+	config, load := newHookedTestLog(t, label, SequencerConfig{}, func(req UpdateRequest, seq chan<- UpdateRequest) {
+		res := make(chan UpdateResult, 1)
+		seq <- UpdateRequest{Label: req.Label, StartingVersion: req.StartingVersion, Values: req.Values, Response: res}
+		result, ok := <-res
+		if !ok {
+			close(req.Response)
+			return
+		}
+		other := make(chan UpdateResult, 1)
+		seq <- UpdateRequest{
+			Label:    req.Label,
+			Values:   []structs.UpdateValue{{Value: []byte("concurrent")}},
+			Response: other,
+		}
+		<-other
+		req.Response <- result
+	})
+	kc := newTestKTClient(t, config, load(), KTClientConfig{})
+	if _, err := kc.Lookup(ctx, label); err != nil {
+		t.Fatal(err)
+	} else if _, err := kc.Claim(ctx, label); err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := kc.Publish(ctx, label, [][]byte{[]byte("version 1"), []byte("version 2")})
+	if err != nil {
+		t.Fatal(err)
+	} else if pub.Position != 1 || pub.FirstVersion != 1 || pub.LastVersion != 2 || pub.TreeSize != 3 {
+		t.Fatalf("unexpected publish result: %+v", pub)
+	}
+}
+
+func TestKTClientPublishMerged(t *testing.T) {
+	ctx := context.Background()
+	label := []byte("label")
+
+	// Another writer's request for the same label arrives just before the
+	// submitted one, and the sequencer merges both into one log entry.
+	seqConfig := SequencerConfig{MaxBatchDelay: 50 * time.Millisecond}
+	config, load := newHookedTestLog(t, label, seqConfig, func(req UpdateRequest, seq chan<- UpdateRequest) {
+		seq <- UpdateRequest{
+			Label:    req.Label,
+			Values:   []structs.UpdateValue{{Value: []byte("concurrent")}},
+			Response: make(chan UpdateResult, 1),
+		}
+		seq <- req
+	})
+	kc := newTestKTClient(t, config, load(), KTClientConfig{})
+	if _, err := kc.Lookup(ctx, label); err != nil {
+		t.Fatal(err)
+	} else if _, err := kc.Claim(ctx, label); err != nil {
+		t.Fatal(err)
+	}
+
+	pub, err := kc.Publish(ctx, label, [][]byte{[]byte("version 2"), []byte("version 3")})
+	if err != nil {
+		t.Fatal(err)
+	} else if pub.Position != 1 || pub.FirstVersion != 2 || pub.LastVersion != 3 || pub.TreeSize != 2 {
+		t.Fatalf("unexpected publish result: %+v", pub)
+	}
+
+	kc = NewKTClient(kc.client, load(), KTClientConfig{})
+	res, err := kc.Lookup(ctx, label)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Version != 3 || !bytes.Equal(res.Value, []byte("version 3")) {
+		t.Fatalf("unexpected lookup result: %+v", res)
+	}
+	if _, err := kc.MonitorAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKTClientPublishMonitored(t *testing.T) {
+	ctx := context.Background()
+	label, other := []byte("label"), []byte("other")
+	config, load := newTestLog(t, label)
+	kc := newTestKTClient(t, config, load(), KTClientConfig{})
+	if _, err := kc.Lookup(ctx, label); err != nil {
+		t.Fatal(err)
+	} else if _, err := kc.Claim(ctx, label); err != nil {
+		t.Fatal(err)
+	} else if _, err := kc.Publish(ctx, label, [][]byte{[]byte("version 1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Other labels are updated, and the owner monitors their label in the
+	// larger tree before publishing again. The proof for the new version must
+	// match the owner's monitoring state, rather than just the label's index.
+	for i := range 5 {
+		ch, err := load().Update(ctx, &structs.UpdateRequest{
+			Label:  other,
+			Values: []structs.LabelValue{{Value: fmt.Appendf(nil, "other %d", i)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for res := range ch {
+			if res.Err != nil {
+				t.Fatal(res.Err)
+			}
+		}
+	}
+	kc = NewKTClient(kc.client, load(), KTClientConfig{})
+	if _, err := kc.MonitorAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	state, err := kc.client.getLabelState(label)
+	if err != nil {
+		t.Fatal(err)
+	} else if state.Owner.Starting <= 1 || len(state.Owner.UpcomingVers) != 0 {
+		t.Fatalf("owner monitoring did not move past the last update: %+v", state.Owner)
+	}
+
+	pub, err := kc.Publish(ctx, label, [][]byte{[]byte("version 2")})
+	if err != nil {
+		t.Fatal(err)
+	} else if pub.Position != 7 || pub.FirstVersion != 2 || pub.LastVersion != 2 || pub.TreeSize != 8 {
+		t.Fatalf("unexpected publish result: %+v", pub)
+	}
+	kc = NewKTClient(kc.client, load(), KTClientConfig{})
+	if _, err := kc.MonitorAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// retryableError is a transient error from a transport.
+type retryableError struct{}
+
+func (retryableError) Error() string   { return "temporarily unavailable" }
+func (retryableError) Retryable() bool { return true }
+
+// flakyLog fails the first `failures` Search requests with `err`.
+type flakyLog struct {
+	wire.Interface
+	failures int
+	err      error
+	calls    int
+}
+
+func (fl *flakyLog) Search(ctx context.Context, req *structs.SearchRequest) (*structs.SearchResponse, error) {
+	fl.calls++
+	if fl.calls <= fl.failures {
+		return nil, fl.err
+	}
+	return fl.Interface.Search(ctx, req)
+}
+
+func TestKTClientRetry(t *testing.T) {
+	ctx := context.Background()
+	label := []byte("label")
+	config, load := newTestLog(t, label)
+	tree := load()
+
+	testCases := []struct {
+		err      error
+		failures int
+		calls    int
+		ok       bool
+	}{
+		{retryableError{}, 2, 3, true},
+		{retryableError{}, 3, 3, false},
+		{errors.New("permanent"), 1, 1, false},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			log := &flakyLog{Interface: tree, failures: tc.failures, err: tc.err}
+			kc := newTestKTClient(t, config, log, KTClientConfig{MaxAttempts: 3})
+
+			_, err := kc.Lookup(ctx, label)
+			if tc.ok && err != nil {
+				t.Fatal(err)
+			} else if !tc.ok && err != tc.err {
+				t.Fatalf("unexpected error: %v", err)
+			} else if log.calls != tc.calls {
+				t.Fatalf("unexpected number of calls: %v != %v", log.calls, tc.calls)
+			}
+		})
+	}
+}
+
+// failingUpdateLog fails every Update request with `err`.
+type failingUpdateLog struct {
+	wire.Interface
+	err   error
+	calls int
+}
+
+func (fl *failingUpdateLog) Update(ctx context.Context, req *structs.UpdateRequest) (<-chan wire.UpdateResponse, error) {
+	fl.calls++
+	return nil, fl.err
+}
+
+func TestKTClientRetryUpdate(t *testing.T) {
+	ctx := context.Background()
+	label := []byte("label")
+	config, load := newTestLog(t, label)
+
+	// Update requests aren't idempotent, so they're only retried if the
+	// connection to the server couldn't be established.
+	testCases := []struct {
+		err   error
+		calls int
+	}{
+		{retryableError{}, 1},
+		{&url.Error{Op: "Post", Err: errors.New("connection reset")}, 1},
+		{&url.Error{Op: "Post", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, 3},
+	}
+	for i, tc := range testCases {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			log := &failingUpdateLog{Interface: load(), err: tc.err}
+			kc := newTestKTClient(t, config, log, KTClientConfig{MaxAttempts: 3})
+			if _, err := kc.Lookup(ctx, label); err != nil {
+				t.Fatal(err)
+			} else if _, err := kc.Claim(ctx, label); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := kc.Publish(ctx, label, [][]byte{[]byte("value")}); err != tc.err {
+				t.Fatalf("unexpected error: %v", err)
+			} else if log.calls != tc.calls {
+				t.Fatalf("unexpected number of calls: %v != %v", log.calls, tc.calls)
+			}
+		})
+	}
+}
@@ -50,6 +50,27 @@ func (ml *ManagedLog) Search(
 	return ml.log.Search(ctx, req)
 }
 
+func (ml *ManagedLog) BatchSearch(
+	ctx context.Context,
+	req *structs.BatchSearchRequest,
+) (*structs.BatchSearchResponse, error) {
+	return ml.log.BatchSearch(ctx, req)
+}
+
+func (ml *ManagedLog) History(
+	ctx context.Context,
+	req *structs.HistoryRequest,
+) (*structs.HistoryResponse, error) {
+	return ml.log.History(ctx, req)
+}
+
+func (ml *ManagedLog) Consistency(
+	ctx context.Context,
+	req *structs.ConsistencyRequest,
+) (*structs.ConsistencyResponse, error) {
+	return ml.log.Consistency(ctx, req)
+}
+
 func (ml *ManagedLog) ContactMonitor(
 	ctx context.Context,
 	req *structs.ContactMonitorRequest,
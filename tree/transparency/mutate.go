@@ -5,7 +5,6 @@ import (
 	"errors"
 	"fmt"
 	"slices"
-	"time"
 
 	"github.com/Bren2010/katie/tree/log"
 	"github.com/Bren2010/katie/tree/prefix"
@@ -17,8 +16,17 @@ import (
 // existing label-version pairs to remove. Version counters are automatically
 // assigned/unassigned.
 //
-// It returns the AuditorUpdate structure for the Third-Party Auditor, if any.
+// It returns the AuditorUpdate structure for the Third-Party Auditor, if any. If
+// the auditor is too far behind, ErrAuditorBehind is returned.
 func (t *Tree) Mutate(add []LabelValue, remove [][]byte) (*structs.AuditorUpdate, error) {
+	return t.mutate(add, remove, false)
+}
+
+// mutate implements Mutate. If `skipIneligible` is true, labels in `remove`
+// that are not yet eligible for deletion are left alone instead of causing an
+// error, and no log entry is created if there would be nothing in it. In that
+// case, the returned AuditorUpdate is nil.
+func (t *Tree) mutate(add []LabelValue, remove [][]byte, skipIneligible bool) (*structs.AuditorUpdate, error) {
 	n := uint64(0)
 	if t.treeHead != nil {
 		n = t.treeHead.TreeSize
@@ -30,7 +38,7 @@ func (t *Tree) Mutate(add []LabelValue, remove [][]byte) (*structs.AuditorUpdate
 	// Decide on the timestamp for the new log entry. We do this so early
 	// because it affects which distinguished log entries exist, which affects
 	// which modifications are allowable.
-	timestamp := uint64(time.Now().UnixMilli())
+	timestamp := t.config.Now()
 	if n > 0 {
 		rightmost, err := provider.GetTimestamp(n - 1)
 		if err != nil {
@@ -39,6 +47,9 @@ func (t *Tree) Mutate(add []LabelValue, remove [][]byte) (*structs.AuditorUpdate
 			return nil, errors.New("refusing to issue tree head: current timestamp is less than previous timestamp")
 		}
 	}
+	if err := t.checkAuditorLag(provider, n, timestamp); err != nil {
+		return nil, err
+	}
 
 	// Compute what the rightmost distinguished log entry will be after the new
 	// log entry is added.
@@ -60,6 +71,12 @@ func (t *Tree) Mutate(add []LabelValue, remove [][]byte) (*structs.AuditorUpdate
 		prefixRemove [][]byte
 	)
 	for _, mutation := range mutations {
+		if skipIneligible && mutation.remove && len(mutation.index) > 0 && !deletable(prevDLE, mutation.index) {
+			mutation.remove = false
+			if len(mutation.add) == 0 {
+				continue
+			}
+		}
 		pa, pr, err := t.mutateLabel(n, prevDLE, mutation)
 		if err != nil {
 			return nil, err
@@ -67,6 +84,9 @@ func (t *Tree) Mutate(add []LabelValue, remove [][]byte) (*structs.AuditorUpdate
 		prefixAdd = append(prefixAdd, pa...)
 		prefixRemove = append(prefixRemove, pr...)
 	}
+	if skipIneligible && len(prefixAdd) == 0 && len(prefixRemove) == 0 {
+		return nil, nil
+	}
 
 	// Sort the new additions and removals by VRF output to avoid accidentally
 	// leaking information to the Third-Party Auditor (if there is one).
@@ -82,22 +102,35 @@ func (t *Tree) Mutate(add []LabelValue, remove [][]byte) (*structs.AuditorUpdate
 		return nil, err
 	}
 
-	// Issue new tree head.
-	if err := t.issueTreeHead(n, timestamp, prefixRoot); err != nil {
-		return nil, err
-	}
-
-	// Construct the AuditorUpdate structure to return.
+	// Construct the AuditorUpdate structure to return. If there is a
+	// Third-Party Auditor and the log entry is at or after the position where
+	// it starts auditing, it is also persisted along with the new log entry so
+	// that it can be provided to the auditor later.
 	removedEntries := make([]prefix.Entry, len(prefixRemove))
 	for i, vrfOutput := range prefixRemove {
 		removedEntries[i] = prefix.Entry{VrfOutput: vrfOutput, Commitment: commitments[i]}
 	}
-	return &structs.AuditorUpdate{
+	update := &structs.AuditorUpdate{
 		Timestamp: timestamp,
 		Added:     prefixAdd,
 		Removed:   removedEntries,
 		Proof:     *prefixProof,
-	}, nil
+	}
+	if t.config.Mode == structs.ThirdPartyAuditing && n >= t.config.AuditorStartPos {
+		raw, err := structs.Marshal(update)
+		if err != nil {
+			return nil, err
+		} else if err := t.tx.PutAuditorUpdate(n, raw); err != nil {
+			return nil, err
+		}
+	}
+
+	// Issue new tree head.
+	if err := t.issueTreeHead(n, timestamp, prefixRoot); err != nil {
+		return nil, err
+	}
+
+	return update, nil
 }
 
 type labelMutation struct {
@@ -167,9 +200,7 @@ func (t *Tree) mutateLabel(n uint64, prevDLE *uint64, mut labelMutation) ([]pref
 	)
 
 	if mut.remove && len(index) > 0 {
-		// The label is not eligible for deletion if it has been modified since
-		// the last distinguished log entry was created.
-		if prevDLE == nil || index[len(index)-1] > *prevDLE {
+		if !deletable(prevDLE, index) {
 			return nil, nil, fmt.Errorf("unable to delete label that was modified recently: %s", label)
 		}
 
@@ -217,6 +248,13 @@ func (t *Tree) mutateLabel(n uint64, prevDLE *uint64, mut labelMutation) ([]pref
 	return add, remove, nil
 }
 
+// deletable returns true if a label with the given index is eligible for
+// deletion. A label is not eligible if it has been modified since the last
+// distinguished log entry was created.
+func deletable(prevDLE *uint64, index []uint64) bool {
+	return prevDLE != nil && index[len(index)-1] <= *prevDLE
+}
+
 // issueTreeHead takes as input the current tree size, the new rightmost
 // timestamp, and the new prefix tree root. It adds a new log entry to the right
 // edge of the log tree and then signs and commits a new tree head.
@@ -249,19 +287,10 @@ func (t *Tree) issueTreeHead(n, timestamp uint64, prefixRoot []byte) error {
 	}
 
 	// Sign and persist the new tree head.
-	tbs, err := structs.Marshal(&structs.TreeHeadTBS{
-		Config:   t.config.Public(),
-		TreeSize: n + 1,
-		Root:     root,
-	})
+	treeHead, err := t.signTreeHead(n+1, root)
 	if err != nil {
 		return err
 	}
-	signature, err := t.config.SignatureKey.Sign(tbs)
-	if err != nil {
-		return err
-	}
-	treeHead := &structs.TreeHead{TreeSize: n + 1, Signature: signature}
 	rawTreeHead, err := structs.Marshal(treeHead)
 	if err != nil {
 		return err
@@ -275,3 +304,21 @@ func (t *Tree) issueTreeHead(n, timestamp uint64, prefixRoot []byte) error {
 
 	return nil
 }
+
+// signTreeHead returns a tree head for the Log Tree of size `n` with root
+// `root`, signed with the Transparency Log's signature key.
+func (t *Tree) signTreeHead(n uint64, root []byte) (*structs.TreeHead, error) {
+	tbs, err := structs.Marshal(&structs.TreeHeadTBS{
+		Config:   t.config.Public(),
+		TreeSize: n,
+		Root:     root,
+	})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := t.config.SignatureKey.Sign(tbs)
+	if err != nil {
+		return nil, err
+	}
+	return &structs.TreeHead{TreeSize: n, Signature: signature}, nil
+}
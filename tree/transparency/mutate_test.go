@@ -2,7 +2,9 @@ package transparency
 
 import (
 	"bytes"
+	"context"
 	"testing"
+	"time"
 
 	"github.com/Bren2010/katie/db/memory"
 	"github.com/Bren2010/katie/tree/transparency/structs"
@@ -186,3 +188,53 @@ func TestAddRemoveSameLabel(t *testing.T) {
 		t.Fatal("unexpected data stored")
 	}
 }
+
+func TestMutateClock(t *testing.T) {
+	ctx := context.Background()
+	clock := test.NewClock()
+	config := test.Config(t)
+	config.Clock = clock
+
+	store := memory.NewTransparencyStore()
+	tree, err := NewTree(config, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mutate := func(label string) {
+		t.Helper()
+		_, err := tree.Mutate([]LabelValue{
+			{Label: []byte(label), Value: structs.UpdateValue{Value: []byte("value")}},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// New log entries are timestamped with the configured clock.
+	mutate("label")
+	raw, err := store.BatchGet([]uint64{0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf, err := structs.NewDecoder(raw[0], structs.DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := structs.NewLogEntry(config.Suite, buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if entry.Timestamp != uint64(clock.Now().UnixMilli()) {
+		t.Fatal("log entry not timestamped with configured clock")
+	}
+
+	// Once the clock is further ahead of the rightmost log entry than
+	// MaxBehind, the tree can't be searched until a new log entry is added.
+	clock.Advance(7 * 24 * time.Hour)
+	if _, err := tree.Search(ctx, &structs.SearchRequest{Label: []byte("label")}); err == nil {
+		t.Fatal("expected search to fail with stale tree")
+	}
+	mutate("other")
+	if _, err := tree.Search(ctx, &structs.SearchRequest{Label: []byte("label")}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,255 @@
+package transparency
+
+import (
+	"errors"
+
+	"github.com/Bren2010/katie/tree/prefix"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+)
+
+// defaultScanSize is the number of label indices that a Reaper loads from the
+// database at once, if not configured otherwise.
+const defaultScanSize = 1000
+
+// ReaperConfig controls how a Reaper searches for and removes expired labels.
+type ReaperConfig struct {
+	// ScanSize is the number of label indices that are loaded from the
+	// database at once. If zero, 1000 indices are loaded at once.
+	ScanSize int
+
+	// MaxBatchSize is the maximum number of labels that will be removed in a
+	// single log entry. If zero, there is no limit.
+	MaxBatchSize int
+}
+
+// Reaper finds labels that have not been updated for longer than the
+// MaximumLifetime of the Transparency Log, and removes them from a Transparency
+// Tree with Mutate.
+//
+// Labels are only removed once they are also eligible for deletion, meaning
+// that they have not been modified since the previous distinguished log entry.
+// Labels that have expired but are not eligible yet are left alone and will be
+// found again by a later scan.
+type Reaper struct {
+	tree   *Tree
+	config ReaperConfig
+
+	cursor []byte // The label to continue scanning from.
+}
+
+// NewReaper returns a new Reaper that removes expired labels from `tree`. Like
+// the Sequencer, it must not be used concurrently with anything else that uses
+// the tree.
+func NewReaper(tree *Tree, config ReaperConfig) *Reaper {
+	if config.ScanSize <= 0 {
+		config.ScanSize = defaultScanSize
+	}
+	return &Reaper{tree: tree, config: config}
+}
+
+// Reap scans label indices, continuing from where the previous call stopped,
+// until it either finds MaxBatchSize expired labels or reaches the last label.
+// The next call after reaching the last label starts over from the first.
+//
+// Any expired labels that are eligible for deletion are removed in a single new
+// log entry, and the AuditorUpdate structure for the log entry is returned. If
+// there were no labels to remove, no log entry is created and nil is returned.
+func (r *Reaper) Reap() (*structs.AuditorUpdate, error) {
+	t := r.tree
+	if t.config.MaximumLifetime == 0 || t.treeHead == nil {
+		return nil, nil
+	}
+	n := t.treeHead.TreeSize
+
+	timestamps, err := t.getTimestamps([]uint64{n - 1})
+	if err != nil {
+		return nil, err
+	}
+	rightmost := timestamps[n-1]
+
+	var expired [][]byte
+	for !r.full(len(expired)) {
+		rawLabels, rawIndices, err := t.tx.ScanIndex(r.cursor, r.config.ScanSize)
+		if err != nil {
+			return nil, err
+		} else if len(rawLabels) != len(rawIndices) {
+			return nil, errors.New("unexpected number of indices returned")
+		}
+		found, pos, err := r.findExpired(rawLabels, rawIndices, rightmost, len(expired))
+		if err != nil {
+			return nil, err
+		}
+		expired = append(expired, found...)
+
+		if pos < len(rawLabels) {
+			// The batch is full. Continue from the label after the last
+			// expired label in the next call.
+			r.cursor = successor(rawLabels[pos-1])
+			break
+		} else if len(rawLabels) < r.config.ScanSize {
+			// The last label was reached.
+			r.cursor = nil
+			break
+		}
+		r.cursor = successor(rawLabels[len(rawLabels)-1])
+	}
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	return t.mutate(nil, expired, true)
+}
+
+// CollectGarbage deletes the prefix tree tiles that are only needed to search
+// the prefix trees of expired log entries, and returns how much storage was
+// reclaimed.
+//
+// Clients search the prefix trees of any unexpired log entry: fixed-version
+// searches and History may stop at any of them, not only at distinguished log
+// entries, and so may the proofs that are produced for new updates. The prefix
+// trees of expired log entries are never searched, except for the one
+// immediately to the left of the leftmost unexpired log entry, which may be
+// needed to prove the position that a version of a label was added at. The
+// prefix trees of that log entry and every log entry to its right are kept.
+//
+// The position that garbage has been collected up to is stored along with the
+// deletions, so each call only looks at the log entries that expired since the
+// previous call, even across restarts.
+func (r *Reaper) CollectGarbage() (*prefix.GCStats, error) {
+	t := r.tree
+	if t.config.MaximumLifetime == 0 || t.treeHead == nil {
+		return &prefix.GCStats{}, nil
+	}
+	n := t.treeHead.TreeSize
+
+	collected, err := t.tx.GetCollected()
+	if err != nil {
+		return nil, err
+	}
+	first, err := t.firstUnexpired(n)
+	if err != nil {
+		return nil, err
+	} else if first <= collected {
+		return &prefix.GCStats{}, nil
+	}
+
+	// The prefix tree of the log entry at position `pos` is version `pos+1`, so
+	// version `first` belongs to the log entry to the left of the leftmost
+	// unexpired log entry.
+	stats, err := prefix.NewTree(t.config.Suite, t.tx.PrefixStore()).CollectGarbage(collected, first)
+	if err != nil {
+		return nil, err
+	} else if err := t.tx.PutCollected(first); err != nil {
+		return nil, err
+	} else if err := t.tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// firstUnexpired returns the position of the leftmost log entry that is not
+// expired in a tree of size `n`. Since timestamps are monotonic, every log
+// entry to its left is expired.
+func (t *Tree) firstUnexpired(n uint64) (uint64, error) {
+	timestamps, err := t.getTimestamps([]uint64{n - 1})
+	if err != nil {
+		return 0, err
+	}
+	rightmost := timestamps[n-1]
+
+	left, right := uint64(0), n-1
+	for left < right {
+		mid := left + (right-left)/2
+		timestamps, err := t.getTimestamps([]uint64{mid})
+		if err != nil {
+			return 0, err
+		} else if t.config.IsExpired(timestamps[mid], rightmost) {
+			left = mid + 1
+		} else {
+			right = mid
+		}
+	}
+	return left, nil
+}
+
+// findExpired returns the labels in `labels` whose last update was published
+// in a log entry that's expired with respect to `rightmost`, the timestamp of
+// the rightmost log entry. The `found` argument is the number of expired labels
+// that have already been found in this batch. It also returns the number of
+// labels that were checked before the batch was filled.
+func (r *Reaper) findExpired(labels, rawIndices [][]byte, rightmost uint64, found int) ([][]byte, int, error) {
+	last := make([]uint64, len(rawIndices))
+	for i, raw := range rawIndices {
+		index, err := decodeIndex(raw)
+		if err != nil {
+			return nil, 0, err
+		} else if len(index) == 0 {
+			return nil, 0, errors.New("stored label index is empty")
+		}
+		last[i] = index[len(index)-1]
+	}
+	timestamps, err := r.tree.getTimestamps(last)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var out [][]byte
+	for i, label := range labels {
+		if r.full(found + len(out)) {
+			return out, i, nil
+		} else if r.tree.config.IsExpired(timestamps[last[i]], rightmost) {
+			out = append(out, label)
+		}
+	}
+	return out, len(labels), nil
+}
+
+// full returns true if a batch of `count` labels is full.
+func (r *Reaper) full(count int) bool {
+	return r.config.MaxBatchSize > 0 && count >= r.config.MaxBatchSize
+}
+
+// getTimestamps returns the timestamps of the log entries at the given
+// positions, keyed by position.
+func (t *Tree) getTimestamps(positions []uint64) (map[uint64]uint64, error) {
+	dedup := make(map[uint64]struct{})
+	keys := make([]uint64, 0, len(positions))
+	for _, pos := range positions {
+		if _, ok := dedup[pos]; !ok {
+			dedup[pos] = struct{}{}
+			keys = append(keys, pos)
+		}
+	}
+	results, err := t.tx.BatchGet(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[uint64]uint64, len(keys))
+	for _, pos := range keys {
+		raw, ok := results[pos]
+		if !ok {
+			return nil, errors.New("expected log entry not found")
+		}
+		buf, err := structs.NewDecoder(raw, structs.DefaultLimits)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := structs.NewLogEntry(t.config.Suite, buf)
+		if err != nil {
+			return nil, err
+		} else if buf.Len() != 0 {
+			return nil, errors.New("unexpected data appended to log entry")
+		}
+		out[pos] = entry.Timestamp
+	}
+	return out, nil
+}
+
+// successor returns the smallest label that is greater than `label`.
+func successor(label []byte) []byte {
+	out := make([]byte, len(label)+1)
+	copy(out, label)
+	return out
+}
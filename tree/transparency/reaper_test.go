@@ -0,0 +1,201 @@
+package transparency
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Bren2010/katie/db/memory"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/test"
+)
+
+func newReaperTree(t *testing.T) (*Tree, *memory.TransparencyStore, *test.Clock) {
+	clock := test.NewClock()
+	config := test.Config(t)
+	config.Clock = clock
+	config.MaximumLifetime = 7 * 86400 * 1000
+
+	store := memory.NewTransparencyStore()
+	tree, err := NewTree(config, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, store, clock
+}
+
+func mutateLabels(t *testing.T, tree *Tree, labels ...string) {
+	t.Helper()
+	var add []LabelValue
+	for _, label := range labels {
+		add = append(add, LabelValue{Label: []byte(label), Value: structs.UpdateValue{Value: []byte("value")}})
+	}
+	if _, err := tree.Mutate(add, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReaper(t *testing.T) {
+	tree, store, clock := newReaperTree(t)
+	reaper := NewReaper(tree, ReaperConfig{})
+
+	mutateLabels(t, tree, "a", "b")
+	clock.Advance(24 * time.Hour)
+	mutateLabels(t, tree, "c")
+
+	// Nothing has expired yet, so no log entry is created.
+	update, err := reaper.Reap()
+	if err != nil {
+		t.Fatal(err)
+	} else if update != nil {
+		t.Fatal("unexpected update returned")
+	} else if len(store.LogEntries) != 2 {
+		t.Fatal("unexpected number of log entries written")
+	}
+
+	// Once the first log entry has expired, the labels that were last updated
+	// in it are removed.
+	clock.Advance(6*24*time.Hour + 12*time.Hour)
+	mutateLabels(t, tree, "d")
+	update, err = reaper.Reap()
+	if err != nil {
+		t.Fatal(err)
+	} else if update == nil || len(update.Removed) != 2 {
+		t.Fatal("unexpected update returned")
+	} else if len(store.LogEntries) != 4 {
+		t.Fatal("unexpected number of log entries written")
+	} else if len(store.Indices) != 2 {
+		t.Fatal("unexpected number of indices")
+	}
+	indices, err := store.BatchGetIndex([][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	if err != nil {
+		t.Fatal(err)
+	} else if indices[0] != nil || indices[1] != nil || indices[2] == nil {
+		t.Fatal("unexpected labels removed")
+	}
+
+	update, err = reaper.Reap()
+	if err != nil {
+		t.Fatal(err)
+	} else if update != nil {
+		t.Fatal("unexpected update returned")
+	}
+}
+
+func TestReaperBatches(t *testing.T) {
+	tree, store, clock := newReaperTree(t)
+	reaper := NewReaper(tree, ReaperConfig{ScanSize: 1, MaxBatchSize: 2})
+
+	mutateLabels(t, tree, "a", "b", "c", "d", "e")
+	clock.Advance(8 * 24 * time.Hour)
+	mutateLabels(t, tree, "f")
+
+	for i, remaining := range []int{4, 2, 1} {
+		if _, err := reaper.Reap(); err != nil {
+			t.Fatal(err)
+		} else if len(store.Indices) != remaining {
+			t.Fatalf("unexpected number of indices after %d reaps: %d", i+1, len(store.Indices))
+		}
+	}
+	if len(store.LogEntries) != 5 {
+		t.Fatal("unexpected number of log entries written")
+	}
+}
+
+func TestMutateSkipIneligible(t *testing.T) {
+	tree, store, _ := newReaperTree(t)
+
+	for i := range 3 {
+		mutateLabels(t, tree, fmt.Sprint(i), "label")
+	}
+
+	// The label was modified too recently to be removed, so it's skipped and
+	// no log entry is created.
+	update, err := tree.mutate(nil, [][]byte{[]byte("label")}, true)
+	if err != nil {
+		t.Fatal(err)
+	} else if update != nil {
+		t.Fatal("unexpected update returned")
+	} else if len(store.LogEntries) != 3 || len(store.Indices) != 4 {
+		t.Fatal("unexpected data written")
+	}
+}
+
+func TestReaperCollectGarbage(t *testing.T) {
+	tree, store, clock := newReaperTree(t)
+	reaper := NewReaper(tree, ReaperConfig{})
+
+	for _, label := range []string{"a", "b", "c"} {
+		mutateLabels(t, tree, label)
+	}
+	clock.Advance(3 * 24 * time.Hour)
+	mutateLabels(t, tree, "d")
+	mutateLabels(t, tree, "e")
+	clock.Advance(4*24*time.Hour + 12*time.Hour)
+	mutateLabels(t, tree, "f")
+
+	// The first three log entries have expired. The prefix trees of the first
+	// two are dropped, while the third is kept for proving version positions.
+	tiles := len(store.PrefixStore().(*memory.PrefixStore).Data)
+	stats, err := reaper.CollectGarbage()
+	if err != nil {
+		t.Fatal(err)
+	} else if stats.Tiles == 0 || stats.Tiles != tiles-len(store.PrefixStore().(*memory.PrefixStore).Data) {
+		t.Fatal("unexpected number of tiles deleted")
+	}
+	if stats, err := reaper.CollectGarbage(); err != nil {
+		t.Fatal(err)
+	} else if stats.Tiles != 0 {
+		t.Fatal("unexpected tiles deleted")
+	} else if store.Collected != 3 {
+		t.Fatal("unexpected collected version stored")
+	}
+
+	// Every label can still be searched for, since searches only look at the
+	// prefix trees of unexpired log entries, and the tree can still be
+	// mutated.
+	ctx := context.Background()
+	search := func(tree *Tree, labels ...string) {
+		t.Helper()
+		ver := uint32(0)
+		for _, label := range labels {
+			if _, err := tree.Search(ctx, &structs.SearchRequest{Label: []byte(label)}); err != nil {
+				t.Fatal(err)
+			} else if _, err := tree.Search(ctx, &structs.SearchRequest{Label: []byte(label), Version: &ver}); err != nil {
+				t.Fatal(err)
+			} else if _, err := tree.History(ctx, &structs.HistoryRequest{Label: []byte(label)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	search(tree, "d", "e", "f")
+	mutateLabels(t, tree, "d", "g")
+	search(tree, "d", "g")
+
+	// A Reaper for a reloaded tree continues from the stored position. Starting
+	// over would fail, since the tiles of the dropped prefix trees are gone.
+	reloaded, err := NewTree(tree.config, store, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reaper = NewReaper(reloaded, ReaperConfig{})
+	if stats, err := reaper.CollectGarbage(); err != nil {
+		t.Fatal(err)
+	} else if stats.Tiles != 0 {
+		t.Fatal("unexpected tiles deleted")
+	}
+
+	// Once more log entries expire, only the tiles that they replaced need to
+	// be compared, and the rest are kept.
+	clock.Advance(4 * 24 * time.Hour)
+	mutateLabels(t, reloaded, "h")
+	if stats, err := reaper.CollectGarbage(); err != nil {
+		t.Fatal(err)
+	} else if stats.Tiles == 0 {
+		t.Fatal("expected tiles to be deleted")
+	} else if store.Collected != 5 {
+		t.Fatal("unexpected collected version stored")
+	}
+	search(reloaded, "f", "g", "h")
+}
@@ -0,0 +1,256 @@
+package transparency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Bren2010/katie/tree/transparency/structs"
+)
+
+// ErrSequencerClosed is returned by Sequencer when the channel of
+// UpdateRequests has been closed and there are no more requests to sequence.
+var ErrSequencerClosed = errors.New("update request channel is closed")
+
+// SequencerConfig controls how UpdateRequests are grouped into log entries.
+type SequencerConfig struct {
+	// MaxBatchSize is the maximum number of UpdateRequests that will be
+	// sequenced in a single log entry. If zero, there is no limit.
+	MaxBatchSize int
+
+	// MaxBatchDelay is how long to wait for additional UpdateRequests after
+	// the first request of a batch is received. If zero, only the requests
+	// that are immediately available are sequenced together.
+	MaxBatchDelay time.Duration
+}
+
+// Sequencer is the central goroutine that receives UpdateRequests from the
+// `updater` channel of a Transparency Tree and applies them to the tree with
+// Mutate.
+//
+// Requests are collected into batches, and each batch is sequenced into a
+// single log entry. Requests in the same batch for the same label are merged:
+// their values are assigned consecutive versions in the order that the
+// requests were received, and each requester is sent the version assigned to
+// its first value. Requests without any Values are rejected, since there is
+// nothing to sequence.
+type Sequencer struct {
+	tree   *Tree
+	ch     <-chan UpdateRequest
+	config SequencerConfig
+
+	pending []UpdateRequest
+	closed  bool
+}
+
+// NewSequencer returns a new Sequencer that applies the UpdateRequests received
+// over `ch` to `tree`. The tree must not be used concurrently by anything else.
+func NewSequencer(tree *Tree, ch <-chan UpdateRequest, config SequencerConfig) *Sequencer {
+	return &Sequencer{tree: tree, ch: ch, config: config}
+}
+
+// Next blocks until it has collected a batch of UpdateRequests, sequences them
+// in a new log entry, and informs the requesters of the outcome. It returns the
+// AuditorUpdate structure for the new log entry, which should be forwarded to
+// the Third-Party Auditor if there is one.
+//
+// If the new log entry could not be created, the Response channel of every
+// request in the batch is closed and an error is returned. ErrSequencerClosed
+// is returned once the channel of UpdateRequests is closed and drained.
+func (s *Sequencer) Next(ctx context.Context) (*structs.AuditorUpdate, error) {
+	for {
+		batch, err := s.collect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		add, accepted, err := s.prepare(batch)
+		if err != nil {
+			for _, req := range batch {
+				respond(req, nil)
+			}
+			return nil, err
+		} else if len(accepted) == 0 {
+			continue
+		}
+
+		update, err := s.tree.Mutate(add, nil)
+		if err != nil {
+			for _, acc := range accepted {
+				respond(acc.req, nil)
+			}
+			return nil, err
+		}
+		pos := s.tree.TreeHead().TreeSize - 1
+		for _, acc := range accepted {
+			respond(acc.req, &UpdateResult{Position: pos, Version: acc.version})
+		}
+
+		return update, nil
+	}
+}
+
+// collect waits for and returns the next batch of UpdateRequests.
+func (s *Sequencer) collect(ctx context.Context) ([]UpdateRequest, error) {
+	var batch []UpdateRequest
+	full := func() bool {
+		return s.config.MaxBatchSize > 0 && len(batch) >= s.config.MaxBatchSize
+	}
+	push := func(req UpdateRequest) {
+		if full() {
+			s.pending = append(s.pending, req)
+			return
+		}
+		batch = append(batch, req)
+	}
+
+	// Start the batch with any requests that were held back previously.
+	pending := s.pending
+	s.pending = nil
+	for _, req := range pending {
+		push(req)
+	}
+
+	// Wait for the first request of the batch, if necessary.
+	if len(batch) == 0 {
+		if s.closed {
+			return nil, ErrSequencerClosed
+		}
+		select {
+		case req, ok := <-s.ch:
+			if !ok {
+				s.closed = true
+				return nil, ErrSequencerClosed
+			}
+			push(req)
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	// If there's no delay configured, take only the requests that are
+	// immediately available.
+	if s.config.MaxBatchDelay == 0 {
+		for !s.closed && !full() {
+			select {
+			case req, ok := <-s.ch:
+				if !ok {
+					s.closed = true
+				} else {
+					push(req)
+				}
+			default:
+				return batch, nil
+			}
+		}
+		return batch, nil
+	}
+
+	// Otherwise, collect further requests until the batch is full or the
+	// deadline passes.
+	timer := time.NewTimer(s.config.MaxBatchDelay)
+	defer timer.Stop()
+
+	for !s.closed && !full() {
+		select {
+		case req, ok := <-s.ch:
+			if !ok {
+				s.closed = true
+			} else {
+				push(req)
+			}
+		case <-timer.C:
+			return batch, nil
+		case <-ctx.Done():
+			s.pending = append(batch, s.pending...)
+			return nil, ctx.Err()
+		}
+	}
+	return batch, nil
+}
+
+// acceptedRequest is an UpdateRequest that was accepted by prepare, along with
+// the version assigned to its first value.
+type acceptedRequest struct {
+	req     UpdateRequest
+	version uint32
+}
+
+// prepare converts a batch of UpdateRequests into the set of label-value pairs
+// to pass to Mutate. The values of requests for the same label are assigned
+// consecutive versions, in the order that the requests appear in the batch.
+// Tombstone versions are inserted for any requests where the requested
+// starting version is greater than the version that would be assigned
+// naturally, except in Third-Party Management mode. Requests that can not be
+// satisfied, or that have no values, are rejected. It returns the label-value
+// pairs and the set of requests that were accepted.
+func (s *Sequencer) prepare(batch []UpdateRequest) ([]LabelValue, []acceptedRequest, error) {
+	var labels [][]byte
+	next := make(map[string]uint64) // next is the next version of each label.
+	for _, req := range batch {
+		labelStr := fmt.Sprintf("%x", req.Label)
+		if _, ok := next[labelStr]; !ok {
+			next[labelStr] = 0
+			labels = append(labels, req.Label)
+		}
+	}
+	indices, err := s.tree.batchGetIndex(labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, label := range labels {
+		next[fmt.Sprintf("%x", label)] = uint64(len(indices[i]))
+	}
+
+	var (
+		add      []LabelValue
+		accepted []acceptedRequest
+	)
+	for _, req := range batch {
+		if len(req.Values) == 0 {
+			respond(req, nil)
+			continue
+		}
+		labelStr := fmt.Sprintf("%x", req.Label)
+		ver := next[labelStr]
+		if req.StartingVersion != nil {
+			start := uint64(*req.StartingVersion)
+			if start < ver || (start > ver && !s.padding()) {
+				respond(req, nil)
+				continue
+			}
+			for ; ver < start; ver++ {
+				add = append(add, LabelValue{
+					Label: req.Label,
+					Value: structs.UpdateValue{Value: []byte{}},
+				})
+			}
+		}
+		for _, val := range req.Values {
+			add = append(add, LabelValue{Label: req.Label, Value: val})
+		}
+		accepted = append(accepted, acceptedRequest{req: req, version: uint32(ver)})
+		next[labelStr] = ver + uint64(len(req.Values))
+	}
+
+	return add, accepted, nil
+}
+
+// padding returns true if tombstone versions may be inserted to align version
+// counters. In Third-Party Management mode, clients verify the Service
+// Operator's signature on every version of a label, so the Service Operator
+// must sign and submit any tombstone versions itself.
+func (s *Sequencer) padding() bool {
+	return s.tree.config.Mode != structs.ThirdPartyManagement
+}
+
+// respond sends `res` over the Response channel of `req`, if `res` is not nil,
+// and then closes the channel.
+func respond(req UpdateRequest, res *UpdateResult) {
+	if req.Response == nil {
+		return
+	} else if res != nil {
+		req.Response <- *res
+	}
+	close(req.Response)
+}
@@ -0,0 +1,340 @@
+package transparency
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Bren2010/katie/crypto/suites"
+	"github.com/Bren2010/katie/db/memory"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/test"
+)
+
+func newTestSequencer(t *testing.T, config SequencerConfig) (*memory.TransparencyStore, *Tree, chan UpdateRequest, *Sequencer) {
+	return newTestSequencerWithConfig(t, test.Config(t), config)
+}
+
+func newTestSequencerWithConfig(
+	t *testing.T,
+	treeConfig structs.PrivateConfig,
+	config SequencerConfig,
+) (*memory.TransparencyStore, *Tree, chan UpdateRequest, *Sequencer) {
+	store := memory.NewTransparencyStore()
+	ch := make(chan UpdateRequest, 10)
+
+	tree, err := NewTree(treeConfig, store, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, tree, ch, NewSequencer(tree, ch, config)
+}
+
+func updateRequest(label string, startingVersion *uint32, values ...string) (UpdateRequest, chan UpdateResult) {
+	res := make(chan UpdateResult, 1)
+	req := UpdateRequest{Label: []byte(label), StartingVersion: startingVersion, Response: res}
+	for _, val := range values {
+		req.Values = append(req.Values, structs.UpdateValue{Value: []byte(val)})
+	}
+	return req, res
+}
+
+func expectPosition(t *testing.T, res chan UpdateResult, expected uint64) {
+	t.Helper()
+	result, ok := <-res
+	if !ok {
+		t.Fatal("request was rejected")
+	} else if result.Position != expected {
+		t.Fatalf("unexpected position: %v != %v", result.Position, expected)
+	}
+}
+
+func expectResult(t *testing.T, res chan UpdateResult, expected UpdateResult) {
+	t.Helper()
+	result, ok := <-res
+	if !ok {
+		t.Fatal("request was rejected")
+	} else if result != expected {
+		t.Fatalf("unexpected result: %+v != %+v", result, expected)
+	}
+}
+
+func expectRejected(t *testing.T, res chan UpdateResult) {
+	t.Helper()
+	if _, ok := <-res; ok {
+		t.Fatal("request was unexpectedly accepted")
+	}
+}
+
+func TestSequencerBatch(t *testing.T) {
+	store, tree, ch, seq := newTestSequencer(t, SequencerConfig{})
+
+	req1, res1 := updateRequest("a", nil, "a0", "a1")
+	req2, res2 := updateRequest("b", nil, "b0")
+	req3, res3 := updateRequest("a", nil, "a2")
+	ch <- req1
+	ch <- req2
+	ch <- req3
+
+	// All of the requests are sequenced in one log entry, with the two
+	// requests for "a" merged in the order they were received.
+	update, err := seq.Next(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	} else if len(update.Added) != 4 {
+		t.Fatal("unexpected number of prefix tree additions")
+	}
+	expectResult(t, res1, UpdateResult{Position: 0, Version: 0})
+	expectResult(t, res2, UpdateResult{Position: 0, Version: 0})
+	expectResult(t, res3, UpdateResult{Position: 0, Version: 2})
+
+	if len(store.LogEntries) != 1 {
+		t.Fatal("unexpected number of log entries written")
+	} else if len(store.Versions) != 4 {
+		t.Fatal("unexpected number of label versions")
+	}
+	for ver, expected := range []string{"a0", "a1", "a2"} {
+		stored, err := tree.getVersion([]byte("a"), uint32(ver))
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(stored.Value.Value, []byte(expected)) {
+			t.Fatal("unexpected data stored")
+		}
+	}
+}
+
+func TestSequencerMergeStartingVersion(t *testing.T) {
+	_, tree, ch, seq := newTestSequencer(t, SequencerConfig{})
+
+	// Starting versions of merged requests are checked against the versions
+	// assigned to the requests for the same label before them in the batch.
+	one, three := uint32(1), uint32(3)
+	req1, res1 := updateRequest("a", nil, "a0")
+	req2, res2 := updateRequest("a", &one, "a1")
+	req3, res3 := updateRequest("a", &one, "x")
+	req4, res4 := updateRequest("a", &three, "a3")
+	ch <- req1
+	ch <- req2
+	ch <- req3
+	ch <- req4
+	if _, err := seq.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectResult(t, res1, UpdateResult{Position: 0, Version: 0})
+	expectResult(t, res2, UpdateResult{Position: 0, Version: 1})
+	expectRejected(t, res3)
+	expectResult(t, res4, UpdateResult{Position: 0, Version: 3})
+
+	for ver, expected := range []string{"a0", "a1", "", "a3"} {
+		stored, err := tree.getVersion([]byte("a"), uint32(ver))
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(stored.Value.Value, []byte(expected)) {
+			t.Fatal("unexpected data stored")
+		}
+	}
+}
+
+func TestSequencerMaxBatchSize(t *testing.T) {
+	_, _, ch, seq := newTestSequencer(t, SequencerConfig{MaxBatchSize: 2})
+
+	var responses []chan UpdateResult
+	for _, label := range []string{"a", "b", "c"} {
+		req, res := updateRequest(label, nil, label)
+		ch <- req
+		responses = append(responses, res)
+	}
+
+	for range 2 {
+		if _, err := seq.Next(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	expectPosition(t, responses[0], 0)
+	expectPosition(t, responses[1], 0)
+	expectPosition(t, responses[2], 1)
+}
+
+func TestSequencerMaxBatchDelay(t *testing.T) {
+	_, _, ch, seq := newTestSequencer(t, SequencerConfig{MaxBatchDelay: 50 * time.Millisecond})
+
+	req1, res1 := updateRequest("a", nil, "a0")
+	req2, res2 := updateRequest("b", nil, "b0")
+	ch <- req1
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ch <- req2
+	}()
+
+	if _, err := seq.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectPosition(t, res1, 0)
+	expectPosition(t, res2, 0)
+}
+
+func TestSequencerStartingVersion(t *testing.T) {
+	_, tree, ch, seq := newTestSequencer(t, SequencerConfig{})
+
+	// Request that the first value be assigned version 2, which requires two
+	// tombstone versions to be created.
+	two, one := uint32(2), uint32(1)
+	req1, res1 := updateRequest("a", &two, "a2")
+	ch <- req1
+	if _, err := seq.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectPosition(t, res1, 0)
+
+	for ver, expected := range []string{"", "", "a2"} {
+		stored, err := tree.getVersion([]byte("a"), uint32(ver))
+		if err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(stored.Value.Value, []byte(expected)) {
+			t.Fatal("unexpected data stored")
+		}
+	}
+
+	// Request a starting version that's already been assigned. This request
+	// is rejected, but doesn't prevent the other request from being sequenced.
+	req2, res2 := updateRequest("a", &one, "a1")
+	req3, res3 := updateRequest("b", nil, "b0")
+	ch <- req2
+	ch <- req3
+	if _, err := seq.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectRejected(t, res2)
+	expectPosition(t, res3, 1)
+}
+
+// signedRequest returns an UpdateRequest where each value is signed by `priv`,
+// as the Service Operator does in Third-Party Management mode.
+func signedRequest(
+	t *testing.T,
+	config *structs.PublicConfig,
+	priv suites.SigningPrivateKey,
+	label string,
+	startingVersion uint32,
+	values ...string,
+) (UpdateRequest, chan UpdateResult) {
+	req, res := updateRequest(label, &startingVersion, values...)
+	for i, val := range req.Values {
+		tbs, err := structs.Marshal(&structs.UpdateTBS{
+			Config:  config,
+			Label:   req.Label,
+			Version: startingVersion + uint32(i),
+			Value:   val.Value,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := priv.Sign(tbs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Values[i].Signature = sig
+	}
+	return req, res
+}
+
+func TestSequencerManagedStartingVersion(t *testing.T) {
+	config, leafKey := test.ConfigWithManager(t)
+	_, tree, ch, seq := newTestSequencerWithConfig(t, config, SequencerConfig{})
+
+	client, err := NewClient(config.Public(), memory.NewClientStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The sequencer can't sign tombstone versions, so a request that leaves a
+	// gap in the label's versions is rejected.
+	req1, res1 := signedRequest(t, config.Public(), leafKey, "a", 2, "a2")
+	req2, res2 := signedRequest(t, config.Public(), leafKey, "b", 0, "b0")
+	ch <- req1
+	ch <- req2
+	if _, err := seq.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectRejected(t, res1)
+	expectPosition(t, res2, 0)
+
+	// The Service Operator fills the gap with its own signed tombstones
+	// instead, which clients are able to verify.
+	req3, res3 := signedRequest(t, config.Public(), leafKey, "a", 0, "", "", "a2")
+	ch <- req3
+	if _, err := seq.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectPosition(t, res3, 1)
+
+	for ver, expected := range []string{"", "", "a2"} {
+		req, verify, err := client.FixedVersionSearch([]byte("a"), uint32(ver))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := tree.Search(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := verify(res); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(res.Value.Value, []byte(expected)) {
+			t.Fatal("unexpected value returned")
+		}
+	}
+}
+
+func TestSequencerNoValues(t *testing.T) {
+	store, _, ch, seq := newTestSequencer(t, SequencerConfig{})
+
+	// A request without any values is rejected, without creating tombstone
+	// versions or a log entry for it. The sequencer then waits for the next
+	// request.
+	two := uint32(2)
+	req1, res1 := updateRequest("a", &two)
+	req2, res2 := updateRequest("b", nil, "b0")
+	ch <- req1
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		ch <- req2
+	}()
+	if _, err := seq.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectRejected(t, res1)
+	expectPosition(t, res2, 0)
+
+	if len(store.LogEntries) != 1 {
+		t.Fatal("unexpected number of log entries written")
+	} else if len(store.Versions) != 1 {
+		t.Fatal("unexpected number of label versions")
+	}
+}
+
+func TestSequencerClosed(t *testing.T) {
+	_, _, ch, seq := newTestSequencer(t, SequencerConfig{})
+
+	req, res := updateRequest("a", nil, "a0")
+	ch <- req
+	close(ch)
+
+	if _, err := seq.Next(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	expectPosition(t, res, 0)
+
+	if _, err := seq.Next(context.Background()); err != ErrSequencerClosed {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSequencerCancel(t *testing.T) {
+	_, _, _, seq := newTestSequencer(t, SequencerConfig{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := seq.Next(ctx); err != context.Canceled {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -9,11 +9,11 @@ import (
 )
 
 type IndexedLogEntry struct {
-	Position uint64
+	Position uint64 `json:"position"`
 	LogEntry
 }
 
-func NewIndexedLogEntry(cs suites.CipherSuite, buf *bytes.Buffer) (*IndexedLogEntry, error) {
+func NewIndexedLogEntry(cs suites.CipherSuite, buf *Decoder) (*IndexedLogEntry, error) {
 	pos, err := readNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
@@ -31,13 +31,13 @@ func (ile *IndexedLogEntry) Marshal(buf *bytes.Buffer) error {
 }
 
 type ClientState struct {
-	TreeHead        TreeHead
-	AuditorTreeHead *AuditorTreeHead
-	FullSubtrees    [][]byte
-	LogEntries      map[uint64]LogEntry
+	TreeHead        TreeHead            `json:"tree_head"`
+	AuditorTreeHead *AuditorTreeHead    `json:"auditor_tree_head,omitempty"`
+	FullSubtrees    [][]byte            `json:"full_subtrees,omitempty"`
+	LogEntries      map[uint64]LogEntry `json:"log_entries,omitempty"`
 }
 
-func NewClientState(config *PublicConfig, buf *bytes.Buffer) (*ClientState, error) {
+func NewClientState(config *PublicConfig, buf *Decoder) (*ClientState, error) {
 	treeHead, err := NewTreeHead(buf)
 	if err != nil {
 		return nil, err
@@ -54,7 +54,7 @@ func NewClientState(config *PublicConfig, buf *bytes.Buffer) (*ClientState, erro
 		return nil, err
 	}
 
-	entrySlice, err := readFuncSlice[uint8](buf, func(buf *bytes.Buffer) (*IndexedLogEntry, error) {
+	entrySlice, err := readFuncSlice[uint8](buf, func(buf *Decoder) (*IndexedLogEntry, error) {
 		return NewIndexedLogEntry(config.Suite, buf)
 	})
 	if err != nil {
@@ -97,17 +97,17 @@ func (cs *ClientState) Marshal(buf *bytes.Buffer) error {
 }
 
 type LabelOwnerState struct {
-	Starting      uint64
-	VerAtStarting int
-	UpcomingVers  []uint64
+	Starting      uint64   `json:"starting"`
+	VerAtStarting int      `json:"ver_at_starting"`
+	UpcomingVers  []uint64 `json:"upcoming_vers,omitempty"`
 }
 
-func NewLabelOwnerState(buf *bytes.Buffer) (*LabelOwnerState, error) {
+func NewLabelOwnerState(buf *Decoder) (*LabelOwnerState, error) {
 	starting, err := readNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
 	}
-	verAtStarting, err := readNumeric[int](buf)
+	verAtStarting, err := readNumeric[int64](buf)
 	if err != nil {
 		return nil, err
 	}
@@ -117,24 +117,24 @@ func NewLabelOwnerState(buf *bytes.Buffer) (*LabelOwnerState, error) {
 	}
 	return &LabelOwnerState{
 		Starting:      starting,
-		VerAtStarting: verAtStarting,
+		VerAtStarting: int(verAtStarting),
 		UpcomingVers:  upcomingVers,
 	}, nil
 }
 
 func (los *LabelOwnerState) Marshal(buf *bytes.Buffer) error {
 	writeNumeric(buf, los.Starting)
-	writeNumeric(buf, los.VerAtStarting)
+	writeNumeric(buf, int64(los.VerAtStarting))
 	return writeNumericSlice[uint32](buf, los.UpcomingVers, "upcoming versions")
 }
 
 type RetainedVersion struct {
-	Version    uint32
-	VrfOutput  []byte
-	Commitment []byte
+	Version    uint32 `json:"version"`
+	VrfOutput  []byte `json:"vrf_output,omitempty"`
+	Commitment []byte `json:"commitment,omitempty"`
 }
 
-func NewRetainedVersion(cs suites.CipherSuite, buf *bytes.Buffer) (*RetainedVersion, error) {
+func NewRetainedVersion(cs suites.CipherSuite, buf *Decoder) (*RetainedVersion, error) {
 	version, err := readNumeric[uint32](buf)
 	if err != nil {
 		return nil, err
@@ -171,12 +171,12 @@ func (rv *RetainedVersion) Marshal(buf *bytes.Buffer) error {
 }
 
 type ClientLabelState struct {
-	Contact  []MonitorMapEntry
-	Owner    *LabelOwnerState
-	Versions []RetainedVersion
+	Contact  []MonitorMapEntry `json:"contact,omitempty"`
+	Owner    *LabelOwnerState  `json:"owner,omitempty"`
+	Versions []RetainedVersion `json:"versions,omitempty"`
 }
 
-func NewClientLabelState(cs suites.CipherSuite, buf *bytes.Buffer) (*ClientLabelState, error) {
+func NewClientLabelState(cs suites.CipherSuite, buf *Decoder) (*ClientLabelState, error) {
 	contact, err := readFuncSlice[uint32](buf, NewMonitorMapEntry)
 	if err != nil {
 		return nil, err
@@ -192,9 +192,12 @@ func NewClientLabelState(cs suites.CipherSuite, buf *bytes.Buffer) (*ClientLabel
 		}
 	}
 
-	versions, err := readFuncSlice[uint32](buf, func(buf *bytes.Buffer) (*RetainedVersion, error) {
+	versions, err := readFuncSlice[uint32](buf, func(buf *Decoder) (*RetainedVersion, error) {
 		return NewRetainedVersion(cs, buf)
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	return &ClientLabelState{Contact: contact, Owner: owner, Versions: versions}, nil
 }
@@ -204,7 +207,9 @@ func (cls *ClientLabelState) Marshal(buf *bytes.Buffer) error {
 		return err
 	}
 	if writeOptional(buf, cls.Owner != nil) {
-		return cls.Owner.Marshal(buf)
+		if err := cls.Owner.Marshal(buf); err != nil {
+			return err
+		}
 	}
 	return writeMarshalSlice[uint32](buf, cls.Versions, "retained versions")
 }
@@ -2,6 +2,7 @@ package structs
 
 import (
 	"bytes"
+	"time"
 
 	"github.com/Bren2010/katie/crypto/suites"
 	"github.com/Bren2010/katie/crypto/vrf"
@@ -15,6 +16,11 @@ const (
 	ThirdPartyAuditing
 )
 
+// Clock is a source of the current time.
+type Clock interface {
+	Now() time.Time
+}
+
 type Config struct {
 	Suite suites.CipherSuite
 	Mode  DeploymentMode
@@ -31,6 +37,19 @@ type Config struct {
 	MaxBehind                  uint64
 	ReasonableMonitoringWindow uint64
 	MaximumLifetime            uint64 // Set to 0 if there is not one.
+
+	// Clock is used to timestamp new log entries and to check the freshness of
+	// the tree. If nil, the system clock is used. It is not part of the encoded
+	// configuration.
+	Clock Clock
+}
+
+// Now returns the current time, in milliseconds since the Unix epoch.
+func (c *Config) Now() uint64 {
+	if c.Clock == nil {
+		return uint64(time.Now().UnixMilli())
+	}
+	return uint64(c.Clock.Now().UnixMilli())
 }
 
 func (c *Config) IsExpired(ts, rightmost uint64) bool {
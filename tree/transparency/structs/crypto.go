@@ -8,10 +8,10 @@ import (
 )
 
 type UpdateSuffix struct {
-	Signature []byte
+	Signature []byte `json:"signature,omitempty"`
 }
 
-func NewUpdateSuffix(config *PublicConfig, buf *bytes.Buffer) (*UpdateSuffix, error) {
+func NewUpdateSuffix(config *PublicConfig, buf *Decoder) (*UpdateSuffix, error) {
 	if config.Mode != ThirdPartyManagement {
 		return &UpdateSuffix{}, nil
 	}
@@ -30,12 +30,12 @@ func (us *UpdateSuffix) Marshal(buf *bytes.Buffer) error {
 }
 
 type UpdateValue struct {
-	Value []byte
+	Value []byte `json:"value,omitempty"`
 	UpdateSuffix
 }
 
-func NewUpdateValue(config *PublicConfig, buf *bytes.Buffer) (*UpdateValue, error) {
-	value, err := readBytes[uint32](buf)
+func NewUpdateValue(config *PublicConfig, buf *Decoder) (*UpdateValue, error) {
+	value, err := readValue(buf)
 	if err != nil {
 		return nil, err
 	}
@@ -98,11 +98,11 @@ func (vi *VrfInput) Marshal(buf *bytes.Buffer) error {
 }
 
 type LogEntry struct {
-	Timestamp  uint64
-	PrefixTree []byte
+	Timestamp  uint64 `json:"timestamp"`
+	PrefixTree []byte `json:"prefix_tree,omitempty"`
 }
 
-func NewLogEntry(cs suites.CipherSuite, buf *bytes.Buffer) (*LogEntry, error) {
+func NewLogEntry(cs suites.CipherSuite, buf *Decoder) (*LogEntry, error) {
 	timestamp, err := readNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
@@ -131,11 +131,11 @@ func (le *LogEntry) Hash(cs suites.CipherSuite) ([]byte, error) {
 }
 
 type BinaryLadderStep struct {
-	Proof      []byte
-	Commitment []byte
+	Proof      []byte `json:"proof,omitempty"`
+	Commitment []byte `json:"commitment,omitempty"`
 }
 
-func NewBinaryLadderStep(cs suites.CipherSuite, buf *bytes.Buffer) (*BinaryLadderStep, error) {
+func NewBinaryLadderStep(cs suites.CipherSuite, buf *Decoder) (*BinaryLadderStep, error) {
 	proof := make([]byte, cs.VrfProofSize())
 	if _, err := io.ReadFull(buf, proof); err != nil {
 		return nil, err
@@ -163,11 +163,11 @@ func (bls *BinaryLadderStep) Marshal(buf *bytes.Buffer) error {
 }
 
 type OpeningAndValue struct {
-	Opening []byte
-	Value   UpdateValue
+	Opening []byte      `json:"opening,omitempty"`
+	Value   UpdateValue `json:"value"`
 }
 
-func NewOpeningAndValue(config *PublicConfig, buf *bytes.Buffer) (*OpeningAndValue, error) {
+func NewOpeningAndValue(config *PublicConfig, buf *Decoder) (*OpeningAndValue, error) {
 	opening := make([]byte, config.Suite.CommitmentOpeningSize())
 	if _, err := io.ReadFull(buf, opening); err != nil {
 		return nil, err
@@ -185,11 +185,11 @@ func (oav *OpeningAndValue) Marshal(buf *bytes.Buffer) error {
 }
 
 type LabelValue struct {
-	Value []byte
+	Value []byte `json:"value,omitempty"`
 }
 
-func NewLabelValue(buf *bytes.Buffer) (*LabelValue, error) {
-	value, err := readBytes[uint32](buf)
+func NewLabelValue(buf *Decoder) (*LabelValue, error) {
+	value, err := readValue(buf)
 	if err != nil {
 		return nil, err
 	}
@@ -201,11 +201,11 @@ func (lv *LabelValue) Marshal(buf *bytes.Buffer) error {
 }
 
 type UpdateInfo struct {
-	Opening []byte
+	Opening []byte `json:"opening,omitempty"`
 	UpdateSuffix
 }
 
-func NewUpdateInfo(config *PublicConfig, buf *bytes.Buffer) (*UpdateInfo, error) {
+func NewUpdateInfo(config *PublicConfig, buf *Decoder) (*UpdateInfo, error) {
 	opening := make([]byte, config.Suite.CommitmentOpeningSize())
 	if _, err := io.ReadFull(buf, opening); err != nil {
 		return nil, err
@@ -0,0 +1,61 @@
+package structs
+
+import (
+	"bytes"
+	"errors"
+	"math"
+)
+
+// Limits bounds the resources used to decode a structure. Every length prefix
+// in an encoded structure is controlled by whoever produced it, so structures
+// received from an untrusted party should be decoded with limits that are no
+// greater than what an honest party would ever produce.
+type Limits struct {
+	MaxSize         int // Maximum size of the encoded structure, in bytes.
+	MaxLabelSize    int // Maximum length of a label, in bytes.
+	MaxValueSize    int // Maximum length of a label's value, in bytes.
+	MaxLadderSize   int // Maximum number of steps in a binary ladder.
+	MaxPrefixProofs int // Maximum number of prefix proofs in a CombinedTreeProof.
+
+	MaxBatchSearchEntries int // Maximum number of labels in a BatchSearchRequest.
+}
+
+// DefaultLimits are the limits used when no others are configured. They allow
+// any structure that fits in 16 MiB with values of up to 1 MiB.
+var DefaultLimits = Limits{
+	MaxSize:         16 * 1024 * 1024,
+	MaxLabelSize:    math.MaxUint8,
+	MaxValueSize:    1024 * 1024,
+	MaxLadderSize:   math.MaxUint16,
+	MaxPrefixProofs: math.MaxUint8,
+
+	MaxBatchSearchEntries: 100,
+}
+
+// Decoder wraps a buffer containing an encoded structure, and enforces a set
+// of Limits while the structure is decoded.
+type Decoder struct {
+	*bytes.Buffer
+	limits Limits
+}
+
+// NewDecoder returns a Decoder that reads from `raw`, or an error if `raw` is
+// larger than allowed by `limits`.
+func NewDecoder(raw []byte, limits Limits) (*Decoder, error) {
+	if len(raw) > limits.MaxSize {
+		return nil, errors.New("encoded structure is too large to decode")
+	}
+	return &Decoder{Buffer: bytes.NewBuffer(raw), limits: limits}, nil
+}
+
+// checkSize returns an error if `size` elements of at least `elemSize` bytes
+// each can not be read from the remaining data, or if `size` exceeds `limit`.
+// It is called before allocating memory for the elements.
+func (d *Decoder) checkSize(size, elemSize, limit int, name string) error {
+	if size > limit {
+		return errors.New(name + " is too long to decode")
+	} else if elemSize > 0 && size > d.Len()/elemSize {
+		return errors.New(name + " is longer than remaining data")
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package structs
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Bren2010/katie/crypto/suites"
+	"github.com/Bren2010/katie/tree/prefix"
+)
+
+func TestDecoderMaxSize(t *testing.T) {
+	limits := DefaultLimits
+	limits.MaxSize = 4
+
+	if _, err := NewDecoder(make([]byte, 4), limits); err != nil {
+		t.Fatal(err)
+	} else if _, err := NewDecoder(make([]byte, 5), limits); err == nil {
+		t.Fatal("expected error for oversized input")
+	}
+}
+
+func TestDecoderHostileLength(t *testing.T) {
+	limits := DefaultLimits
+	limits.MaxValueSize = math.MaxUint32
+
+	// A value that claims to be 4 GiB long, followed by very little data, must
+	// be rejected before the memory is allocated.
+	raw := []byte{0xff, 0xff, 0xff, 0xff, 1, 2, 3}
+	buf, err := NewDecoder(raw, limits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewLabelValue(buf); err == nil {
+		t.Fatal("expected error for truncated value")
+	}
+
+	buf, err = NewDecoder(raw, DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewLabelValue(buf); err == nil {
+		t.Fatal("expected error for oversized value")
+	}
+
+	// The same applies to slices of fixed-size elements.
+	buf, err = NewDecoder([]byte{0xff, 0xff, 0, 0}, DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewInclusionProof(suites.KTSha256P256{}, buf); err == nil {
+		t.Fatal("expected error for truncated inclusion proof")
+	}
+}
+
+func TestDecoderLabelSize(t *testing.T) {
+	raw, err := Marshal(&SearchRequest{Label: []byte("hello world")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := NewDecoder(raw, DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewSearchRequest(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	limits := DefaultLimits
+	limits.MaxLabelSize = 5
+	buf, err = NewDecoder(raw, limits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewSearchRequest(buf); err == nil {
+		t.Fatal("expected error for oversized label")
+	}
+}
+
+func TestDecoderPrefixProofs(t *testing.T) {
+	cs := suites.KTSha256P256{}
+
+	ctp := &CombinedTreeProof{PrefixProofs: make([]prefix.PrefixProof, 3)}
+	raw, err := Marshal(ctp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limits := DefaultLimits
+	limits.MaxPrefixProofs = 3
+	buf, err := NewDecoder(raw, limits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewCombinedTreeProof(cs, buf); err != nil {
+		t.Fatal(err)
+	}
+
+	limits.MaxPrefixProofs = 2
+	buf, err = NewDecoder(raw, limits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewCombinedTreeProof(cs, buf); err == nil {
+		t.Fatal("expected error for too many prefix proofs")
+	}
+}
+
+func TestDecoderPrefixProofLength(t *testing.T) {
+	// No timestamps, followed by a prefix proof that claims to contain 65535
+	// elements.
+	raw := []byte{0, 1, 0, 0xff, 0xff}
+	buf, err := NewDecoder(raw, DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewCombinedTreeProof(suites.KTSha256P256{}, buf); err == nil {
+		t.Fatal("expected error for truncated prefix proof")
+	}
+}
+
+func TestDecoderBatchSearchEntries(t *testing.T) {
+	req := &BatchSearchRequest{Entries: make([]BatchSearchEntry, 3)}
+	for i := range req.Entries {
+		req.Entries[i].Label = []byte("label")
+	}
+	raw, err := Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	limits := DefaultLimits
+	limits.MaxBatchSearchEntries = 3
+	buf, err := NewDecoder(raw, limits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewBatchSearchRequest(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	limits.MaxBatchSearchEntries = 2
+	buf, err = NewDecoder(raw, limits)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := NewBatchSearchRequest(buf); err == nil {
+		t.Fatal("expected error for too many batch search entries")
+	}
+}
@@ -0,0 +1,88 @@
+package structs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// Every structure that is sent over the wire, or that is stored by a client or
+// auditor, also has a canonical JSON encoding. It is intended for debugging
+// tools and JSON-based APIs, and maps to the binary encoding as follows:
+//
+//   - Structures are JSON objects, with each field named in snake_case, such
+//     as "full_tree_head" or "vrf_output". Fields are encoded in the order
+//     that they're declared, and there is no other whitespace.
+//   - Embedded structures, such as the UpdateSuffix of an UpdateValue, have
+//     their fields inlined into the enclosing object.
+//   - Integers are JSON numbers.
+//   - Byte strings are encoded with standard, padded base64.
+//   - Optional fields, lists, and byte strings are omitted when they're absent
+//     or empty. A FullTreeHead with no "tree_head" field is a SameHead.
+//   - The LogEntries of a ClientState are an object keyed by the decimal
+//     position of each log entry. Keys are sorted as strings, like any map
+//     encoded by encoding/json, so "10" comes before "9".
+//   - Each result in a prefix proof is an object with a "type" field, which is
+//     one of "inclusion", "non_inclusion_leaf", or "non_inclusion_parent", and
+//     a "depth" field. Non-inclusion leaf results also have "vrf_output" and
+//     "commitment" fields.
+//
+// Fields that are implied by the configuration of the Transparency Log, such
+// as the FullTreeHeadType, are not encoded.
+
+// MarshalJSON returns the canonical JSON encoding of a structure.
+func MarshalJSON(x Marshaller) ([]byte, error) {
+	return json.Marshal(x)
+}
+
+// UnmarshalJSON decodes the JSON encoding of a structure. To ensure that the
+// output is something that could have been received in the binary encoding,
+// the structure is converted to its binary encoding and decoded with `newF`,
+// subject to `limits`. An error is returned if any field is not valid for the
+// binary encoding, such as a hash of the wrong length. The input does not need
+// to be canonically encoded, but may not contain unknown fields.
+func UnmarshalJSON[T any, P interface {
+	*T
+	Marshaller
+}](raw []byte, limits Limits, newF func(*Decoder) (*T, error)) (*T, error) {
+	if len(raw) > limits.MaxSize {
+		return nil, errors.New("encoded structure is too large to decode")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	parsed := new(T)
+	if err := dec.Decode(parsed); err != nil {
+		return nil, err
+	} else if dec.More() {
+		return nil, errors.New("unexpected data appended to structure")
+	}
+	binary, err := Marshal(P(parsed))
+	if err != nil {
+		return nil, err
+	}
+	buf, err := NewDecoder(binary, limits)
+	if err != nil {
+		return nil, err
+	}
+	out, err := newF(buf)
+	if err != nil {
+		return nil, err
+	} else if buf.Len() != 0 {
+		return nil, errors.New("unexpected data appended to structure")
+	}
+
+	// Fields of the wrong length can shift the binary encoding such that it is
+	// still decoded successfully, but to a different structure.
+	expected, err := MarshalJSON(P(parsed))
+	if err != nil {
+		return nil, err
+	}
+	actual, err := MarshalJSON(P(out))
+	if err != nil {
+		return nil, err
+	} else if !bytes.Equal(expected, actual) {
+		return nil, errors.New("structure does not match its binary encoding")
+	}
+	return out, nil
+}
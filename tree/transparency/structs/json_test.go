@@ -0,0 +1,172 @@
+package structs
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Bren2010/katie/crypto/suites"
+)
+
+func TestJSON(t *testing.T) {
+	last, ver := uint64(10), uint32(3)
+	req := &SearchRequest{Last: &last, Label: []byte("alice"), Version: &ver}
+
+	raw, err := MarshalJSON(req)
+	if err != nil {
+		t.Fatal(err)
+	} else if string(raw) != `{"last":10,"label":"YWxpY2U=","version":3}` {
+		t.Fatalf("unexpected encoding: %s", raw)
+	}
+	parsed, err := UnmarshalJSON(raw, DefaultLimits, NewSearchRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := Marshal(parsed)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(expected, actual) {
+		t.Fatal("request changed after round trip through JSON")
+	}
+
+	// Non-canonical input is accepted, but unknown fields are not.
+	if _, err := UnmarshalJSON([]byte(` { "label": "YWxpY2U=" } `), DefaultLimits, NewSearchRequest); err != nil {
+		t.Fatal(err)
+	} else if _, err := UnmarshalJSON([]byte(`{"labels":"YWxpY2U="}`), DefaultLimits, NewSearchRequest); err == nil {
+		t.Fatal("expected error for unknown field")
+	}
+}
+
+func TestJSONWrongLength(t *testing.T) {
+	cs := suites.KTSha256P256{}
+	newF := func(buf *Decoder) (*InclusionProof, error) { return NewInclusionProof(cs, buf) }
+
+	ip := &InclusionProof{Elements: [][]byte{make([]byte, 32), make([]byte, 32)}}
+	raw, err := MarshalJSON(ip)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := UnmarshalJSON(raw, DefaultLimits, newF); err != nil {
+		t.Fatal(err)
+	}
+
+	// Moving a byte from one element to the other leaves the binary encoding
+	// the same length, but must still be rejected.
+	ip.Elements = [][]byte{make([]byte, 31), make([]byte, 33)}
+	raw, err = MarshalJSON(ip)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := UnmarshalJSON(raw, DefaultLimits, newF); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Fatalf("expected error for elements of the wrong length, got: %v", err)
+	}
+}
+
+func TestJSONHashSize(t *testing.T) {
+	ip := &InclusionProof{Elements: [][]byte{make([]byte, 48), make([]byte, 48)}}
+	raw, err := MarshalJSON(ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The proof decodes with a cipher suite that has 48-byte hashes, but not
+	// with one that has 32-byte hashes, even though the total length of the
+	// elements is a multiple of 32.
+	for _, tc := range []struct {
+		cs suites.CipherSuite
+		ok bool
+	}{
+		{suites.KTSha384P384{}, true},
+		{suites.KTSha256P256{}, false},
+	} {
+		newF := func(buf *Decoder) (*InclusionProof, error) { return NewInclusionProof(tc.cs, buf) }
+		parsed, err := UnmarshalJSON(raw, DefaultLimits, newF)
+		if !tc.ok {
+			if err == nil {
+				t.Fatal("expected error decoding proof with wrong hash size")
+			}
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		} else if len(parsed.Elements) != 2 || len(parsed.Elements[0]) != 48 {
+			t.Fatal("unexpected proof decoded")
+		}
+	}
+}
+
+func TestFullTreeHeadJSON(t *testing.T) {
+	config := &PublicConfig{Config: Config{Suite: suites.KTSha256P256{}, Mode: ThirdPartyAuditing}}
+	newF := func(buf *Decoder) (*FullTreeHead, error) { return NewFullTreeHead(config, buf) }
+
+	for _, tc := range []struct {
+		fth      *FullTreeHead
+		expected string
+	}{
+		{&FullTreeHead{}, `{}`},
+		{
+			&FullTreeHead{
+				TreeHead:        &TreeHead{TreeSize: 5, Signature: []byte{1, 2}},
+				AuditorTreeHead: &AuditorTreeHead{Timestamp: 6, TreeSize: 4, Signature: []byte{3}},
+			},
+			`{"tree_head":{"tree_size":5,"signature":"AQI="},"auditor_tree_head":{"timestamp":6,"tree_size":4,"signature":"Aw=="}}`,
+		},
+	} {
+		raw, err := MarshalJSON(tc.fth)
+		if err != nil {
+			t.Fatal(err)
+		} else if string(raw) != tc.expected {
+			t.Fatalf("unexpected encoding: %s", raw)
+		}
+		parsed, err := UnmarshalJSON(raw, DefaultLimits, newF)
+		if err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(tc.fth, parsed) {
+			t.Fatal("full tree head changed after round trip through JSON")
+		}
+	}
+}
+
+func TestClientStateJSON(t *testing.T) {
+	config := &PublicConfig{Config: Config{Suite: suites.KTSha256P256{}, Mode: ContactMonitoring}}
+	newF := func(buf *Decoder) (*ClientState, error) { return NewClientState(config, buf) }
+
+	state := &ClientState{
+		TreeHead:     TreeHead{TreeSize: 101, Signature: []byte{1}},
+		FullSubtrees: [][]byte{make([]byte, 32), make([]byte, 32)},
+		LogEntries: map[uint64]LogEntry{
+			9:   {Timestamp: 1, PrefixTree: make([]byte, 32)},
+			10:  {Timestamp: 2, PrefixTree: make([]byte, 32)},
+			100: {Timestamp: 3, PrefixTree: make([]byte, 32)},
+		},
+	}
+	raw, err := MarshalJSON(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Log entries are keyed by position, sorted as strings.
+	keys := []int{
+		strings.Index(string(raw), `"10":`),
+		strings.Index(string(raw), `"100":`),
+		strings.Index(string(raw), `"9":`),
+	}
+	if keys[0] < 0 || keys[0] >= keys[1] || keys[1] >= keys[2] {
+		t.Fatalf("unexpected order of log entries: %s", raw)
+	}
+
+	parsed, err := UnmarshalJSON(raw, DefaultLimits, newF)
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(state, parsed) {
+		t.Fatal("client state changed after round trip through JSON")
+	}
+
+	// A log entry with a position that isn't a number is rejected.
+	bad := strings.Replace(string(raw), `"100":`, `"x":`, 1)
+	if _, err := UnmarshalJSON([]byte(bad), DefaultLimits, newF); err == nil {
+		t.Fatal("expected error for malformed log entry position")
+	}
+}
@@ -6,18 +6,18 @@ import (
 )
 
 type SearchRequest struct {
-	Last *uint64
+	Last *uint64 `json:"last,omitempty"`
 
-	Label   []byte
-	Version *uint32
+	Label   []byte  `json:"label,omitempty"`
+	Version *uint32 `json:"version,omitempty"`
 }
 
-func NewSearchRequest(buf *bytes.Buffer) (*SearchRequest, error) {
+func NewSearchRequest(buf *Decoder) (*SearchRequest, error) {
 	last, err := readOptionalNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
 	}
-	label, err := readBytes[uint8](buf)
+	label, err := readLabel(buf)
 	if err != nil {
 		return nil, err
 	}
@@ -38,20 +38,20 @@ func (sr *SearchRequest) Marshal(buf *bytes.Buffer) error {
 }
 
 type SearchResponse struct {
-	FullTreeHead FullTreeHead
+	FullTreeHead FullTreeHead `json:"full_tree_head"`
 
-	Version *uint32
-	Opening []byte
-	Value   UpdateValue
+	Version *uint32     `json:"version,omitempty"`
+	Opening []byte      `json:"opening,omitempty"`
+	Value   UpdateValue `json:"value"`
 
-	BinaryLadder []BinaryLadderStep
-	Search       CombinedTreeProof
+	BinaryLadder []BinaryLadderStep `json:"binary_ladder,omitempty"`
+	Search       CombinedTreeProof  `json:"search"`
 }
 
 func NewSearchResponse(
 	config *PublicConfig,
 	req *SearchRequest,
-	buf *bytes.Buffer,
+	buf *Decoder,
 ) (*SearchResponse, error) {
 	fth, err := NewFullTreeHead(config, buf)
 	if err != nil {
@@ -75,7 +75,7 @@ func NewSearchResponse(
 		return nil, err
 	}
 
-	steps, err := readFuncSlice[uint8](buf, func(buf *bytes.Buffer) (*BinaryLadderStep, error) {
+	steps, err := readLimitedFuncSlice[uint8](buf, buf.limits.MaxLadderSize, "binary ladder", func(buf *Decoder) (*BinaryLadderStep, error) {
 		return NewBinaryLadderStep(config.Suite, buf)
 	})
 	if err != nil {
@@ -108,12 +108,346 @@ func (sr *SearchResponse) Marshal(buf *bytes.Buffer) error {
 	return sr.Search.Marshal(buf)
 }
 
+type BatchSearchEntry struct {
+	Label   []byte  `json:"label,omitempty"`
+	Version *uint32 `json:"version,omitempty"`
+}
+
+func NewBatchSearchEntry(buf *Decoder) (*BatchSearchEntry, error) {
+	label, err := readLabel(buf)
+	if err != nil {
+		return nil, err
+	}
+	version, err := readOptionalNumeric[uint32](buf)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchSearchEntry{label, version}, nil
+}
+
+func (bse *BatchSearchEntry) Marshal(buf *bytes.Buffer) error {
+	if err := writeBytes[uint8](buf, bse.Label, "label"); err != nil {
+		return err
+	}
+	writeOptionalNumeric(buf, bse.Version)
+	return nil
+}
+
+type BatchSearchRequest struct {
+	Last *uint64 `json:"last,omitempty"`
+
+	Entries []BatchSearchEntry `json:"entries,omitempty"`
+}
+
+func NewBatchSearchRequest(buf *Decoder) (*BatchSearchRequest, error) {
+	last, err := readOptionalNumeric[uint64](buf)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readLimitedFuncSlice[uint16](buf, buf.limits.MaxBatchSearchEntries, "batch search entry list", NewBatchSearchEntry)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchSearchRequest{last, entries}, nil
+}
+
+func (bsr *BatchSearchRequest) Marshal(buf *bytes.Buffer) error {
+	writeOptionalNumeric(buf, bsr.Last)
+	return writeMarshalSlice[uint16](buf, bsr.Entries, "batch search entry")
+}
+
+// BatchSearchResult contains the fields of a SearchResponse that are specific
+// to one label of a BatchSearchRequest.
+type BatchSearchResult struct {
+	Version *uint32     `json:"version,omitempty"`
+	Opening []byte      `json:"opening,omitempty"`
+	Value   UpdateValue `json:"value"`
+
+	BinaryLadder []BinaryLadderStep `json:"binary_ladder,omitempty"`
+}
+
+func NewBatchSearchResult(
+	config *PublicConfig,
+	entry *BatchSearchEntry,
+	buf *Decoder,
+) (*BatchSearchResult, error) {
+	var version *uint32
+	if entry.Version == nil {
+		versionActual, err := readNumeric[uint32](buf)
+		if err != nil {
+			return nil, err
+		}
+		version = &versionActual
+	}
+	opening := make([]byte, config.Suite.CommitmentOpeningSize())
+	if _, err := io.ReadFull(buf, opening); err != nil {
+		return nil, err
+	}
+	value, err := NewUpdateValue(config, buf)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := readLimitedFuncSlice[uint8](buf, buf.limits.MaxLadderSize, "binary ladder", func(buf *Decoder) (*BinaryLadderStep, error) {
+		return NewBinaryLadderStep(config.Suite, buf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BatchSearchResult{version, opening, *value, steps}, nil
+}
+
+func (bsr *BatchSearchResult) Marshal(buf *bytes.Buffer) error {
+	if bsr.Version != nil {
+		writeNumeric(buf, *bsr.Version)
+	}
+	buf.Write(bsr.Opening)
+	if err := bsr.Value.Marshal(buf); err != nil {
+		return err
+	}
+	return writeMarshalSlice[uint8](buf, bsr.BinaryLadder, "binary ladder")
+}
+
+// BatchSearchResponse is the response to a BatchSearchRequest. It contains one
+// BatchSearchResult for each entry of the request, in the same order, and a
+// single CombinedTreeProof that covers every label.
+type BatchSearchResponse struct {
+	FullTreeHead FullTreeHead `json:"full_tree_head"`
+
+	Results []BatchSearchResult `json:"results,omitempty"`
+	Search  CombinedTreeProof   `json:"search"`
+}
+
+func NewBatchSearchResponse(
+	config *PublicConfig,
+	req *BatchSearchRequest,
+	buf *Decoder,
+) (*BatchSearchResponse, error) {
+	fth, err := NewFullTreeHead(config, buf)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]BatchSearchResult, len(req.Entries))
+	for i := range req.Entries {
+		result, err := NewBatchSearchResult(config, &req.Entries[i], buf)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = *result
+	}
+	search, err := NewCombinedTreeProof(config.Suite, buf)
+	if err != nil {
+		return nil, err
+	}
+	return &BatchSearchResponse{*fth, results, *search}, nil
+}
+
+func (bsr *BatchSearchResponse) Marshal(buf *bytes.Buffer) error {
+	if err := bsr.FullTreeHead.Marshal(buf); err != nil {
+		return err
+	}
+	for _, result := range bsr.Results {
+		if err := result.Marshal(buf); err != nil {
+			return err
+		}
+	}
+	return bsr.Search.Marshal(buf)
+}
+
+// HistoryRequest requests a page of the history of a label, starting at
+// version `Start`.
+type HistoryRequest struct {
+	Last *uint64 `json:"last,omitempty"`
+
+	Label []byte `json:"label,omitempty"`
+	Start uint32 `json:"start"`
+}
+
+func NewHistoryRequest(buf *Decoder) (*HistoryRequest, error) {
+	last, err := readOptionalNumeric[uint64](buf)
+	if err != nil {
+		return nil, err
+	}
+	label, err := readLabel(buf)
+	if err != nil {
+		return nil, err
+	}
+	start, err := readNumeric[uint32](buf)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryRequest{last, label, start}, nil
+}
+
+func (hr *HistoryRequest) Marshal(buf *bytes.Buffer) error {
+	writeOptionalNumeric(buf, hr.Last)
+	if err := writeBytes[uint8](buf, hr.Label, "label"); err != nil {
+		return err
+	}
+	writeNumeric(buf, hr.Start)
+	return nil
+}
+
+// HistoryEntry contains a single version of a label from a HistoryResponse,
+// along with the position of the log entry where the version was added.
+type HistoryEntry struct {
+	Position uint64      `json:"position"`
+	Opening  []byte      `json:"opening,omitempty"`
+	Value    UpdateValue `json:"value"`
+
+	BinaryLadder []BinaryLadderStep `json:"binary_ladder,omitempty"`
+}
+
+func NewHistoryEntry(config *PublicConfig, buf *Decoder) (*HistoryEntry, error) {
+	pos, err := readNumeric[uint64](buf)
+	if err != nil {
+		return nil, err
+	}
+	opening := make([]byte, config.Suite.CommitmentOpeningSize())
+	if _, err := io.ReadFull(buf, opening); err != nil {
+		return nil, err
+	}
+	value, err := NewUpdateValue(config, buf)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := readLimitedFuncSlice[uint8](buf, buf.limits.MaxLadderSize, "binary ladder", func(buf *Decoder) (*BinaryLadderStep, error) {
+		return NewBinaryLadderStep(config.Suite, buf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryEntry{pos, opening, *value, steps}, nil
+}
+
+func (he *HistoryEntry) Marshal(buf *bytes.Buffer) error {
+	writeNumeric(buf, he.Position)
+	buf.Write(he.Opening)
+	if err := he.Value.Marshal(buf); err != nil {
+		return err
+	}
+	return writeMarshalSlice[uint8](buf, he.BinaryLadder, "binary ladder")
+}
+
+// HistoryResponse is the response to a HistoryRequest. It contains the greatest
+// version of the label and the binary ladder proving it, followed by an entry
+// for each consecutive version of the label from the requested start version.
+// A single CombinedTreeProof covers all of them.
+type HistoryResponse struct {
+	FullTreeHead FullTreeHead `json:"full_tree_head"`
+
+	Version      uint32             `json:"version"`
+	BinaryLadder []BinaryLadderStep `json:"binary_ladder,omitempty"`
+
+	Entries []HistoryEntry    `json:"entries,omitempty"`
+	Search  CombinedTreeProof `json:"search"`
+}
+
+func NewHistoryResponse(config *PublicConfig, buf *Decoder) (*HistoryResponse, error) {
+	fth, err := NewFullTreeHead(config, buf)
+	if err != nil {
+		return nil, err
+	}
+	version, err := readNumeric[uint32](buf)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := readLimitedFuncSlice[uint8](buf, buf.limits.MaxLadderSize, "binary ladder", func(buf *Decoder) (*BinaryLadderStep, error) {
+		return NewBinaryLadderStep(config.Suite, buf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := readFuncSlice[uint8](buf, func(buf *Decoder) (*HistoryEntry, error) {
+		return NewHistoryEntry(config, buf)
+	})
+	if err != nil {
+		return nil, err
+	}
+	search, err := NewCombinedTreeProof(config.Suite, buf)
+	if err != nil {
+		return nil, err
+	}
+	return &HistoryResponse{*fth, version, steps, entries, *search}, nil
+}
+
+func (hr *HistoryResponse) Marshal(buf *bytes.Buffer) error {
+	if err := hr.FullTreeHead.Marshal(buf); err != nil {
+		return err
+	}
+	writeNumeric(buf, hr.Version)
+	if err := writeMarshalSlice[uint8](buf, hr.BinaryLadder, "binary ladder"); err != nil {
+		return err
+	} else if err := writeMarshalSlice[uint8](buf, hr.Entries, "history entry"); err != nil {
+		return err
+	}
+	return hr.Search.Marshal(buf)
+}
+
+// ConsistencyRequest requests a proof that the Log Tree of size `Older` is a
+// prefix of the Log Tree of size `Newer`.
+type ConsistencyRequest struct {
+	Older uint64 `json:"older"`
+	Newer uint64 `json:"newer"`
+}
+
+func NewConsistencyRequest(buf *Decoder) (*ConsistencyRequest, error) {
+	older, err := readNumeric[uint64](buf)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := readNumeric[uint64](buf)
+	if err != nil {
+		return nil, err
+	}
+	return &ConsistencyRequest{older, newer}, nil
+}
+
+func (cr *ConsistencyRequest) Marshal(buf *bytes.Buffer) error {
+	writeNumeric(buf, cr.Older)
+	writeNumeric(buf, cr.Newer)
+	return nil
+}
+
+// ConsistencyResponse is the response to a ConsistencyRequest. It contains the
+// signed tree heads of both Log Tree sizes, and a consistency proof between
+// them in the format of Section 2.1.4 of RFC 9162.
+type ConsistencyResponse struct {
+	Older TreeHead `json:"older"`
+	Newer TreeHead `json:"newer"`
+	Proof [][]byte `json:"proof,omitempty"`
+}
+
+func NewConsistencyResponse(config *PublicConfig, buf *Decoder) (*ConsistencyResponse, error) {
+	older, err := NewTreeHead(buf)
+	if err != nil {
+		return nil, err
+	}
+	newer, err := NewTreeHead(buf)
+	if err != nil {
+		return nil, err
+	}
+	proof, err := readByteSlice[uint8](buf, config.Suite.HashSize())
+	if err != nil {
+		return nil, err
+	}
+	return &ConsistencyResponse{*older, *newer, proof}, nil
+}
+
+func (cr *ConsistencyResponse) Marshal(buf *bytes.Buffer) error {
+	if err := cr.Older.Marshal(buf); err != nil {
+		return err
+	} else if err := cr.Newer.Marshal(buf); err != nil {
+		return err
+	}
+	return writeByteSlice[uint8](buf, cr.Proof, "consistency proof")
+}
+
 type MonitorMapEntry struct {
-	Position uint64
-	Version  uint32
+	Position uint64 `json:"position"`
+	Version  uint32 `json:"version"`
 }
 
-func NewMonitorMapEntry(buf *bytes.Buffer) (*MonitorMapEntry, error) {
+func NewMonitorMapEntry(buf *Decoder) (*MonitorMapEntry, error) {
 	position, err := readNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
@@ -132,18 +466,18 @@ func (mme *MonitorMapEntry) Marshal(buf *bytes.Buffer) error {
 }
 
 type ContactMonitorRequest struct {
-	Last *uint64
+	Last *uint64 `json:"last,omitempty"`
 
-	Label   []byte
-	Entries []MonitorMapEntry
+	Label   []byte            `json:"label,omitempty"`
+	Entries []MonitorMapEntry `json:"entries,omitempty"`
 }
 
-func NewContactMonitorRequest(buf *bytes.Buffer) (*ContactMonitorRequest, error) {
+func NewContactMonitorRequest(buf *Decoder) (*ContactMonitorRequest, error) {
 	last, err := readOptionalNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
 	}
-	label, err := readBytes[uint8](buf)
+	label, err := readLabel(buf)
 	if err != nil {
 		return nil, err
 	}
@@ -163,13 +497,13 @@ func (cmr *ContactMonitorRequest) Marshal(buf *bytes.Buffer) error {
 }
 
 type ContactMonitorResponse struct {
-	FullTreeHead FullTreeHead
-	Monitor      CombinedTreeProof
+	FullTreeHead FullTreeHead      `json:"full_tree_head"`
+	Monitor      CombinedTreeProof `json:"monitor"`
 }
 
 func NewContactMonitorResponse(
 	config *PublicConfig,
-	buf *bytes.Buffer,
+	buf *Decoder,
 ) (*ContactMonitorResponse, error) {
 	fth, err := NewFullTreeHead(config, buf)
 	if err != nil {
@@ -190,18 +524,18 @@ func (cmr *ContactMonitorResponse) Marshal(buf *bytes.Buffer) error {
 }
 
 type OwnerInitRequest struct {
-	Last *uint64
+	Last *uint64 `json:"last,omitempty"`
 
-	Label []byte
-	Start uint64
+	Label []byte `json:"label,omitempty"`
+	Start uint64 `json:"start"`
 }
 
-func NewOwnerInitRequest(buf *bytes.Buffer) (*OwnerInitRequest, error) {
+func NewOwnerInitRequest(buf *Decoder) (*OwnerInitRequest, error) {
 	last, err := readOptionalNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
 	}
-	label, err := readBytes[uint8](buf)
+	label, err := readLabel(buf)
 	if err != nil {
 		return nil, err
 	}
@@ -222,16 +556,16 @@ func (oir *OwnerInitRequest) Marshal(buf *bytes.Buffer) error {
 }
 
 type OwnerInitResponse struct {
-	FullTreeHead FullTreeHead
+	FullTreeHead FullTreeHead `json:"full_tree_head"`
 
-	GreatestVersions []uint32
-	BinaryLadder     []BinaryLadderStep
-	Init             CombinedTreeProof
+	GreatestVersions []uint32           `json:"greatest_versions,omitempty"`
+	BinaryLadder     []BinaryLadderStep `json:"binary_ladder,omitempty"`
+	Init             CombinedTreeProof  `json:"init"`
 }
 
 func NewOwnerInitResponse(
 	config *PublicConfig,
-	buf *bytes.Buffer,
+	buf *Decoder,
 ) (*OwnerInitResponse, error) {
 	fth, err := NewFullTreeHead(config, buf)
 	if err != nil {
@@ -241,7 +575,7 @@ func NewOwnerInitResponse(
 	if err != nil {
 		return nil, err
 	}
-	steps, err := readFuncSlice[uint16](buf, func(buf *bytes.Buffer) (*BinaryLadderStep, error) {
+	steps, err := readLimitedFuncSlice[uint16](buf, buf.limits.MaxLadderSize, "binary ladder", func(buf *Decoder) (*BinaryLadderStep, error) {
 		return NewBinaryLadderStep(config.Suite, buf)
 	})
 	if err != nil {
@@ -266,20 +600,20 @@ func (oir *OwnerInitResponse) Marshal(buf *bytes.Buffer) error {
 }
 
 type OwnerMonitorRequest struct {
-	Last *uint64
+	Last *uint64 `json:"last,omitempty"`
 
-	Label           []byte
-	Entries         []MonitorMapEntry
-	Start           uint64
-	GreatestVersion *uint32
+	Label           []byte            `json:"label,omitempty"`
+	Entries         []MonitorMapEntry `json:"entries,omitempty"`
+	Start           uint64            `json:"start"`
+	GreatestVersion *uint32           `json:"greatest_version,omitempty"`
 }
 
-func NewOwnerMonitorRequest(buf *bytes.Buffer) (*OwnerMonitorRequest, error) {
+func NewOwnerMonitorRequest(buf *Decoder) (*OwnerMonitorRequest, error) {
 	last, err := readOptionalNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
 	}
-	label, err := readBytes[uint8](buf)
+	label, err := readLabel(buf)
 	if err != nil {
 		return nil, err
 	}
@@ -311,13 +645,13 @@ func (omr *OwnerMonitorRequest) Marshal(buf *bytes.Buffer) error {
 }
 
 type OwnerMonitorResponse struct {
-	FullTreeHead FullTreeHead
-	Monitor      CombinedTreeProof
+	FullTreeHead FullTreeHead      `json:"full_tree_head"`
+	Monitor      CombinedTreeProof `json:"monitor"`
 }
 
 func NewOwnerMonitorResponse(
 	config *PublicConfig,
-	buf *bytes.Buffer,
+	buf *Decoder,
 ) (*OwnerMonitorResponse, error) {
 	fth, err := NewFullTreeHead(config, buf)
 	if err != nil {
@@ -338,19 +672,19 @@ func (omr *OwnerMonitorResponse) Marshal(buf *bytes.Buffer) error {
 }
 
 type UpdateRequest struct {
-	Last *uint64
+	Last *uint64 `json:"last,omitempty"`
 
-	Label           []byte
-	GreatestVersion *uint32
-	Values          []LabelValue
+	Label           []byte       `json:"label,omitempty"`
+	GreatestVersion *uint32      `json:"greatest_version,omitempty"`
+	Values          []LabelValue `json:"values,omitempty"`
 }
 
-func NewUpdateRequest(buf *bytes.Buffer) (*UpdateRequest, error) {
+func NewUpdateRequest(buf *Decoder) (*UpdateRequest, error) {
 	last, err := readOptionalNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
 	}
-	label, err := readBytes[uint8](buf)
+	label, err := readLabel(buf)
 	if err != nil {
 		return nil, err
 	}
@@ -375,20 +709,20 @@ func (ur *UpdateRequest) Marshal(buf *bytes.Buffer) error {
 }
 
 type ManagerUpdateRequest struct {
-	Last *uint64
+	Last *uint64 `json:"last,omitempty"`
 
-	Label           []byte
-	GreatestVersion *uint32
-	SignedVersion   uint32
-	Values          []UpdateValue
+	Label           []byte        `json:"label,omitempty"`
+	GreatestVersion *uint32       `json:"greatest_version,omitempty"`
+	SignedVersion   uint32        `json:"signed_version"`
+	Values          []UpdateValue `json:"values,omitempty"`
 }
 
-func NewManagerUpdateRequest(config *PublicConfig, buf *bytes.Buffer) (*ManagerUpdateRequest, error) {
+func NewManagerUpdateRequest(config *PublicConfig, buf *Decoder) (*ManagerUpdateRequest, error) {
 	last, err := readOptionalNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
 	}
-	label, err := readBytes[uint8](buf)
+	label, err := readLabel(buf)
 	if err != nil {
 		return nil, err
 	}
@@ -400,7 +734,7 @@ func NewManagerUpdateRequest(config *PublicConfig, buf *bytes.Buffer) (*ManagerU
 	if err != nil {
 		return nil, err
 	}
-	entries, err := readFuncSlice[uint8](buf, func(buf *bytes.Buffer) (*UpdateValue, error) {
+	entries, err := readFuncSlice[uint8](buf, func(buf *Decoder) (*UpdateValue, error) {
 		return NewUpdateValue(config, buf)
 	})
 	if err != nil {
@@ -419,18 +753,23 @@ func (mur *ManagerUpdateRequest) Marshal(buf *bytes.Buffer) error {
 	return writeMarshalSlice[uint8](buf, mur.Values, "label value")
 }
 
+// UpdateResponse contains the versions of a label that were created in the log
+// entry at `Position`. `Values` is omitted if every version was submitted by
+// the requester. Otherwise, if some of the versions were submitted by the
+// requester, `Submitted` is the index in `Values` of the first of them.
 type UpdateResponse struct {
-	FullTreeHead FullTreeHead
+	FullTreeHead FullTreeHead `json:"full_tree_head"`
 
-	Position uint64
-	Values   []LabelValue
-	Info     []UpdateInfo
+	Position  uint64       `json:"position"`
+	Values    []LabelValue `json:"values,omitempty"`
+	Submitted *uint8       `json:"submitted,omitempty"`
+	Info      []UpdateInfo `json:"info,omitempty"`
 
-	BinaryLadder []BinaryLadderStep
-	Update       CombinedTreeProof
+	BinaryLadder []BinaryLadderStep `json:"binary_ladder,omitempty"`
+	Update       CombinedTreeProof  `json:"update"`
 }
 
-func NewUpdateResponse(config *PublicConfig, buf *bytes.Buffer) (*UpdateResponse, error) {
+func NewUpdateResponse(config *PublicConfig, buf *Decoder) (*UpdateResponse, error) {
 	fth, err := NewFullTreeHead(config, buf)
 	if err != nil {
 		return nil, err
@@ -444,14 +783,18 @@ func NewUpdateResponse(config *PublicConfig, buf *bytes.Buffer) (*UpdateResponse
 	if err != nil {
 		return nil, err
 	}
-	info, err := readFuncSlice[uint8](buf, func(buf *bytes.Buffer) (*UpdateInfo, error) {
+	submitted, err := readOptionalNumeric[uint8](buf)
+	if err != nil {
+		return nil, err
+	}
+	info, err := readFuncSlice[uint8](buf, func(buf *Decoder) (*UpdateInfo, error) {
 		return NewUpdateInfo(config, buf)
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	ladder, err := readFuncSlice[uint8](buf, func(buf *bytes.Buffer) (*BinaryLadderStep, error) {
+	ladder, err := readLimitedFuncSlice[uint8](buf, buf.limits.MaxLadderSize, "binary ladder", func(buf *Decoder) (*BinaryLadderStep, error) {
 		return NewBinaryLadderStep(config.Suite, buf)
 	})
 	if err != nil {
@@ -462,7 +805,7 @@ func NewUpdateResponse(config *PublicConfig, buf *bytes.Buffer) (*UpdateResponse
 		return nil, err
 	}
 
-	return &UpdateResponse{*fth, position, values, info, ladder, *update}, nil
+	return &UpdateResponse{*fth, position, values, submitted, info, ladder, *update}, nil
 }
 
 func (ur *UpdateResponse) Marshal(buf *bytes.Buffer) error {
@@ -474,6 +817,7 @@ func (ur *UpdateResponse) Marshal(buf *bytes.Buffer) error {
 	if err := writeMarshalSlice[uint8](buf, ur.Values, "label value"); err != nil {
 		return err
 	}
+	writeOptionalNumeric(buf, ur.Submitted)
 	if err := writeMarshalSlice[uint8](buf, ur.Info, "update info"); err != nil {
 		return err
 	}
@@ -8,10 +8,10 @@ import (
 )
 
 type InclusionProof struct {
-	Elements [][]byte
+	Elements [][]byte `json:"elements,omitempty"`
 }
 
-func NewInclusionProof(cs suites.CipherSuite, buf *bytes.Buffer) (*InclusionProof, error) {
+func NewInclusionProof(cs suites.CipherSuite, buf *Decoder) (*InclusionProof, error) {
 	elements, err := readByteSlice[uint16](buf, cs.HashSize())
 	if err != nil {
 		return nil, err
@@ -24,20 +24,20 @@ func (ip *InclusionProof) Marshal(buf *bytes.Buffer) error {
 }
 
 type CombinedTreeProof struct {
-	Timestamps   []uint64
-	PrefixProofs []prefix.PrefixProof
-	PrefixRoots  [][]byte
+	Timestamps   []uint64             `json:"timestamps,omitempty"`
+	PrefixProofs []prefix.PrefixProof `json:"prefix_proofs,omitempty"`
+	PrefixRoots  [][]byte             `json:"prefix_roots,omitempty"`
 
-	Inclusion InclusionProof
+	Inclusion InclusionProof `json:"inclusion"`
 }
 
-func NewCombinedTreeProof(cs suites.CipherSuite, buf *bytes.Buffer) (*CombinedTreeProof, error) {
+func NewCombinedTreeProof(cs suites.CipherSuite, buf *Decoder) (*CombinedTreeProof, error) {
 	timestamps, err := readNumericSlice[uint8, uint64](buf)
 	if err != nil {
 		return nil, err
 	}
-	proofs, err := readFuncSlice[uint8](buf, func(buf *bytes.Buffer) (*prefix.PrefixProof, error) {
-		return prefix.NewPrefixProof(cs, buf)
+	proofs, err := readLimitedFuncSlice[uint8](buf, buf.limits.MaxPrefixProofs, "prefix proof list", func(buf *Decoder) (*prefix.PrefixProof, error) {
+		return prefix.NewPrefixProof(cs, buf.Buffer)
 	})
 	if err != nil {
 		return nil, err
@@ -24,10 +24,10 @@ func Marshal(x Marshaller) ([]byte, error) {
 }
 
 type numeric interface {
-	uint8 | uint16 | uint32 | uint64 | int
+	uint8 | uint16 | uint32 | uint64 | int64
 }
 
-func readNumeric[T numeric](buf *bytes.Buffer) (T, error) {
+func readNumeric[T numeric](buf *Decoder) (T, error) {
 	var val T
 	if err := binary.Read(buf, binary.BigEndian, &val); err != nil {
 		return 0, err
@@ -39,7 +39,7 @@ func writeNumeric[T numeric](buf *bytes.Buffer, val T) {
 	binary.Write(buf, binary.BigEndian, val)
 }
 
-func readOptional(buf *bytes.Buffer) (bool, error) {
+func readOptional(buf *Decoder) (bool, error) {
 	present, err := buf.ReadByte()
 	if err != nil {
 		return false, err
@@ -58,7 +58,7 @@ func writeOptional(buf *bytes.Buffer, present bool) bool {
 	return present
 }
 
-func readOptionalNumeric[T numeric](buf *bytes.Buffer) (*T, error) {
+func readOptionalNumeric[T numeric](buf *Decoder) (*T, error) {
 	var val *T
 
 	if present, err := readOptional(buf); err != nil {
@@ -86,10 +86,18 @@ type sizeParam interface {
 
 func max[S sizeParam]() int { return int(^S(0)) }
 
-func readBytes[S sizeParam](buf *bytes.Buffer) ([]byte, error) {
+func readBytes[S sizeParam](buf *Decoder) ([]byte, error) {
+	return readLimitedBytes[S](buf, max[S](), "byte string")
+}
+
+// readLimitedBytes is the same as readBytes, except that the byte string may be
+// at most `limit` bytes long.
+func readLimitedBytes[S sizeParam](buf *Decoder, limit int, name string) ([]byte, error) {
 	size, err := readNumeric[S](buf)
 	if err != nil {
 		return nil, err
+	} else if err := buf.checkSize(int(size), 1, limit, name); err != nil {
+		return nil, err
 	}
 	out := make([]byte, size)
 	if _, err := io.ReadFull(buf, out); err != nil {
@@ -107,10 +115,23 @@ func writeBytes[S sizeParam](buf *bytes.Buffer, out []byte, name string) error {
 	return nil
 }
 
-func readByteSlice[S sizeParam](buf *bytes.Buffer, n int) ([][]byte, error) {
+// readLabel reads a label, which may be at most MaxLabelSize bytes long.
+func readLabel(buf *Decoder) ([]byte, error) {
+	return readLimitedBytes[uint8](buf, buf.limits.MaxLabelSize, "label")
+}
+
+// readValue reads a label's value, which may be at most MaxValueSize bytes
+// long.
+func readValue(buf *Decoder) ([]byte, error) {
+	return readLimitedBytes[uint32](buf, buf.limits.MaxValueSize, "value")
+}
+
+func readByteSlice[S sizeParam](buf *Decoder, n int) ([][]byte, error) {
 	size, err := readNumeric[S](buf)
 	if err != nil {
 		return nil, err
+	} else if err := buf.checkSize(int(size), n, max[S](), "byte string list"); err != nil {
+		return nil, err
 	}
 	out := make([][]byte, size)
 	for i := range int(size) {
@@ -134,10 +155,12 @@ func writeByteSlice[S sizeParam](buf *bytes.Buffer, out [][]byte, name string) e
 	return nil
 }
 
-func readNumericSlice[S sizeParam, T numeric](buf *bytes.Buffer) ([]T, error) {
+func readNumericSlice[S sizeParam, T numeric](buf *Decoder) ([]T, error) {
 	size, err := readNumeric[S](buf)
 	if err != nil {
 		return nil, err
+	} else if err := buf.checkSize(int(size), binary.Size(T(0)), max[S](), "numeric list"); err != nil {
+		return nil, err
 	}
 	out := make([]T, size)
 	for i := range int(size) {
@@ -166,12 +189,26 @@ func writeNumericSlice[S sizeParam, T numeric](
 }
 
 func readFuncSlice[S sizeParam, T any](
-	buf *bytes.Buffer,
-	newF func(*bytes.Buffer) (*T, error),
+	buf *Decoder,
+	newF func(*Decoder) (*T, error),
+) ([]T, error) {
+	return readLimitedFuncSlice[S](buf, max[S](), "list", newF)
+}
+
+// readLimitedFuncSlice is the same as readFuncSlice, except that the list may
+// contain at most `limit` elements. Every element is assumed to be encoded in
+// at least one byte.
+func readLimitedFuncSlice[S sizeParam, T any](
+	buf *Decoder,
+	limit int,
+	name string,
+	newF func(*Decoder) (*T, error),
 ) ([]T, error) {
 	size, err := readNumeric[S](buf)
 	if err != nil {
 		return nil, err
+	} else if err := buf.checkSize(int(size), 1, limit, name); err != nil {
+		return nil, err
 	}
 	out := make([]T, size)
 	for i := range int(size) {
@@ -8,7 +8,7 @@ import (
 	"github.com/Bren2010/katie/tree/prefix"
 )
 
-func newPrefixEntry(cs suites.CipherSuite, buf *bytes.Buffer) (*prefix.Entry, error) {
+func newPrefixEntry(cs suites.CipherSuite, buf *Decoder) (*prefix.Entry, error) {
 	vrfOutput := make([]byte, cs.HashSize())
 	if _, err := io.ReadFull(buf, vrfOutput); err != nil {
 		return nil, err
@@ -27,18 +27,19 @@ func marshalPrefixEntry(entry *prefix.Entry, buf *bytes.Buffer) error {
 }
 
 type AuditorUpdate struct {
-	Timestamp      uint64
-	Added, Removed []prefix.Entry
-	Proof          prefix.PrefixProof
+	Timestamp uint64             `json:"timestamp"`
+	Added     []prefix.Entry     `json:"added,omitempty"`
+	Removed   []prefix.Entry     `json:"removed,omitempty"`
+	Proof     prefix.PrefixProof `json:"proof"`
 }
 
-func NewAuditorUpdate(cs suites.CipherSuite, buf *bytes.Buffer) (*AuditorUpdate, error) {
+func NewAuditorUpdate(cs suites.CipherSuite, buf *Decoder) (*AuditorUpdate, error) {
 	timestamp, err := readNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
 	}
 
-	newF := func(buf *bytes.Buffer) (*prefix.Entry, error) { return newPrefixEntry(cs, buf) }
+	newF := func(buf *Decoder) (*prefix.Entry, error) { return newPrefixEntry(cs, buf) }
 	added, err := readFuncSlice[uint16](buf, newF)
 	if err != nil {
 		return nil, err
@@ -48,7 +49,7 @@ func NewAuditorUpdate(cs suites.CipherSuite, buf *bytes.Buffer) (*AuditorUpdate,
 		return nil, err
 	}
 
-	proof, err := prefix.NewPrefixProof(cs, buf)
+	proof, err := prefix.NewPrefixProof(cs, buf.Buffer)
 	if err != nil {
 		return nil, err
 	}
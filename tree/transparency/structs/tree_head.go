@@ -6,11 +6,11 @@ import (
 )
 
 type TreeHead struct {
-	TreeSize  uint64
-	Signature []byte
+	TreeSize  uint64 `json:"tree_size"`
+	Signature []byte `json:"signature,omitempty"`
 }
 
-func NewTreeHead(buf *bytes.Buffer) (*TreeHead, error) {
+func NewTreeHead(buf *Decoder) (*TreeHead, error) {
 	treeSize, err := readNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
@@ -43,12 +43,12 @@ func (tbs *TreeHeadTBS) Marshal(buf *bytes.Buffer) error {
 }
 
 type AuditorTreeHead struct {
-	Timestamp uint64
-	TreeSize  uint64
-	Signature []byte
+	Timestamp uint64 `json:"timestamp"`
+	TreeSize  uint64 `json:"tree_size"`
+	Signature []byte `json:"signature,omitempty"`
 }
 
-func NewAuditorTreeHead(buf *bytes.Buffer) (*AuditorTreeHead, error) {
+func NewAuditorTreeHead(buf *Decoder) (*AuditorTreeHead, error) {
 	timestamp, err := readNumeric[uint64](buf)
 	if err != nil {
 		return nil, err
@@ -95,11 +95,11 @@ const (
 )
 
 type FullTreeHead struct {
-	TreeHead        *TreeHead
-	AuditorTreeHead *AuditorTreeHead
+	TreeHead        *TreeHead        `json:"tree_head,omitempty"`
+	AuditorTreeHead *AuditorTreeHead `json:"auditor_tree_head,omitempty"`
 }
 
-func NewFullTreeHead(config *PublicConfig, buf *bytes.Buffer) (*FullTreeHead, error) {
+func NewFullTreeHead(config *PublicConfig, buf *Decoder) (*FullTreeHead, error) {
 	b, err := buf.ReadByte()
 	if err != nil {
 		return nil, err
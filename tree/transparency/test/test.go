@@ -4,7 +4,9 @@ import (
 	"encoding/hex"
 	"fmt"
 	"slices"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/Bren2010/katie/crypto/suites"
 	"github.com/Bren2010/katie/tree/transparency/math"
@@ -12,9 +14,28 @@ import (
 )
 
 func Config(t *testing.T) structs.PrivateConfig {
-	cs := suites.KTSha256P256{}
+	return newConfig(
+		t,
+		suites.KTSha256P256{},
+		"d4987fdd18738be11e93f7f087bf3e0ef5743b8deea192509bbf716c9463c218",
+		"d1f2dcc02cc82c1f2b623e91946c945a2a1eb2983a47f283d8dd2af3d9b9d9ad",
+	)
+}
+
+// P384Config returns the same configuration as Config, except using the
+// KTSha384P384 cipher suite. It's used to check that everything works with a
+// hash size other than 32 bytes.
+func P384Config(t *testing.T) structs.PrivateConfig {
+	return newConfig(
+		t,
+		suites.KTSha384P384{},
+		"5e061be03d1f60755bae9b54f29027e5f9a06aeaad94c0a514d4777d013b9e399092a305f3f0def68a50f84770e01920",
+		"4d804ef230dbaecb889d6db48fa51d087fa4c6299a6b0822b9a324ab1260e3d1a967beb68e463fc9efda3c949dd13966",
+	)
+}
 
-	rawSigKey, err := hex.DecodeString("d4987fdd18738be11e93f7f087bf3e0ef5743b8deea192509bbf716c9463c218")
+func newConfig(t *testing.T, cs suites.CipherSuite, sigKeyHex, vrfKeyHex string) structs.PrivateConfig {
+	rawSigKey, err := hex.DecodeString(sigKeyHex)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -22,7 +43,7 @@ func Config(t *testing.T) structs.PrivateConfig {
 	if err != nil {
 		t.Fatal(err)
 	}
-	rawVrfKey, err := hex.DecodeString("d1f2dcc02cc82c1f2b623e91946c945a2a1eb2983a47f283d8dd2af3d9b9d9ad")
+	rawVrfKey, err := hex.DecodeString(vrfKeyHex)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -60,13 +81,54 @@ func ConfigWithAuditor(t *testing.T) (structs.PrivateConfig, suites.SigningPriva
 	}
 
 	config.Mode = structs.ThirdPartyAuditing
-	config.MaxAuditorLag = 1
+	config.MaxAuditorLag = 60 * 1000
 	config.AuditorStartPos = 0
 	config.AuditorPublicKey = auditorKey.Public()
 
 	return config, auditorKey
 }
 
+func ConfigWithManager(t *testing.T) (structs.PrivateConfig, suites.SigningPrivateKey) {
+	config := Config(t)
+
+	rawLeafKey, err := hex.DecodeString("c3be572a9f7fc57000063b83e6aadf3a06ab1fb6b2fb45ae29eb87b3af934461")
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafKey, err := config.Suite.ParseSigningPrivateKey(rawLeafKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config.Mode = structs.ThirdPartyManagement
+	config.LeafPublicKey = leafKey.Public()
+
+	return config, leafKey
+}
+
+// Clock is a structs.Clock that only advances when Advance is called, so that
+// tests can simulate the passage of time without sleeping.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewClock returns a new Clock that starts at the current time.
+func NewClock() *Clock { return &Clock{now: time.Now()} }
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by `d`.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
 type ProofHandle struct {
 	timestamps map[uint64]uint64
 	versions   map[uint64]uint32
@@ -146,6 +208,9 @@ func (ph *ProofHandle) Tracker() *math.VersionTracker {
 	return &math.VersionTracker{}
 }
 
+func (ph *ProofHandle) SelectLabel(i int) error {
+	panic("not implemented")
+}
 func (ph *ProofHandle) AddVersion(ver uint32, vrfOutput, commitment []byte) error {
 	panic("not implemented")
 }
@@ -1,11 +1,11 @@
 package transparency
 
 import (
-	"bytes"
 	"context"
 	"errors"
 
 	"github.com/Bren2010/katie/db"
+	"github.com/Bren2010/katie/tree/log"
 	"github.com/Bren2010/katie/tree/transparency/algorithms"
 	"github.com/Bren2010/katie/tree/transparency/math"
 	"github.com/Bren2010/katie/tree/transparency/structs"
@@ -22,18 +22,22 @@ type LabelValue struct {
 // sending UpdateRequest structures to be applied to the tree, so that these
 // requests can be received and applied by a central goroutine.
 //
-// This goroutine must ensure that only one UpdateRequest per `Label` is
-// processed in a single log entry. Processing multiple UpdateRequests with the
-// same `Label` will cause monitoring failures.
+// If multiple UpdateRequests with the same `Label` are processed in a single
+// log entry, this goroutine must merge them into one set of new versions and
+// tell each requester which of those versions are its own. Otherwise, the
+// requesters will be unable to verify the new versions.
 type UpdateRequest struct {
 	Label []byte
 
 	// StartingVersion is the version that should be assigned to the first
 	// element of `Values`, if any. This is used in Third-Party Management mode
 	// to align version counters assigned by the Service Operator and the
-	// Manager. If this is less than what would be assigned naturally, empty or
-	// tombstone versions need to be sequenced such that they match. If this is
-	// greater than what would be assigned, an error condition has occured and
+	// Manager. If this is greater than what would be assigned naturally, empty
+	// or tombstone versions need to be sequenced such that they match, unless
+	// Third-Party Management is in use: clients require a signature from the
+	// Service Operator on every version, so the request should be rejected and
+	// the Service Operator needs to sign the tombstone versions itself. If this
+	// is less than what would be assigned, an error condition has occured and
 	// the request should be rejected.
 	StartingVersion *uint32
 
@@ -41,11 +45,20 @@ type UpdateRequest struct {
 	// version counters should be assigned.
 	Values []structs.UpdateValue
 
-	// Once the new versions of the label are created, the log entry where the
-	// new versions were inserted is sent over `Response`. If creating the new
-	// versions fails, `Response` is closed with no value sent. This channel
-	// should have a buffer size of 1 to prevent blocking writes.
-	Response chan<- uint64
+	// Once the new versions of the label are created, an UpdateResult is sent
+	// over `Response`. If creating the new versions fails, `Response` is closed
+	// with no value sent. This channel should have a buffer size of 1 to
+	// prevent blocking writes.
+	Response chan<- UpdateResult
+}
+
+// UpdateResult is sent in response to an UpdateRequest once the new versions of
+// the label are created.
+type UpdateResult struct {
+	// Position is the log entry where the new versions were inserted.
+	Position uint64
+	// Version is the version assigned to the first element of `Values`.
+	Version uint32
 }
 
 // Tree is an implementation of a Transparency Tree that handles all state
@@ -78,7 +91,10 @@ func NewTree(
 		auditorHead *structs.AuditorTreeHead
 	)
 	if rawTreeHead != nil {
-		buf := bytes.NewBuffer(rawTreeHead)
+		buf, err := structs.NewDecoder(rawTreeHead, structs.DefaultLimits)
+		if err != nil {
+			return nil, err
+		}
 		treeHead, err = structs.NewTreeHead(buf)
 		if err != nil {
 			return nil, err
@@ -87,7 +103,10 @@ func NewTree(
 		}
 	}
 	if config.Mode == structs.ThirdPartyAuditing && rawAuditor != nil {
-		buf := bytes.NewBuffer(rawAuditor)
+		buf, err := structs.NewDecoder(rawAuditor, structs.DefaultLimits)
+		if err != nil {
+			return nil, err
+		}
 		auditorHead, err = structs.NewAuditorTreeHead(buf)
 		if err != nil {
 			return nil, err
@@ -107,12 +126,16 @@ func NewTree(
 
 func (t *Tree) TreeHead() *structs.TreeHead { return t.treeHead }
 
+// AuditorTreeHead returns the most recent tree head from the Third-Party
+// Auditor, or nil if there is none.
+func (t *Tree) AuditorTreeHead() *structs.AuditorTreeHead { return t.auditorHead }
+
 func (t *Tree) fullTreeHead(last *uint64) (fth *structs.FullTreeHead, n uint64, nP, m *uint64, err error) {
 	if t.treeHead == nil {
 		return nil, 0, nil, nil, errors.New("can not operate on an empty tree")
 	} else if last != nil {
 		if *last == t.treeHead.TreeSize {
-			return &structs.FullTreeHead{}, t.treeHead.TreeSize, nil, nil, nil
+			return &structs.FullTreeHead{}, t.treeHead.TreeSize, nil, last, nil
 		} else if *last > t.treeHead.TreeSize {
 			return nil, 0, nil, nil, errors.New("tree size advertised by user is greater than current tree size")
 		}
@@ -144,7 +167,10 @@ func (t *Tree) updateView(last *uint64, provider *algorithms.DataProvider) error
 			if !ok {
 				return errors.New("expected frontier log entry not found")
 			}
-			buf := bytes.NewBuffer(raw)
+			buf, err := structs.NewDecoder(raw, structs.DefaultLimits)
+			if err != nil {
+				return err
+			}
 			entry, err := structs.NewLogEntry(t.config.Suite, buf)
 			if err != nil {
 				return err
@@ -164,71 +190,123 @@ func (t *Tree) Search(
 	ctx context.Context,
 	req *structs.SearchRequest,
 ) (*structs.SearchResponse, error) {
-	fth, n, nP, m, err := t.fullTreeHead(req.Last)
+	res, err := t.search(req.Last, []structs.BatchSearchEntry{{Label: req.Label, Version: req.Version}})
 	if err != nil {
 		return nil, err
 	}
+	result := res.Results[0]
 
-	// Load label index and determine greatest version that exists, if any.
-	indices, err := t.batchGetIndex([][]byte{req.Label})
+	return &structs.SearchResponse{
+		FullTreeHead: res.FullTreeHead,
+
+		Version: result.Version,
+		Opening: result.Opening,
+		Value:   result.Value,
+
+		BinaryLadder: result.BinaryLadder,
+		Search:       res.Search,
+	}, nil
+}
+
+// BatchSearch executes a search for each label in the request, and returns a
+// single CombinedTreeProof that covers all of them. This is equivalent to, but
+// much smaller than, executing a separate Search for each label.
+func (t *Tree) BatchSearch(
+	ctx context.Context,
+	req *structs.BatchSearchRequest,
+) (*structs.BatchSearchResponse, error) {
+	if len(req.Entries) == 0 {
+		return nil, errors.New("no labels provided in batch search request")
+	} else if len(req.Entries) > structs.DefaultLimits.MaxBatchSearchEntries {
+		return nil, errors.New("too many labels provided in batch search request")
+	}
+	return t.search(req.Last, req.Entries)
+}
+
+// search executes a greatest-version or fixed-version search for each of the
+// given entries, depending on whether a version is specified.
+func (t *Tree) search(last *uint64, entries []structs.BatchSearchEntry) (*structs.BatchSearchResponse, error) {
+	fth, n, nP, m, err := t.fullTreeHead(last)
 	if err != nil {
 		return nil, err
 	}
-	greatest := len(indices[0]) - 1
-
-	handle := algorithms.NewProducedProofHandle(t.config.Suite, t.tx, indices[0])
 
-	// Determine which versions we will need VRF outputs for, and also load the
-	// target version of the label.
-	var (
-		ladder          []uint32
-		openingAndValue *structs.OpeningAndValue
-	)
-	if req.Version == nil {
-		if greatest < 0 {
-			ladder = []uint32{0}
-			openingAndValue, err = t.getVersion(req.Label, 0)
-		} else {
-			ladder = math.SearchBinaryLadder(uint32(greatest), uint32(greatest), nil, nil)
-			openingAndValue, err = t.getVersion(req.Label, uint32(greatest))
-		}
-	} else {
-		ladder = math.SearchBinaryLadder(*req.Version, *req.Version, nil, nil)
-		openingAndValue, err = t.getVersion(req.Label, *req.Version)
+	// Load the index of each label to determine the greatest version that
+	// exists, if any.
+	labels := make([][]byte, len(entries))
+	for i, entry := range entries {
+		labels[i] = entry.Label
 	}
+	indices, err := t.batchGetIndex(labels)
 	if err != nil {
 		return nil, err
 	}
+	greatest := make([]int, len(entries))
+	for i, index := range indices {
+		greatest[i] = len(index) - 1
+	}
 
-	// Compute VRF outputs and proofs for ladder.
-	steps := make([]structs.BinaryLadderStep, len(ladder))
-	for i, ver := range ladder {
-		vrfOutput, proof, err := t.computeVrfOutput(req.Label, ver)
-		if err != nil {
+	handle := algorithms.NewBatchProducedProofHandle(t.config.Suite, t.tx, indices)
+
+	results := make([]structs.BatchSearchResult, len(entries))
+	targets := make([]uint32, len(entries))
+	ladders := make([][]uint32, len(entries))
+	for i, entry := range entries {
+		if err := handle.SelectLabel(i); err != nil {
 			return nil, err
-		} else if err := handle.AddVersion(ver, vrfOutput); err != nil {
+		}
+
+		// Determine which versions we will need VRF outputs for, and also load
+		// the target version of the label.
+		if entry.Version != nil {
+			targets[i] = *entry.Version
+			ladders[i] = math.SearchBinaryLadder(targets[i], targets[i], nil, nil)
+		} else if greatest[i] < 0 {
+			ladders[i] = []uint32{0}
+			results[i].Version = &targets[i]
+		} else {
+			targets[i] = uint32(greatest[i])
+			ladders[i] = math.SearchBinaryLadder(targets[i], targets[i], nil, nil)
+			results[i].Version = &targets[i]
+		}
+		openingAndValue, err := t.getVersion(entry.Label, targets[i])
+		if err != nil {
 			return nil, err
 		}
-		steps[i] = structs.BinaryLadderStep{Proof: proof}
+		results[i].Opening = openingAndValue.Opening
+		results[i].Value = openingAndValue.Value
+
+		// Compute VRF outputs and proofs for ladder.
+		results[i].BinaryLadder = make([]structs.BinaryLadderStep, len(ladders[i]))
+		for j, ver := range ladders[i] {
+			vrfOutput, proof, err := t.computeVrfOutput(entry.Label, ver)
+			if err != nil {
+				return nil, err
+			} else if err := handle.AddVersion(ver, vrfOutput); err != nil {
+				return nil, err
+			}
+			results[i].BinaryLadder[j] = structs.BinaryLadderStep{Proof: proof}
+		}
 	}
 
-	// Execute the algorithm to update the user's view of the tree, and then
-	// either a greatest-version or fixed-version search.
+	// Execute the algorithm to update the user's view of the tree once, and
+	// then either a greatest-version or fixed-version search for each label.
 	provider := algorithms.NewDataProvider(t.config.Suite, handle)
-	if err := t.updateView(req.Last, provider); err != nil {
+	if err := t.updateView(last, provider); err != nil {
 		return nil, err
 	}
-	if req.Version == nil {
-		if greatest < 0 {
-			_, err = algorithms.GreatestVersionSearch(t.config.Public(), 0, t.treeHead.TreeSize, provider)
+	for i, entry := range entries {
+		if err := provider.SelectLabel(i); err != nil {
+			return nil, err
+		}
+		if entry.Version == nil {
+			_, err = algorithms.GreatestVersionSearch(t.config.Public(), targets[i], t.treeHead.TreeSize, provider)
 		} else {
-			_, err = algorithms.GreatestVersionSearch(t.config.Public(), uint32(greatest), t.treeHead.TreeSize, provider)
+			_, err = algorithms.FixedVersionSearch(t.config.Public(), targets[i], t.treeHead.TreeSize, provider)
+		}
+		if err != nil && err != algorithms.ErrLabelNotFound {
+			return nil, err
 		}
-	} else {
-		_, err = algorithms.FixedVersionSearch(t.config.Public(), *req.Version, t.treeHead.TreeSize, provider)
-	}
-	if err != nil && err != algorithms.ErrLabelNotFound {
-		return nil, err
 	}
 	combinedProof, err := provider.Output(n, nP, m)
 	if err != nil {
@@ -236,34 +314,222 @@ func (t *Tree) Search(
 	}
 
 	// Populate commitment field of appropriate BinaryLadderStep structures.
-	for i, ver := range ladder {
-		populate := req.Version == nil && int(ver) != greatest ||
-			req.Version != nil && ver != *req.Version
+	for i, entry := range entries {
+		if err := handle.SelectLabel(i); err != nil {
+			return nil, err
+		}
+		for j, ver := range ladders[i] {
+			populate := entry.Version == nil && int(ver) != greatest[i] ||
+				entry.Version != nil && ver != *entry.Version
 
-		if populate {
-			steps[i].Commitment = handle.GetCommitment(ver)
+			if populate {
+				results[i].BinaryLadder[j].Commitment = handle.GetCommitment(ver)
+			}
 		}
 	}
 
-	// Put together final SearchResponse structure.
-	var outputVer *uint32
-	if req.Version == nil {
-		var ver uint32
-		if greatest < 0 {
-			ver = 0
-		} else {
-			ver = uint32(greatest)
+	return &structs.BatchSearchResponse{
+		FullTreeHead: *fth,
+
+		Results: results,
+		Search:  *combinedProof,
+	}, nil
+}
+
+// historyPageSize is the maximum number of versions of a label that are
+// returned in a single HistoryResponse. It is limited by the number of prefix
+// proofs and timestamps that fit in a CombinedTreeProof.
+const historyPageSize = 32
+
+// History returns a page of the history of a label: each version of the label
+// starting from the requested version, along with the position of the log
+// entry where the version was added. It also executes a greatest-version
+// search, so that the client knows how many versions there are in total.
+//
+// Like Search, it returns ErrLabelExpired if the requested versions were added
+// in log entries that have expired.
+func (t *Tree) History(
+	ctx context.Context,
+	req *structs.HistoryRequest,
+) (*structs.HistoryResponse, error) {
+	fth, n, nP, m, err := t.fullTreeHead(req.Last)
+	if err != nil {
+		return nil, err
+	}
+
+	indices, err := t.batchGetIndex([][]byte{req.Label})
+	if err != nil {
+		return nil, err
+	}
+	index := indices[0]
+	if len(index) == 0 {
+		return nil, algorithms.ErrLabelNotFound
+	}
+	greatest := uint32(len(index) - 1)
+	if req.Start > greatest {
+		return nil, errors.New("start version is greater than greatest version of label")
+	}
+	count := min(greatest-req.Start+1, historyPageSize)
+
+	// The proof is produced as though the greatest-version search, and the
+	// position of each version, are for separate labels.
+	handleIndices := make([][]uint64, count+1)
+	for i := range handleIndices {
+		handleIndices[i] = index
+	}
+	handle := algorithms.NewBatchProducedProofHandle(t.config.Suite, t.tx, handleIndices)
+
+	type vrfResult struct{ output, proof []byte }
+	vrfResults := make(map[uint32]vrfResult)
+	addLadder := func(ladder []uint32) ([]structs.BinaryLadderStep, error) {
+		steps := make([]structs.BinaryLadderStep, len(ladder))
+		for i, ver := range ladder {
+			res, ok := vrfResults[ver]
+			if !ok {
+				output, proof, err := t.computeVrfOutput(req.Label, ver)
+				if err != nil {
+					return nil, err
+				}
+				res = vrfResult{output, proof}
+				vrfResults[ver] = res
+			}
+			if err := handle.AddVersion(ver, res.output); err != nil {
+				return nil, err
+			}
+			steps[i] = structs.BinaryLadderStep{Proof: res.proof}
 		}
-		outputVer = &ver
+		return steps, nil
 	}
-	return &structs.SearchResponse{
+
+	greatestLadder := math.SearchBinaryLadder(greatest, greatest, nil, nil)
+	greatestSteps, err := addLadder(greatestLadder)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]structs.HistoryEntry, count)
+	for i := range entries {
+		ver := req.Start + uint32(i)
+		if err := handle.SelectLabel(i + 1); err != nil {
+			return nil, err
+		}
+		openingAndValue, err := t.getVersion(req.Label, ver)
+		if err != nil {
+			return nil, err
+		}
+		steps, err := addLadder(math.SearchBinaryLadder(ver, ver, nil, nil))
+		if err != nil {
+			return nil, err
+		}
+		entries[i] = structs.HistoryEntry{
+			Position:     index[ver],
+			Opening:      openingAndValue.Opening,
+			Value:        openingAndValue.Value,
+			BinaryLadder: steps,
+		}
+	}
+
+	// Execute the algorithm to update the user's view of the tree, a
+	// greatest-version search, and then prove the position of each version.
+	provider := algorithms.NewDataProvider(t.config.Suite, handle)
+	if err := t.updateView(req.Last, provider); err != nil {
+		return nil, err
+	} else if err := provider.SelectLabel(0); err != nil {
+		return nil, err
+	}
+	_, err = algorithms.GreatestVersionSearch(t.config.Public(), greatest, t.treeHead.TreeSize, provider)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range entries {
+		if err := provider.SelectLabel(i + 1); err != nil {
+			return nil, err
+		}
+		ver := req.Start + uint32(i)
+		if err := algorithms.VersionPosition(t.config.Public(), ver, entry.Position, t.treeHead.TreeSize, provider); err != nil {
+			return nil, err
+		}
+	}
+	combinedProof, err := provider.Output(n, nP, m)
+	if err != nil {
+		return nil, err
+	}
+
+	// Populate the commitment field of each BinaryLadderStep, except for the
+	// versions whose value is provided.
+	if err := handle.SelectLabel(0); err != nil {
+		return nil, err
+	}
+	for i, ver := range greatestLadder {
+		greatestSteps[i].Commitment = handle.GetCommitment(ver)
+	}
+	for i := range entries {
+		if err := handle.SelectLabel(i + 1); err != nil {
+			return nil, err
+		}
+		target := req.Start + uint32(i)
+		for j, ver := range math.SearchBinaryLadder(target, target, nil, nil) {
+			if ver != target {
+				entries[i].BinaryLadder[j].Commitment = handle.GetCommitment(ver)
+			}
+		}
+	}
+
+	return &structs.HistoryResponse{
 		FullTreeHead: *fth,
 
-		Version: outputVer,
-		Opening: openingAndValue.Opening,
-		Value:   openingAndValue.Value,
+		Version:      greatest,
+		BinaryLadder: greatestSteps,
+
+		Entries: entries,
+		Search:  *combinedProof,
+	}, nil
+}
+
+// Consistency returns a proof that the Log Tree of size `Older` is a prefix of
+// the Log Tree of size `Newer`. Unlike the other operations, it doesn't depend
+// on any client state, so it's suitable for witnesses and auditors that only
+// track tree heads.
+func (t *Tree) Consistency(
+	ctx context.Context,
+	req *structs.ConsistencyRequest,
+) (*structs.ConsistencyResponse, error) {
+	if t.treeHead == nil {
+		return nil, errors.New("can not operate on an empty tree")
+	} else if req.Newer > t.treeHead.TreeSize {
+		return nil, errors.New("requested tree size is greater than current tree size")
+	}
+	logTree := log.NewTree(t.config.Suite, t.tx.LogStore())
+	proof, err := logTree.GetConsistency(req.Older, req.Newer)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the current tree head is stored, so tree heads for other sizes are
+	// signed on demand.
+	treeHeads := make([]structs.TreeHead, 2)
+	for i, n := range []uint64{req.Older, req.Newer} {
+		if n == t.treeHead.TreeSize {
+			treeHeads[i] = *t.treeHead
+			continue
+		}
+		fullSubtrees, err := logTree.GetFullSubtrees(n)
+		if err != nil {
+			return nil, err
+		}
+		root, err := log.Root(t.config.Suite, n, fullSubtrees)
+		if err != nil {
+			return nil, err
+		}
+		treeHead, err := t.signTreeHead(n, root)
+		if err != nil {
+			return nil, err
+		}
+		treeHeads[i] = *treeHead
+	}
 
-		BinaryLadder: steps,
-		Search:       *combinedProof,
+	return &structs.ConsistencyResponse{
+		Older: treeHeads[0],
+		Newer: treeHeads[1],
+		Proof: proof,
 	}, nil
 }
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"errors"
 	"slices"
 	"testing"
+	"time"
 
 	"github.com/Bren2010/katie/db/memory"
+	"github.com/Bren2010/katie/tree/log"
+	"github.com/Bren2010/katie/tree/transparency/algorithms"
 	"github.com/Bren2010/katie/tree/transparency/math"
 	"github.com/Bren2010/katie/tree/transparency/structs"
 	"github.com/Bren2010/katie/tree/transparency/test"
@@ -140,3 +144,529 @@ func TestSearch(t *testing.T) {
 	}
 	verifySearchResponse(t, res, false, nil, []byte{1}, 1, 6, []uint32{1, 2})
 }
+
+func TestSearchRepeated(t *testing.T) {
+	ctx := context.Background()
+	tree, labels := generateRandomTree(t)
+	client, err := NewClient(tree.config.Public(), memory.NewClientStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second search provides versions that the client retained from the
+	// first one.
+	for range 2 {
+		req, verify, err := client.GreatestVersionSearch(labels[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := tree.Search(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := verify(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBatchSearch(t *testing.T) {
+	ctx := context.Background()
+	tree, labels := generateRandomTree(t)
+	client, err := NewClient(tree.config.Public(), memory.NewClientStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The result for each label should be the same as from a separate search,
+	// while the proof is shared.
+	ver := uint32(2)
+	entries := []structs.BatchSearchEntry{
+		{Label: labels[0]},
+		{Label: labels[1], Version: &ver},
+		{Label: labels[2]},
+	}
+	req, verify, err := client.BatchSearch(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tree.BatchSearch(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Results) != len(entries) {
+		t.Fatalf("unexpected number of results: %v", len(res.Results))
+	}
+	separate := 0
+	for i, entry := range entries {
+		single, err := tree.Search(ctx, &structs.SearchRequest{Label: entry.Label, Version: entry.Version})
+		if err != nil {
+			t.Fatal(err)
+		}
+		separate += len(single.Search.Timestamps)
+
+		result := res.Results[i]
+		if entry.Version == nil {
+			verifySearchResponse(t, &structs.SearchResponse{
+				FullTreeHead: res.FullTreeHead,
+				Version:      result.Version,
+				Value:        result.Value,
+				BinaryLadder: result.BinaryLadder,
+			}, true, single.Version, []byte{6}, 6, 6, []uint32{6})
+		} else {
+			verifySearchResponse(t, &structs.SearchResponse{
+				FullTreeHead: res.FullTreeHead,
+				Version:      result.Version,
+				Value:        result.Value,
+				BinaryLadder: result.BinaryLadder,
+			}, true, nil, []byte{2}, 2, 6, []uint32{2})
+		}
+	}
+	if len(res.Search.Timestamps) >= separate {
+		t.Fatal("batch search proof does not share timestamps between labels")
+	}
+
+	// Results given out of order fail verification.
+	swapped := *res
+	swapped.Results = []structs.BatchSearchResult{res.Results[2], res.Results[1], res.Results[0]}
+	if err := verify(&swapped); err == nil {
+		t.Fatal("expected verification of reordered results to fail")
+	}
+	req, verify, err = client.BatchSearch(entries)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := verify(res); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the client has state, the tree head is omitted from the response.
+	req, verify, err = client.BatchSearch(entries[:2])
+	if err != nil {
+		t.Fatal(err)
+	} else if req.Last == nil {
+		t.Fatal("expected client to advertise tree size")
+	}
+	res, err = tree.BatchSearch(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.FullTreeHead.TreeHead != nil {
+		t.Fatal("tree head provided when none expected")
+	} else if err := verify(res); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tree.BatchSearch(ctx, &structs.BatchSearchRequest{}); err == nil {
+		t.Fatal("expected empty batch search to fail")
+	}
+	tooMany := make([]structs.BatchSearchEntry, structs.DefaultLimits.MaxBatchSearchEntries+1)
+	for i := range tooMany {
+		tooMany[i] = structs.BatchSearchEntry{Label: labels[0]}
+	}
+	if _, err := tree.BatchSearch(ctx, &structs.BatchSearchRequest{Entries: tooMany}); err == nil {
+		t.Fatal("expected oversized batch search to fail")
+	}
+}
+
+// checkJSON checks that `x` is unchanged after a round trip through its JSON
+// encoding, where `newF` decodes the binary encoding.
+func checkJSON[T any, P interface {
+	*T
+	structs.Marshaller
+}](t *testing.T, x P, newF func(*structs.Decoder) (*T, error)) {
+	t.Helper()
+
+	raw, err := structs.MarshalJSON(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := structs.UnmarshalJSON[T, P](raw, structs.DefaultLimits, newF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := structs.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := structs.Marshal(P(parsed))
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(expected, actual) {
+		t.Fatalf("%T changed after round trip through JSON", x)
+	}
+}
+
+func TestSearchJSON(t *testing.T) {
+	ctx := context.Background()
+	tree, labels := generateRandomTree(t)
+	config := tree.config.Public()
+
+	// With and without a new tree head.
+	size := tree.treeHead.TreeSize
+	for _, last := range []*uint64{nil, &size} {
+		req := &structs.SearchRequest{Last: last, Label: labels[0]}
+		res, err := tree.Search(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		} else if (last == nil) != (res.FullTreeHead.TreeHead != nil) {
+			t.Fatal("unexpected full tree head returned")
+		}
+		checkJSON(t, res, func(buf *structs.Decoder) (*structs.SearchResponse, error) {
+			return structs.NewSearchResponse(config, req, buf)
+		})
+	}
+
+	batchReq := &structs.BatchSearchRequest{Entries: []structs.BatchSearchEntry{
+		{Label: labels[0]},
+		{Label: labels[1]},
+	}}
+	batchRes, err := tree.BatchSearch(ctx, batchReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, batchRes, func(buf *structs.Decoder) (*structs.BatchSearchResponse, error) {
+		return structs.NewBatchSearchResponse(config, batchReq, buf)
+	})
+}
+
+func TestResponseJSON(t *testing.T) {
+	ctx := context.Background()
+	tree, labels := generateRandomTree(t)
+	config := tree.config.Public()
+
+	historyRes, err := tree.History(ctx, &structs.HistoryRequest{Label: labels[0], Start: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, historyRes, func(buf *structs.Decoder) (*structs.HistoryResponse, error) {
+		return structs.NewHistoryResponse(config, buf)
+	})
+
+	consistencyRes, err := tree.Consistency(ctx, &structs.ConsistencyRequest{Older: 3, Newer: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, consistencyRes, func(buf *structs.Decoder) (*structs.ConsistencyResponse, error) {
+		return structs.NewConsistencyResponse(config, buf)
+	})
+
+	client, err := NewClient(config, memory.NewClientStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	searchReq, verify, err := client.GreatestVersionSearch(labels[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	searchRes, err := tree.Search(ctx, searchReq)
+	if err != nil {
+		t.Fatal(err)
+	} else if err := verify(searchRes); err != nil {
+		t.Fatal(err)
+	}
+	initReq, _, err := client.OwnerInit(labels[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	initRes, err := tree.OwnerInit(ctx, initReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkJSON(t, initRes, func(buf *structs.Decoder) (*structs.OwnerInitResponse, error) {
+		return structs.NewOwnerInitResponse(config, buf)
+	})
+
+	// The new version is sent without its value, since it's the only version
+	// in its log entry. If it had been merged with other versions, the values
+	// would be sent along with the index of the submitted version.
+	label, zero := []byte("label"), uint32(0)
+	_, load := newTestLog(t, label)
+	ch, err := load().Update(ctx, &structs.UpdateRequest{
+		Label:           label,
+		GreatestVersion: &zero,
+		Values:          []structs.LabelValue{{Value: []byte("version 1")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var updateRes []*structs.UpdateResponse
+	for res := range ch {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		updateRes = append(updateRes, res.Out)
+	}
+	if len(updateRes) != 1 || len(updateRes[0].Values) != 0 {
+		t.Fatal("unexpected update responses")
+	}
+	merged, submitted := *updateRes[0], uint8(0)
+	merged.Values = []structs.LabelValue{{Value: []byte("version 1")}}
+	merged.Submitted = &submitted
+	for _, res := range []*structs.UpdateResponse{updateRes[0], &merged} {
+		checkJSON(t, res, func(buf *structs.Decoder) (*structs.UpdateResponse, error) {
+			return structs.NewUpdateResponse(config, buf)
+		})
+	}
+}
+
+func TestAuditorUpdateJSON(t *testing.T) {
+	tree, labels := generateRandomTree(t)
+	cs := tree.config.Suite
+
+	update, err := tree.Mutate([]LabelValue{
+		{Label: labels[0], Value: structs.UpdateValue{Value: []byte("new")}},
+		{Label: []byte("new label"), Value: structs.UpdateValue{Value: []byte("new")}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(update.Added) != 2 || len(update.Proof.Results) == 0 {
+		t.Fatal("unexpected auditor update")
+	}
+	checkJSON(t, update, func(buf *structs.Decoder) (*structs.AuditorUpdate, error) {
+		return structs.NewAuditorUpdate(cs, buf)
+	})
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	tree, labels := generateRandomTree(t)
+	client, err := NewClient(tree.config.Public(), memory.NewClientStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, verify, err := client.History(labels[0], 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tree.History(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Version != 6 {
+		t.Fatalf("unexpected greatest version: %v", res.Version)
+	} else if len(res.Entries) != 5 {
+		t.Fatalf("unexpected number of entries: %v", len(res.Entries))
+	}
+	for i, entry := range res.Entries {
+		// Each version of each label was added in a separate log entry.
+		if entry.Position != uint64(i+2) {
+			t.Fatalf("unexpected position for version %v: %v", i+2, entry.Position)
+		} else if !bytes.Equal(entry.Value.Value, []byte{byte(i + 2)}) {
+			t.Fatalf("unexpected value for version %v", i+2)
+		}
+	}
+
+	// A response claiming the wrong position for a version fails verification.
+	tampered := *res
+	tampered.Entries = slices.Clone(res.Entries)
+	tampered.Entries[1].Position++
+	if err := verify(&tampered); err == nil {
+		t.Fatal("expected tampered history to fail verification")
+	}
+	if err := verify(res); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requesting versions past the greatest version fails.
+	req, _, err = client.History(labels[0], 7)
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err := tree.History(ctx, req); err == nil {
+		t.Fatal("expected history past greatest version to fail")
+	}
+}
+
+func TestHistoryExpired(t *testing.T) {
+	ctx := context.Background()
+	tree, _, clock := newReaperTree(t)
+	label := []byte("label")
+	mutate := func(val byte) {
+		t.Helper()
+		add := []LabelValue{{Label: label, Value: structs.UpdateValue{Value: []byte{val}}}}
+		if _, err := tree.Mutate(add, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mutate(0)
+	mutate(1)
+	clock.Advance(8 * 24 * time.Hour)
+	mutate(2)
+	mutate(3)
+
+	client, err := NewClient(tree.config.Public(), memory.NewClientStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Versions that were added in expired log entries can't be requested.
+	for _, start := range []uint32{0, 1} {
+		req, _, err := client.History(label, start)
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err := tree.History(ctx, req); !errors.Is(err, algorithms.ErrLabelExpired) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Versions that were added in unexpired log entries can be.
+	req, verify, err := client.History(label, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tree.History(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Entries) != 2 || res.Entries[0].Position != 2 {
+		t.Fatal("unexpected history entries returned")
+	} else if err := verify(res); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHistoryPages(t *testing.T) {
+	ctx := context.Background()
+	config := test.Config(t)
+	tree, err := NewTree(config, memory.NewTransparencyStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	label := []byte("label")
+	for i := range historyPageSize + 8 {
+		add := []LabelValue{{Label: label, Value: structs.UpdateValue{Value: []byte{byte(i)}}}}
+		if _, err := tree.Mutate(add, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	client, err := NewClient(config.Public(), memory.NewClientStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start, seen := uint32(0), 0
+	for {
+		req, verify, err := client.History(label, start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := tree.History(ctx, req)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := verify(res); err != nil {
+			t.Fatal(err)
+		}
+		seen++
+		start += uint32(len(res.Entries))
+		if start > res.Version {
+			break
+		}
+	}
+	if seen != 2 || start != historyPageSize+8 {
+		t.Fatalf("unexpected pages: %v, %v", seen, start)
+	}
+}
+
+func TestConsistency(t *testing.T) {
+	tree, _ := generateRandomTree(t)
+	cs := tree.config.Suite
+	n := tree.treeHead.TreeSize
+
+	logTree := log.NewTree(cs, tree.tx.LogStore())
+	roots := make([][]byte, n+1)
+	for size := uint64(1); size <= n; size++ {
+		fullSubtrees, err := logTree.GetFullSubtrees(size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots[size], err = log.Root(cs, size, fullSubtrees)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	config := tree.config.Public()
+	for older := uint64(1); older <= n; older++ {
+		for newer := older; newer <= n; newer++ {
+			req := &structs.ConsistencyRequest{Older: older, Newer: newer}
+			res, err := tree.Consistency(context.Background(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			root, err := VerifyConsistency(config, req, res, roots[older])
+			if err != nil {
+				t.Fatal(err)
+			} else if !bytes.Equal(root, roots[newer]) {
+				t.Fatal("unexpected root returned")
+			}
+
+			// Tree heads that were signed for other sizes fail verification.
+			if older == newer {
+				continue
+			}
+			swapped := *res
+			swapped.Older, swapped.Newer = res.Newer, res.Older
+			if _, err := VerifyConsistency(config, req, &swapped, roots[older]); err == nil {
+				t.Fatal("expected error verifying swapped tree heads")
+			}
+			tampered := *res
+			tampered.Newer.Signature = res.Older.Signature
+			if _, err := VerifyConsistency(config, req, &tampered, roots[older]); err == nil {
+				t.Fatal("expected error verifying tampered tree head")
+			}
+		}
+	}
+
+	_, err := tree.Consistency(context.Background(), &structs.ConsistencyRequest{Older: 1, Newer: n + 1})
+	if err == nil {
+		t.Fatal("expected error requesting tree size beyond current tree size")
+	}
+}
+
+// TestP384 checks that searches work end to end with a cipher suite whose hash
+// size isn't 32 bytes, including encoding and decoding the response.
+func TestP384(t *testing.T) {
+	config := test.P384Config(t)
+	tree, err := NewTree(config, memory.NewTransparencyStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(config.Public(), memory.NewClientStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	label := []byte("label")
+	for i := range 5 {
+		_, err := tree.Mutate([]LabelValue{
+			{Label: label, Value: structs.UpdateValue{Value: []byte{byte(i)}}},
+			{Label: []byte{byte(i)}, Value: structs.UpdateValue{Value: []byte{byte(i)}}},
+		}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, verify, err := client.GreatestVersionSearch(label)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := tree.Search(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, err := structs.Marshal(res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf, err := structs.NewDecoder(raw, structs.DefaultLimits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err = structs.NewSearchResponse(config.Public(), req, buf)
+		if err != nil {
+			t.Fatal(err)
+		} else if buf.Len() != 0 {
+			t.Fatal("unexpected data appended to search response")
+		} else if err := verify(res); err != nil {
+			t.Fatal(err)
+		} else if *res.Version != uint32(i) || !bytes.Equal(res.Value.Value, []byte{byte(i)}) {
+			t.Fatal("unexpected search result")
+		}
+	}
+}
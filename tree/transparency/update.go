@@ -5,6 +5,7 @@ import (
 	"errors"
 	"slices"
 
+	"github.com/Bren2010/katie/db"
 	"github.com/Bren2010/katie/tree/transparency/algorithms"
 	"github.com/Bren2010/katie/tree/transparency/math"
 	"github.com/Bren2010/katie/tree/transparency/structs"
@@ -87,6 +88,8 @@ type updater struct {
 
 	index []uint64 // index is the label's index.
 	ver   int      // ver is the next version the user needs to be informed about.
+
+	snapshot db.TransparencyStore // snapshot is the store that `tree` was reloaded over, if any.
 }
 
 func newUpdater(t *Tree, ctx context.Context, ch chan wire.UpdateResponse) *updater {
@@ -161,12 +164,17 @@ func (u *updater) send(res wire.UpdateResponse) bool {
 
 func (u *updater) process() {
 	defer close(u.ch)
+	defer func() {
+		if u.snapshot != nil {
+			u.snapshot.Release()
+		}
+	}()
 
 	// If the greatest version that was advertised by the user is less than the
 	// actual greatest version, first push out UpdateResponses for the
 	// unknown versions.
 	for u.ver < len(u.index) {
-		out, err := u.next(true)
+		out, err := u.next(nil)
 		if ok := u.send(wire.UpdateResponse{Out: out, Err: err}); !ok {
 			return
 		} else if err != nil {
@@ -186,7 +194,7 @@ func (u *updater) process() {
 		})
 		return
 	}
-	res := make(chan uint64, 1)
+	res := make(chan UpdateResult, 1)
 	req := UpdateRequest{
 		Label:           u.label,
 		StartingVersion: u.signedVer,
@@ -201,11 +209,11 @@ func (u *updater) process() {
 	}
 
 	var (
-		pos uint64
-		ok  bool
+		result UpdateResult
+		ok     bool
 	)
 	select {
-	case pos, ok = <-res:
+	case result, ok = <-res:
 		if !ok {
 			u.send(wire.UpdateResponse{
 				Err: errors.New("failed to sequence requested new versions of label"),
@@ -215,7 +223,7 @@ func (u *updater) process() {
 	case <-u.ctx.Done():
 		return
 	}
-	if err := u.reloadTree(pos); err != nil {
+	if err := u.reloadTree(result.Position); err != nil {
 		u.send(wire.UpdateResponse{Err: err})
 		return
 	}
@@ -223,7 +231,11 @@ func (u *updater) process() {
 	// Push out the UpdateResponse for our new version of the label, and any
 	// others that were created concurrently.
 	for u.ver < len(u.index) {
-		out, err := u.next(u.index[u.ver] != pos)
+		var submitted *uint32
+		if u.index[u.ver] == result.Position {
+			submitted = &result.Version
+		}
+		out, err := u.next(submitted)
 		if ok := u.send(wire.UpdateResponse{Out: out, Err: err}); !ok {
 			return
 		} else if err != nil {
@@ -235,13 +247,31 @@ func (u *updater) process() {
 	}
 }
 
-func (u *updater) next(withValues bool) (*structs.UpdateResponse, error) {
-	t, pos := u.tree, u.index[u.ver]
+// next returns the UpdateResponse for the next log entry where new versions of
+// the label were created. If the log entry contains the versions submitted by
+// the user, `submitted` is the version assigned to the first of them.
+func (u *updater) next(submitted *uint32) (*structs.UpdateResponse, error) {
+	t, pos, startVer := u.tree, u.index[u.ver], u.ver
 
-	values, info, err := u.infos(pos, withValues)
+	values, info, err := u.infos(pos)
 	if err != nil {
 		return nil, err
 	}
+
+	// Values are omitted only if every version in the log entry was submitted
+	// by the user. Otherwise, the user is told where its versions start.
+	var offset *uint8
+	if submitted != nil {
+		start := int(*submitted) - startVer
+		if start < 0 || start+len(u.values) > len(info) {
+			return nil, errors.New("submitted versions not found in log entry")
+		} else if len(info) == len(u.values) {
+			values = nil
+		} else {
+			off := uint8(start)
+			offset = &off
+		}
+	}
 	ladder, err := u.ladder(u.ver-len(info), u.ver-1)
 	if err != nil {
 		return nil, err
@@ -261,9 +291,30 @@ func (u *updater) next(withValues bool) (*structs.UpdateResponse, error) {
 	monitor, err := algorithms.NewMonitor(t.config.Public(), n, provider)
 	if err != nil {
 		return nil, err
-	} else if err := monitor.Update(pos, len(info)); err != nil {
+	}
+	// Reconstruct the owner state the user has before these new versions. The
+	// server doesn't know the user's actual starting position, but the Update
+	// algorithm only depends on the greatest version, the position of the last
+	// update, and the greatest version at the parent of the first log entry it
+	// inspects. The user's starting position is distinguished and entries are
+	// only inspected below the first non-distinguished ancestor of `pos-1`, so
+	// none of the inspected log entries or that parent are to the left of it,
+	// and the label's index gives the same answers as the user's state.
+	monitor.Owner = &algorithms.OwnerState{
+		VerAtStarting: -1,
+		UpcomingVers:  slices.Clone(u.index[:u.ver-len(info)]),
+	}
+	if err := monitor.Update(pos, len(info)); err != nil {
 		return nil, err
 	}
+	for ver := range handle.RequiredVersions() {
+		vrfOutput, _, err := t.computeVrfOutput(u.label, ver)
+		if err != nil {
+			return nil, err
+		} else if err := handle.AddVersion(ver, vrfOutput); err != nil {
+			return nil, err
+		}
+	}
 	proof, err := provider.Output(n, nP, m)
 	if err != nil {
 		return nil, err
@@ -272,19 +323,19 @@ func (u *updater) next(withValues bool) (*structs.UpdateResponse, error) {
 	return &structs.UpdateResponse{
 		FullTreeHead: *fth,
 
-		Position: pos,
-		Values:   values,
-		Info:     info,
+		Position:  pos,
+		Values:    values,
+		Submitted: offset,
+		Info:      info,
 
 		BinaryLadder: ladder,
 		Update:       *proof,
 	}, nil
 }
 
-// infos returns the UpdateInfo structures for all of the versions inserted in
-// the log entry at `pos`. If `withValues` is true, it also returns the value
-// for each version.
-func (u *updater) infos(pos uint64, withValues bool) ([]structs.LabelValue, []structs.UpdateInfo, error) {
+// infos returns the values and UpdateInfo structures for all of the versions
+// inserted in the log entry at `pos`.
+func (u *updater) infos(pos uint64) ([]structs.LabelValue, []structs.UpdateInfo, error) {
 	var (
 		values []structs.LabelValue
 		info   []structs.UpdateInfo
@@ -295,9 +346,7 @@ func (u *updater) infos(pos uint64, withValues bool) ([]structs.LabelValue, []st
 		if err != nil {
 			return nil, nil, err
 		}
-		if withValues {
-			values = append(values, structs.LabelValue{Value: res.Value.Value})
-		}
+		values = append(values, structs.LabelValue{Value: res.Value.Value})
 		info = append(info, structs.UpdateInfo{
 			Opening:      res.Opening,
 			UpdateSuffix: res.Value.UpdateSuffix,
@@ -319,23 +368,35 @@ func (u *updater) ladder(startVer, endVer int) ([]structs.BinaryLadderStep, erro
 // reloadTree refreshes the `tree` and `index` fields of `u` to cover new
 // versions created in the log entry `pos`.
 func (u *updater) reloadTree(pos uint64) error {
-	tree, err := NewTree(u.tree.config, u.tree.tx, u.tree.updater)
+	tx, err := u.tree.tx.Clone()
 	if err != nil {
 		return err
-	} else if tree.treeHead.TreeSize <= pos {
+	}
+	tree, err := NewTree(u.tree.config, tx, u.tree.updater)
+	if err != nil {
+		tx.Release()
+		return err
+	} else if tree.treeHead == nil || tree.treeHead.TreeSize <= pos {
+		tx.Release()
 		return errors.New("reloaded tree does not contain new versions of label")
 	}
 
-	indices, err := u.tree.batchGetIndex([][]byte{u.label})
+	indices, err := tree.batchGetIndex([][]byte{u.label})
 	if err != nil {
+		tx.Release()
 		return err
 	}
 	index := indices[0]
 	if len(index) == 0 || index[len(index)-1] < pos {
+		tx.Release()
 		return errors.New("reloaded index does not contain new versions of label")
 	}
 
+	if u.snapshot != nil {
+		u.snapshot.Release()
+	}
 	u.tree = tree
 	u.index = index
+	u.snapshot = tx
 	return nil
 }
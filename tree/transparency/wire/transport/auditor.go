@@ -0,0 +1,76 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net/http"
+
+	"github.com/Bren2010/katie/crypto/suites"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/wire"
+)
+
+// auditRequest is the body of a request to a Third-Party Auditor: the position
+// of the log entry as a uint64, followed by its AuditorUpdate.
+type auditRequest struct {
+	pos    uint64
+	update *structs.AuditorUpdate
+}
+
+func newAuditRequest(cs suites.CipherSuite, buf *structs.Decoder) (*auditRequest, error) {
+	var pos uint64
+	if err := binary.Read(buf, binary.BigEndian, &pos); err != nil {
+		return nil, err
+	}
+	update, err := structs.NewAuditorUpdate(cs, buf)
+	if err != nil {
+		return nil, err
+	}
+	return &auditRequest{pos: pos, update: update}, nil
+}
+
+func (ar *auditRequest) Marshal(buf *bytes.Buffer) error {
+	binary.Write(buf, binary.BigEndian, ar.pos)
+	return ar.update.Marshal(buf)
+}
+
+// NewAuditorServer returns a new Server that exposes the operations of a
+// Third-Party Auditor. `config` is the public configuration of the
+// Transparency Log being audited, which is necessary to decode requests.
+func NewAuditorServer(config *structs.PublicConfig, auditor wire.AuditorInterface) *Server {
+	s := &Server{
+		auditor: auditor,
+		config:  config,
+		limits:  limitsWithMaxSize(MaxAuditRequestSize),
+		mux:     http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST "+AuditPath, s.audit)
+	return s
+}
+
+func (s *Server) audit(rw http.ResponseWriter, req *http.Request) {
+	parsed, err := readRequest(req, s.limits, func(buf *structs.Decoder) (*auditRequest, error) {
+		return newAuditRequest(s.config.Suite, buf)
+	})
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	head, err := s.auditor.Audit(req.Context(), parsed.pos, parsed.update)
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	raw, err := structs.Marshal(head)
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	rw.Header().Set("Content-Type", ContentType)
+	rw.Write(raw)
+}
+
+func (c *Client) Audit(ctx context.Context, pos uint64, update *structs.AuditorUpdate) (*structs.AuditorTreeHead, error) {
+	return call(ctx, c, AuditPath, &auditRequest{pos: pos, update: update}, structs.NewAuditorTreeHead)
+}
@@ -0,0 +1,214 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/wire"
+)
+
+// Client implements wire.Interface, wire.ManagerInterface, and
+// wire.AuditorInterface by making requests to a remote Server.
+type Client struct {
+	config  *structs.PublicConfig
+	baseURL string
+	client  *http.Client
+	limits  structs.Limits
+}
+
+var (
+	_ wire.Interface        = &Client{}
+	_ wire.ManagerInterface = &Client{}
+	_ wire.AuditorInterface = &Client{}
+)
+
+// NewClient returns a new Client for the Transparency Log with the given
+// public configuration, hosted at `baseURL`. If `client` is nil,
+// http.DefaultClient is used.
+func NewClient(config *structs.PublicConfig, baseURL string, client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{
+		config:  config,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  client,
+		limits:  limitsWithMaxSize(MaxResponseSize),
+	}
+}
+
+// SetLimits changes the limits that responses are decoded with. The MaxSize
+// field of `limits` is also the maximum size of a response body.
+func (c *Client) SetLimits(limits structs.Limits) { c.limits = limits }
+
+// do sends the encoded request `req` to `path`. On success, it returns the
+// response body, which the caller is responsible for closing.
+func (c *Client) do(ctx context.Context, path string, req structs.Marshaller) (io.ReadCloser, error) {
+	raw, err := structs.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", ContentType)
+
+	res, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	} else if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		msg, err := io.ReadAll(io.LimitReader(res.Body, MaxRequestSize))
+		if err != nil {
+			return nil, err
+		}
+		return nil, &Error{res.StatusCode, errors.New(strings.TrimSpace(string(msg)))}
+	}
+	return res.Body, nil
+}
+
+// call sends the encoded request `req` to `path` and decodes the response with
+// `newF`.
+func call[T any](ctx context.Context, c *Client, path string, req structs.Marshaller, newF func(*structs.Decoder) (*T, error)) (*T, error) {
+	body, err := c.do(ctx, path, req)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(body, int64(c.limits.MaxSize)+1))
+	if err != nil {
+		return nil, err
+	} else if len(raw) > c.limits.MaxSize {
+		return nil, errors.New("response body is too large")
+	}
+	buf, err := structs.NewDecoder(raw, c.limits)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := newF(buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	} else if buf.Len() != 0 {
+		return nil, errors.New("unexpected data appended to response")
+	}
+	return parsed, nil
+}
+
+func (c *Client) Search(ctx context.Context, req *structs.SearchRequest) (*structs.SearchResponse, error) {
+	return call(ctx, c, SearchPath, req, func(buf *structs.Decoder) (*structs.SearchResponse, error) {
+		return structs.NewSearchResponse(c.config, req, buf)
+	})
+}
+
+func (c *Client) BatchSearch(ctx context.Context, req *structs.BatchSearchRequest) (*structs.BatchSearchResponse, error) {
+	return call(ctx, c, BatchSearchPath, req, func(buf *structs.Decoder) (*structs.BatchSearchResponse, error) {
+		return structs.NewBatchSearchResponse(c.config, req, buf)
+	})
+}
+
+func (c *Client) History(ctx context.Context, req *structs.HistoryRequest) (*structs.HistoryResponse, error) {
+	return call(ctx, c, HistoryPath, req, func(buf *structs.Decoder) (*structs.HistoryResponse, error) {
+		return structs.NewHistoryResponse(c.config, buf)
+	})
+}
+
+func (c *Client) Consistency(ctx context.Context, req *structs.ConsistencyRequest) (*structs.ConsistencyResponse, error) {
+	return call(ctx, c, ConsistencyPath, req, func(buf *structs.Decoder) (*structs.ConsistencyResponse, error) {
+		return structs.NewConsistencyResponse(c.config, buf)
+	})
+}
+
+func (c *Client) ContactMonitor(ctx context.Context, req *structs.ContactMonitorRequest) (*structs.ContactMonitorResponse, error) {
+	return call(ctx, c, ContactMonitorPath, req, func(buf *structs.Decoder) (*structs.ContactMonitorResponse, error) {
+		return structs.NewContactMonitorResponse(c.config, buf)
+	})
+}
+
+func (c *Client) OwnerInit(ctx context.Context, req *structs.OwnerInitRequest) (*structs.OwnerInitResponse, error) {
+	return call(ctx, c, OwnerInitPath, req, func(buf *structs.Decoder) (*structs.OwnerInitResponse, error) {
+		return structs.NewOwnerInitResponse(c.config, buf)
+	})
+}
+
+func (c *Client) OwnerMonitor(ctx context.Context, req *structs.OwnerMonitorRequest) (*structs.OwnerMonitorResponse, error) {
+	return call(ctx, c, OwnerMonitorPath, req, func(buf *structs.Decoder) (*structs.OwnerMonitorResponse, error) {
+		return structs.NewOwnerMonitorResponse(c.config, buf)
+	})
+}
+
+func (c *Client) Update(ctx context.Context, req *structs.UpdateRequest) (<-chan wire.UpdateResponse, error) {
+	body, err := c.do(ctx, UpdatePath, req)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan wire.UpdateResponse)
+	go c.receive(ctx, body, ch)
+	return ch, nil
+}
+
+func (c *Client) ManagerUpdate(ctx context.Context, req *structs.ManagerUpdateRequest) (<-chan wire.UpdateResponse, error) {
+	body, err := c.do(ctx, ManagerUpdatePath, req)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan wire.UpdateResponse)
+	go c.receive(ctx, body, ch)
+	return ch, nil
+}
+
+// receive reads frames from the streamed response `body`, decodes them, and
+// sends them over `ch`. The stream is terminated by the first error.
+func (c *Client) receive(ctx context.Context, body io.ReadCloser, ch chan<- wire.UpdateResponse) {
+	defer close(ch)
+	defer body.Close()
+
+	send := func(res wire.UpdateResponse) bool {
+		select {
+		case ch <- res:
+			return res.Err == nil
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		frameType, payload, err := readFrame(body)
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			send(wire.UpdateResponse{Err: err})
+			return
+		}
+
+		switch frameType {
+		case updateFrame:
+			var out *structs.UpdateResponse
+			buf, err := structs.NewDecoder(payload, c.limits)
+			if err == nil {
+				out, err = structs.NewUpdateResponse(c.config, buf)
+			}
+			if err != nil {
+				err = fmt.Errorf("failed to decode response: %w", err)
+			} else if buf.Len() != 0 {
+				err = errors.New("unexpected data appended to response")
+			}
+			if ok := send(wire.UpdateResponse{Out: out, Err: err}); !ok {
+				return
+			}
+		case errorFrame:
+			send(wire.UpdateResponse{Err: errors.New(string(payload))})
+			return
+		default:
+			send(wire.UpdateResponse{Err: fmt.Errorf("unexpected frame type: %d", frameType)})
+			return
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bren2010/katie/db/memory"
+	"github.com/Bren2010/katie/tree/transparency"
+	"github.com/Bren2010/katie/tree/transparency/algorithms"
+	"github.com/Bren2010/katie/tree/transparency/auditor"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/test"
+)
+
+func TestClientSearch(t *testing.T) {
+	config, tree := newTestTree(t)
+	srv := httptest.NewServer(NewServer(tree))
+	defer srv.Close()
+
+	client := NewClient(config, srv.URL, nil)
+	res, err := client.Search(context.Background(), &structs.SearchRequest{Label: []byte("label")})
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(res.Value.Value, []byte("value")) {
+		t.Fatal("unexpected value returned")
+	}
+}
+
+func TestClientBatchSearch(t *testing.T) {
+	config, tree := newTestTree(t)
+	srv := httptest.NewServer(NewServer(tree))
+	defer srv.Close()
+
+	ver := uint32(0)
+	client := NewClient(config, srv.URL, nil)
+	res, err := client.BatchSearch(context.Background(), &structs.BatchSearchRequest{
+		Entries: []structs.BatchSearchEntry{
+			{Label: []byte("label")},
+			{Label: []byte("label"), Version: &ver},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Results) != 2 {
+		t.Fatalf("unexpected number of results: %v", len(res.Results))
+	}
+	for _, result := range res.Results {
+		if !bytes.Equal(result.Value.Value, []byte("value")) {
+			t.Fatal("unexpected value returned")
+		}
+	}
+}
+
+func TestClientHistory(t *testing.T) {
+	config, tree := newTestTree(t)
+	srv := httptest.NewServer(NewServer(tree))
+	defer srv.Close()
+
+	client := NewClient(config, srv.URL, nil)
+	res, err := client.History(context.Background(), &structs.HistoryRequest{Label: []byte("label")})
+	if err != nil {
+		t.Fatal(err)
+	} else if res.Version != 0 || len(res.Entries) != 1 {
+		t.Fatalf("unexpected history returned: %v, %v", res.Version, len(res.Entries))
+	} else if !bytes.Equal(res.Entries[0].Value.Value, []byte("value")) {
+		t.Fatal("unexpected value returned")
+	}
+}
+
+func TestClientConsistency(t *testing.T) {
+	config, tree := newTestTree(t)
+	srv := httptest.NewServer(NewServer(tree))
+	defer srv.Close()
+
+	client := NewClient(config, srv.URL, nil)
+	res, err := client.Consistency(context.Background(), &structs.ConsistencyRequest{Older: 1, Newer: 1})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(res.Proof) != 0 {
+		t.Fatal("unexpected proof returned")
+	}
+	_, err = client.Consistency(context.Background(), &structs.ConsistencyRequest{Older: 1, Newer: 2})
+	if err == nil {
+		t.Fatal("expected error requesting tree size beyond current tree size")
+	}
+}
+
+func TestClientUpdate(t *testing.T) {
+	privateConfig := test.Config(t)
+	store := memory.NewTransparencyStore()
+	ch := make(chan transparency.UpdateRequest)
+
+	// Start a sequencer in the background.
+	sequencerTree, err := transparency.NewTree(privateConfig, store, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = sequencerTree.Mutate([]transparency.LabelValue{
+		{Label: []byte("other"), Value: structs.UpdateValue{Value: []byte("value")}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seq := transparency.NewSequencer(sequencerTree, ch, transparency.SequencerConfig{})
+	go func() {
+		for {
+			if _, err := seq.Next(context.Background()); err != nil {
+				return
+			}
+		}
+	}()
+	defer close(ch)
+
+	snapshot, err := store.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snapshot.Release()
+	tree, err := transparency.NewTree(privateConfig, snapshot, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(NewServer(tree))
+	defer srv.Close()
+
+	client := NewClient(privateConfig.Public(), srv.URL, nil)
+	resCh, err := client.Update(context.Background(), &structs.UpdateRequest{
+		Label:  []byte("label"),
+		Values: []structs.LabelValue{{Value: []byte("a")}, {Value: []byte("b")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count := 0
+	for res := range resCh {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		} else if res.Out.Position != 1 {
+			t.Fatal("unexpected position for new versions")
+		} else if len(res.Out.Info) != 2 {
+			t.Fatal("unexpected number of new versions")
+		}
+		count++
+	}
+	if count != 1 {
+		t.Fatalf("unexpected number of update responses: %v", count)
+	}
+}
+
+func TestClientError(t *testing.T) {
+	config, _ := newTestTree(t)
+	srv := httptest.NewServer(NewServer(errorLog{err: algorithms.ErrLabelNotFound}))
+	defer srv.Close()
+
+	client := NewClient(config, srv.URL, nil)
+	_, err := client.Search(context.Background(), &structs.SearchRequest{Label: []byte("label")})
+
+	var target *Error
+	if !errors.As(err, &target) {
+		t.Fatalf("unexpected error: %v", err)
+	} else if target.Status != http.StatusNotFound {
+		t.Fatalf("unexpected status code: %v", target.Status)
+	} else if target.Err.Error() != algorithms.ErrLabelNotFound.Error() {
+		t.Fatalf("unexpected error message: %v", target.Err)
+	}
+
+	// The ManagerUpdate operation is not exposed by the server.
+	_, err = client.ManagerUpdate(context.Background(), &structs.ManagerUpdateRequest{Label: []byte("label")})
+	if !errors.As(err, &target) || target.Status != http.StatusNotFound {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestClientAudit(t *testing.T) {
+	config, auditorKey := test.ConfigWithAuditor(t)
+	tree, err := transparency.NewTree(config, memory.NewTransparencyStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	update, err := tree.Mutate([]transparency.LabelValue{
+		{Label: []byte("label"), Value: structs.UpdateValue{Value: []byte("value")}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := auditor.NewAuditor(config.Public(), auditorKey, memory.NewAuditorStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(NewAuditorServer(config.Public(), auditor.NewService(a)))
+	defer srv.Close()
+
+	client := NewClient(config.Public(), srv.URL, nil)
+	head, err := client.Audit(context.Background(), 0, update)
+	if err != nil {
+		t.Fatal(err)
+	} else if head.TreeSize != 1 {
+		t.Fatalf("unexpected auditor tree size: %v", head.TreeSize)
+	}
+}
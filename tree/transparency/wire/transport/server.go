@@ -0,0 +1,230 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/wire"
+)
+
+// Server is an http.Handler that exposes a Transparency Log's wire.Interface,
+// and optionally its wire.ManagerInterface, over HTTP. It may alternatively
+// expose a Third-Party Auditor's wire.AuditorInterface.
+type Server struct {
+	log     wire.Interface
+	manager wire.ManagerInterface
+	auditor wire.AuditorInterface
+	config  *structs.PublicConfig
+	limits  structs.Limits
+
+	mux *http.ServeMux
+}
+
+var _ http.Handler = &Server{}
+
+// NewServer returns a new Server that exposes the operations of `log`.
+func NewServer(log wire.Interface) *Server {
+	s := &Server{log: log, limits: limitsWithMaxSize(MaxRequestSize), mux: http.NewServeMux()}
+	s.mux.HandleFunc("POST "+SearchPath, s.search)
+	s.mux.HandleFunc("POST "+BatchSearchPath, s.batchSearch)
+	s.mux.HandleFunc("POST "+HistoryPath, s.history)
+	s.mux.HandleFunc("POST "+ConsistencyPath, s.consistency)
+	s.mux.HandleFunc("POST "+ContactMonitorPath, s.contactMonitor)
+	s.mux.HandleFunc("POST "+OwnerInitPath, s.ownerInit)
+	s.mux.HandleFunc("POST "+OwnerMonitorPath, s.ownerMonitor)
+	s.mux.HandleFunc("POST "+UpdatePath, s.update)
+	return s
+}
+
+// NewManagerServer returns a new Server that exposes the operations of a
+// Third-Party Manager, including the ManagerUpdate operation. `config` is the
+// Transparency Log's public configuration, which is necessary to decode
+// ManagerUpdate requests.
+func NewManagerServer(config *structs.PublicConfig, manager wire.ManagerInterface) *Server {
+	s := NewServer(manager)
+	s.manager = manager
+	s.config = config
+	s.mux.HandleFunc("POST "+ManagerUpdatePath, s.managerUpdate)
+	return s
+}
+
+// SetLimits changes the limits that requests are decoded with. The MaxSize
+// field of `limits` is also the maximum size of a request body.
+func (s *Server) SetLimits(limits structs.Limits) { s.limits = limits }
+
+func (s *Server) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	s.mux.ServeHTTP(rw, req)
+}
+
+// writeError responds to the request with `err`.
+func writeError(rw http.ResponseWriter, req *http.Request, err error) {
+	status := statusCode(err)
+	if status >= http.StatusInternalServerError {
+		log.Printf("%v: %v", req.URL.Path, err)
+	}
+	var target *Error
+	if errors.As(err, &target) {
+		err = target.Err
+	}
+
+	rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	rw.WriteHeader(status)
+	fmt.Fprintln(rw, err.Error())
+}
+
+// readRequest reads the request body, which may be at most `limits.MaxSize`
+// bytes, and decodes it with `newF`.
+func readRequest[T any](req *http.Request, limits structs.Limits, newF func(*structs.Decoder) (*T, error)) (*T, error) {
+	raw, err := io.ReadAll(io.LimitReader(req.Body, int64(limits.MaxSize)+1))
+	if err != nil {
+		return nil, &Error{http.StatusBadRequest, err}
+	} else if len(raw) > limits.MaxSize {
+		return nil, &Error{http.StatusRequestEntityTooLarge, errors.New("request body is too large")}
+	}
+
+	buf, err := structs.NewDecoder(raw, limits)
+	if err != nil {
+		return nil, &Error{http.StatusRequestEntityTooLarge, err}
+	}
+	parsed, err := newF(buf)
+	if err != nil {
+		return nil, &Error{http.StatusBadRequest, fmt.Errorf("failed to decode request: %w", err)}
+	} else if buf.Len() != 0 {
+		return nil, &Error{http.StatusBadRequest, errors.New("unexpected data appended to request")}
+	}
+	return parsed, nil
+}
+
+// handle decodes the request with `newF`, executes the operation `op`, and
+// writes the encoded output of the operation as the response.
+func handle[T any, U structs.Marshaller](
+	rw http.ResponseWriter,
+	req *http.Request,
+	limits structs.Limits,
+	newF func(*structs.Decoder) (*T, error),
+	op func(*T) (U, error),
+) {
+	parsed, err := readRequest(req, limits, newF)
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	res, err := op(parsed)
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	raw, err := structs.Marshal(res)
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	rw.Header().Set("Content-Type", ContentType)
+	rw.Write(raw)
+}
+
+func (s *Server) search(rw http.ResponseWriter, req *http.Request) {
+	handle(rw, req, s.limits, structs.NewSearchRequest, func(parsed *structs.SearchRequest) (*structs.SearchResponse, error) {
+		return s.log.Search(req.Context(), parsed)
+	})
+}
+
+func (s *Server) batchSearch(rw http.ResponseWriter, req *http.Request) {
+	handle(rw, req, s.limits, structs.NewBatchSearchRequest, func(parsed *structs.BatchSearchRequest) (*structs.BatchSearchResponse, error) {
+		return s.log.BatchSearch(req.Context(), parsed)
+	})
+}
+
+func (s *Server) history(rw http.ResponseWriter, req *http.Request) {
+	handle(rw, req, s.limits, structs.NewHistoryRequest, func(parsed *structs.HistoryRequest) (*structs.HistoryResponse, error) {
+		return s.log.History(req.Context(), parsed)
+	})
+}
+
+func (s *Server) consistency(rw http.ResponseWriter, req *http.Request) {
+	handle(rw, req, s.limits, structs.NewConsistencyRequest, func(parsed *structs.ConsistencyRequest) (*structs.ConsistencyResponse, error) {
+		return s.log.Consistency(req.Context(), parsed)
+	})
+}
+
+func (s *Server) contactMonitor(rw http.ResponseWriter, req *http.Request) {
+	handle(rw, req, s.limits, structs.NewContactMonitorRequest, func(parsed *structs.ContactMonitorRequest) (*structs.ContactMonitorResponse, error) {
+		return s.log.ContactMonitor(req.Context(), parsed)
+	})
+}
+
+func (s *Server) ownerInit(rw http.ResponseWriter, req *http.Request) {
+	handle(rw, req, s.limits, structs.NewOwnerInitRequest, func(parsed *structs.OwnerInitRequest) (*structs.OwnerInitResponse, error) {
+		return s.log.OwnerInit(req.Context(), parsed)
+	})
+}
+
+func (s *Server) ownerMonitor(rw http.ResponseWriter, req *http.Request) {
+	handle(rw, req, s.limits, structs.NewOwnerMonitorRequest, func(parsed *structs.OwnerMonitorRequest) (*structs.OwnerMonitorResponse, error) {
+		return s.log.OwnerMonitor(req.Context(), parsed)
+	})
+}
+
+func (s *Server) update(rw http.ResponseWriter, req *http.Request) {
+	parsed, err := readRequest(req, s.limits, structs.NewUpdateRequest)
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	ch, err := s.log.Update(req.Context(), parsed)
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	stream(rw, req, ch)
+}
+
+func (s *Server) managerUpdate(rw http.ResponseWriter, req *http.Request) {
+	parsed, err := readRequest(req, s.limits, func(buf *structs.Decoder) (*structs.ManagerUpdateRequest, error) {
+		return structs.NewManagerUpdateRequest(s.config, buf)
+	})
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	ch, err := s.manager.ManagerUpdate(req.Context(), parsed)
+	if err != nil {
+		writeError(rw, req, err)
+		return
+	}
+	stream(rw, req, ch)
+}
+
+// stream writes each UpdateResponse received from `ch` to the client as a
+// separate frame. An error terminates the stream.
+func stream(rw http.ResponseWriter, req *http.Request, ch <-chan wire.UpdateResponse) {
+	// Drain the channel on exit so that the producing goroutine can exit.
+	defer func() {
+		for range ch {
+		}
+	}()
+
+	rw.Header().Set("Content-Type", ContentType)
+	rw.WriteHeader(http.StatusOK)
+
+	for res := range ch {
+		var raw []byte
+		if res.Err == nil {
+			raw, res.Err = structs.Marshal(res.Out)
+		}
+		if res.Err != nil {
+			log.Printf("%v: %v", req.URL.Path, res.Err)
+			if err := writeFrame(rw, errorFrame, []byte(res.Err.Error())); err != nil {
+				log.Printf("%v: failed to write frame: %v", req.URL.Path, err)
+			}
+			return
+		} else if err := writeFrame(rw, updateFrame, raw); err != nil {
+			log.Printf("%v: failed to write frame: %v", req.URL.Path, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package transport
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Bren2010/katie/db/memory"
+	"github.com/Bren2010/katie/tree/transparency"
+	"github.com/Bren2010/katie/tree/transparency/algorithms"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+	"github.com/Bren2010/katie/tree/transparency/test"
+	"github.com/Bren2010/katie/tree/transparency/wire"
+)
+
+func newTestTree(t *testing.T) (*structs.PublicConfig, *transparency.Tree) {
+	config := test.Config(t)
+	tree, err := transparency.NewTree(config, memory.NewTransparencyStore(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = tree.Mutate([]transparency.LabelValue{
+		{Label: []byte("label"), Value: structs.UpdateValue{Value: []byte("value")}},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.Public(), tree
+}
+
+func post(t *testing.T, srv *httptest.Server, path string, body []byte) (*http.Response, []byte) {
+	res, err := http.Post(srv.URL+path, ContentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	raw, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, raw
+}
+
+func TestServerSearch(t *testing.T) {
+	config, tree := newTestTree(t)
+	srv := httptest.NewServer(NewServer(tree))
+	defer srv.Close()
+
+	req := &structs.SearchRequest{Label: []byte("label")}
+	raw, err := structs.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, body := post(t, srv, SearchPath, raw)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %v", res.StatusCode)
+	}
+	buf, err := structs.NewDecoder(body, structs.DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := structs.NewSearchResponse(config, req, buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if buf.Len() != 0 {
+		t.Fatal("unexpected data appended to response")
+	} else if !bytes.Equal(parsed.Value.Value, []byte("value")) {
+		t.Fatal("unexpected value returned")
+	}
+}
+
+func TestServerBadRequest(t *testing.T) {
+	_, tree := newTestTree(t)
+	srv := httptest.NewServer(NewServer(tree))
+	defer srv.Close()
+
+	// Malformed request body.
+	res, _ := post(t, srv, SearchPath, []byte{0xff})
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected status code: %v", res.StatusCode)
+	}
+
+	// Oversized request body.
+	res, _ = post(t, srv, SearchPath, make([]byte, MaxRequestSize+1))
+	if res.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Fatalf("unexpected status code: %v", res.StatusCode)
+	}
+
+	// Wrong method.
+	get, err := http.Get(srv.URL + SearchPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	get.Body.Close()
+	if get.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("unexpected status code: %v", get.StatusCode)
+	}
+
+	// ManagerUpdate is not exposed by default.
+	res, _ = post(t, srv, ManagerUpdatePath, nil)
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("unexpected status code: %v", res.StatusCode)
+	}
+}
+
+func TestServerUpdateStream(t *testing.T) {
+	_, tree := newTestTree(t)
+	srv := httptest.NewServer(NewServer(tree))
+	defer srv.Close()
+
+	// The tree has no updater, so the stream terminates with an error frame.
+	raw, err := structs.Marshal(&structs.UpdateRequest{
+		Label:  []byte("other"),
+		Values: []structs.LabelValue{{Value: []byte("value")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, body := post(t, srv, UpdatePath, raw)
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected status code: %v", res.StatusCode)
+	}
+	buf := bytes.NewBuffer(body)
+	frameType, _, err := readFrame(buf)
+	if err != nil {
+		t.Fatal(err)
+	} else if frameType != errorFrame {
+		t.Fatal("expected error frame")
+	} else if _, _, err := readFrame(buf); err != io.EOF {
+		t.Fatal("expected end of stream")
+	}
+}
+
+type errorLog struct {
+	wire.Interface
+	err error
+}
+
+func (el errorLog) Search(ctx context.Context, req *structs.SearchRequest) (*structs.SearchResponse, error) {
+	return nil, el.err
+}
+
+func TestServerErrorStatus(t *testing.T) {
+	testCases := []struct {
+		err    error
+		status int
+	}{
+		{errors.New("internal"), http.StatusInternalServerError},
+		{&Error{http.StatusForbidden, errors.New("forbidden")}, http.StatusForbidden},
+		{algorithms.ErrLabelNotFound, http.StatusNotFound},
+		{algorithms.ErrLabelExpired, http.StatusGone},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+	}
+	raw, err := structs.Marshal(&structs.SearchRequest{Label: []byte("label")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range testCases {
+		srv := httptest.NewServer(NewServer(errorLog{err: tc.err}))
+		res, _ := post(t, srv, SearchPath, raw)
+		srv.Close()
+
+		if res.StatusCode != tc.status {
+			t.Fatalf("unexpected status code for %q: %v != %v", tc.err, res.StatusCode, tc.status)
+		}
+	}
+}
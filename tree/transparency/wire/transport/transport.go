@@ -0,0 +1,147 @@
+// Package transport implements an HTTP transport for the wire-level interfaces
+// of a Transparency Log, where requests and responses are encoded with the
+// same binary encoding as the rest of the protocol.
+package transport
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/Bren2010/katie/tree/transparency/algorithms"
+	"github.com/Bren2010/katie/tree/transparency/structs"
+)
+
+// Paths at which each operation is exposed.
+const (
+	SearchPath         = "/v1/search"
+	BatchSearchPath    = "/v1/batch-search"
+	HistoryPath        = "/v1/history"
+	ConsistencyPath    = "/v1/consistency"
+	ContactMonitorPath = "/v1/contact-monitor"
+	OwnerInitPath      = "/v1/owner-init"
+	OwnerMonitorPath   = "/v1/owner-monitor"
+	UpdatePath         = "/v1/update"
+	ManagerUpdatePath  = "/v1/manager-update"
+	AuditPath          = "/v1/audit"
+)
+
+// ContentType is the media type of all request and response bodies.
+const ContentType = "application/octet-stream"
+
+// MaxRequestSize is the maximum size of a request body, in bytes.
+const MaxRequestSize = 64 * 1024
+
+// MaxAuditRequestSize is the maximum size of the body of a request to a
+// Third-Party Auditor, in bytes. It is larger than MaxRequestSize because an
+// AuditorUpdate contains a prefix tree proof for every label in a log entry.
+const MaxAuditRequestSize = 16 * 1024 * 1024
+
+// MaxResponseSize is the maximum size of a response body, or of a single frame
+// in a streamed response, in bytes.
+const MaxResponseSize = 16 * 1024 * 1024
+
+// limitsWithMaxSize returns structs.DefaultLimits, with the maximum size of an
+// encoded structure set to `size`.
+func limitsWithMaxSize(size int) structs.Limits {
+	limits := structs.DefaultLimits
+	limits.MaxSize = size
+	return limits
+}
+
+// Frame types used to stream the output of an Update operation. Each frame
+// consists of a one-byte frame type, the length of the payload as a uint32, and
+// then the payload itself.
+const (
+	updateFrame byte = iota + 1 // Payload is an encoded UpdateResponse.
+	errorFrame                  // Payload is an error message.
+)
+
+// Error is an error that has an associated HTTP status code. Implementations
+// of wire.Interface may return an Error to control the status code that the
+// server responds with, and the client returns an Error for any response with
+// an unsuccessful status code.
+type Error struct {
+	Status int
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %v", e.Status, http.StatusText(e.Status), e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Retryable returns true if the status code indicates a transient failure,
+// where the same request may succeed if it is sent again. The server may still
+// have acted on the request, so this is only meaningful for idempotent ones.
+func (e *Error) Retryable() bool {
+	switch e.Status {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// statusCode returns the HTTP status code that best describes `err`.
+func statusCode(err error) int {
+	var target *Error
+	switch {
+	case errors.As(err, &target):
+		return target.Status
+	case errors.Is(err, algorithms.ErrLabelNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, algorithms.ErrLabelExpired):
+		return http.StatusGone
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, context.Canceled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeFrame writes a single frame of a streamed response to `w`.
+func writeFrame(w io.Writer, frameType byte, payload []byte) error {
+	if len(payload) > MaxResponseSize {
+		return errors.New("frame payload is too large")
+	}
+	header := make([]byte, 5)
+	header[0] = frameType
+	binary.BigEndian.PutUint32(header[1:], uint32(len(payload)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	} else if _, err := w.Write(payload); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// readFrame reads a single frame of a streamed response from `r`. It returns
+// io.EOF if the stream ended cleanly before the start of a frame.
+func readFrame(r io.Reader) (byte, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err == io.EOF {
+		return 0, nil, io.EOF
+	} else if err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > MaxResponseSize {
+		return 0, nil, errors.New("frame payload is too large")
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, nil, err
+	}
+	return header[0], payload, nil
+}
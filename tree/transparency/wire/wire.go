@@ -13,6 +13,9 @@ import (
 // proof verification.
 type Interface interface {
 	Search(ctx context.Context, req *structs.SearchRequest) (*structs.SearchResponse, error)
+	BatchSearch(ctx context.Context, req *structs.BatchSearchRequest) (*structs.BatchSearchResponse, error)
+	History(ctx context.Context, req *structs.HistoryRequest) (*structs.HistoryResponse, error)
+	Consistency(ctx context.Context, req *structs.ConsistencyRequest) (*structs.ConsistencyResponse, error)
 	ContactMonitor(ctx context.Context, req *structs.ContactMonitorRequest) (*structs.ContactMonitorResponse, error)
 	OwnerInit(ctx context.Context, req *structs.OwnerInitRequest) (*structs.OwnerInitResponse, error)
 	OwnerMonitor(ctx context.Context, req *structs.OwnerMonitorRequest) (*structs.OwnerMonitorResponse, error)
@@ -28,6 +31,15 @@ type ManagerInterface interface {
 	ManagerUpdate(ctx context.Context, req *structs.ManagerUpdateRequest) (<-chan UpdateResponse, error)
 }
 
+// AuditorInterface is the interface implemented by a Third-Party Auditor.
+type AuditorInterface interface {
+	// Audit processes the AuditorUpdate for the log entry at position `pos`
+	// and returns the auditor's signed tree head. Updates must be provided in
+	// order, although the most recently processed update may be provided
+	// again, in which case the same tree head is returned.
+	Audit(ctx context.Context, pos uint64, update *structs.AuditorUpdate) (*structs.AuditorTreeHead, error)
+}
+
 // UpdateResponse wraps the output of an Update operation, which is either a
 // struct.UpdateResponse or an error.
 type UpdateResponse struct {